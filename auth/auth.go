@@ -9,77 +9,170 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-// JwtWrapper wraps the signing key and the issuer.
+// JwtWrapper wraps the signing keys and the issuer.
 type JwtWrapper struct {
-	SecretKey       string
-	Issuer          string
-	ExpirationHours int64
+	Keys       *KeySet
+	Issuer     string
+	Expiration time.Duration
 }
 
-// JwtClaim adds email as a claim to the token.
+// JwtClaim adds email and the login session as claims to the token. The
+// Subject is the ID of the User the token was issued to.
+//
+// SessionID is the session of the refresh tokens the token was issued with.
+// The token is only accepted while that session is not logged out.
 type JwtClaim struct {
-	Email string
+	Email     string
+	SessionID string
 	jwt.StandardClaims
 }
 
-// GenerateToken generates a JWT token.
-func (j *JwtWrapper) GenerateToken(email string) (string, error) {
+// UserID parses the ID of the User from the Subject.
+func (c *JwtClaim) UserID() (int, error) {
+	return subjectUserID(c.Subject)
+}
+
+// ChallengeAudience is the audience of the challenge tokens, which access
+// tokens never have.
+const ChallengeAudience = "login-2fa"
+
+// ChallengeClaim is the claim of a challenge token, given to a User with 2FA
+// who logged in with their password. It is exchanged with a 2FA code for an
+// access token and can not be used as one.
+//
+// Remember is whether the User asked to stay logged in.
+type ChallengeClaim struct {
+	Remember bool
+	jwt.StandardClaims
+}
+
+// UserID parses the ID of the User from the Subject.
+func (c *ChallengeClaim) UserID() (int, error) {
+	return subjectUserID(c.Subject)
+}
+
+func subjectUserID(subject string) (int, error) {
+	userID, err := strconv.Atoi(subject)
+	if err != nil {
+		return 0, errors.New("INVALID TOKEN. The token has no User.")
+	}
+	return userID, nil
+}
+
+// GenerateToken generates a JWT token for the User.
+func (j *JwtWrapper) GenerateToken(userID uint, email string, sessionID string) (string, error) {
 	claims := &JwtClaim{
-		Email: email,
+		Email:     email,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(j.ExpirationHours)).Unix(),
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			ExpiresAt: time.Now().Local().Add(j.Expiration).Unix(),
+			IssuedAt:  time.Now().Local().Unix(),
 			Issuer:    j.Issuer,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	signedToken, err := token.SignedString([]byte(j.SecretKey))
+	signedToken, err := j.Keys.Sign(claims)
 	if err != nil {
 		return "", err
 	}
 	return signedToken, nil
 }
 
-// ValidateToken validates the JWT token brought by the Header.
-func (j *JwtWrapper) ValidateToken(signedToken string, userID string) (claims *JwtClaim, err error) {
+// GenerateChallengeToken generates a challenge token for the User.
+func (j *JwtWrapper) GenerateChallengeToken(userID uint, remember bool) (string, error) {
+	claims := &ChallengeClaim{
+		Remember: remember,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Audience:  ChallengeAudience,
+			ExpiresAt: time.Now().Local().Add(j.Expiration).Unix(),
+			IssuedAt:  time.Now().Local().Unix(),
+			Issuer:    j.Issuer,
+		},
+	}
+	return j.Keys.Sign(claims)
+}
+
+// ParseChallengeToken verifies the signature, the expiry and the audience of
+// the challenge token and returns its claims.
+func (j *JwtWrapper) ParseChallengeToken(signedToken string) (*ChallengeClaim, error) {
+	token, err := jwt.ParseWithClaims(
+		signedToken,
+		&ChallengeClaim{},
+		j.Keys.Keyfunc,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ChallengeClaim)
+	if !ok {
+		return nil, errors.New("couldn't parse claims")
+	}
+
+	if !claims.VerifyAudience(ChallengeAudience, true) {
+		return nil, errors.New("not a challenge token")
+	}
+	if claims.ExpiresAt < time.Now().Local().Unix() {
+		return nil, errors.New("JWT is expired")
+	}
+	return claims, nil
+}
+
+// ParseToken verifies the signature and the expiry of the JWT token and
+// returns its claims.
+func (j *JwtWrapper) ParseToken(signedToken string) (*JwtClaim, error) {
 	token, err := jwt.ParseWithClaims(
 		signedToken,
 		&JwtClaim{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(j.SecretKey), nil
-		},
+		j.Keys.Keyfunc,
 	)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(*JwtClaim)
 	if !ok {
-		err = errors.New("couldn't parse claims")
-		return
+		return nil, errors.New("couldn't parse claims")
+	}
+
+	// Challenge tokens are signed with the same keys, but are not access
+	// tokens.
+	if claims.Audience != "" {
+		return nil, errors.New("not an access token")
+	}
+	if claims.ExpiresAt < time.Now().Local().Unix() {
+		return nil, errors.New("JWT is expired")
 	}
+	return claims, nil
+}
 
-	userId, err := strconv.Atoi(userID)
+// ValidateToken validates the JWT token brought by the Header and fetches the
+// User it was issued to.
+func (j *JwtWrapper) ValidateToken(signedToken string) (*JwtClaim, *models.User, error) {
+	claims, err := j.ParseToken(signedToken)
 	if err != nil {
-		return
+		return nil, nil, err
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var user models.User
-	sourceUser := user.GetUserByID(userId)
+	sourceUser := user.GetUserByID(userID)
 	if sourceUser == nil {
-		err = errors.New("Could not find User.")
-		return
+		return nil, nil, errors.New("Could not find User.")
 	}
 
-	if claims.Email != sourceUser.Email {
-		err = errors.New("INVALID TOKEN. You are not authorized to access this request.")
-		return
+	if sourceUser.DeactivatedAt != nil {
+		return nil, nil, errors.New("Account is deactivated.")
 	}
 
-	if claims.ExpiresAt < time.Now().Local().Unix() {
-		err = errors.New("JWT is expired")
-		return
+	if sourceUser.TokenRevoked(claims.IssuedAt) {
+		return nil, nil, errors.New("Token has been revoked. Please log in again.")
 	}
-	return
+	return claims, sourceUser, nil
 }
@@ -3,34 +3,70 @@ package auth
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGenerateToken(t *testing.T) {
 	jwtWrapper := JwtWrapper{
-		SecretKey:       "verysecretkey",
-		Issuer:          "AuthService",
-		ExpirationHours: 24,
+		Keys:       NewHMACKeySet("verysecretkey"),
+		Issuer:     "AuthService",
+		Expiration: 24 * time.Hour,
 	}
 
-	generatedToken, err := jwtWrapper.GenerateToken("jwt@email.com")
+	generatedToken, err := jwtWrapper.GenerateToken(1, "jwt@email.com", "session")
 	assert.NoError(t, err)
 
 	os.Setenv("testToken", generatedToken)
 }
 
-func TestValidateToken(t *testing.T) {
+func TestParseToken(t *testing.T) {
 	encodedToken := os.Getenv("testToken")
 
 	jwtWrapper := JwtWrapper{
-		SecretKey: "verysecretkey",
-		Issuer:    "AuthService",
+		Keys:   NewHMACKeySet("verysecretkey"),
+		Issuer: "AuthService",
 	}
 
-	claims, err := jwtWrapper.ValidateToken(encodedToken, "1")
+	claims, err := jwtWrapper.ParseToken(encodedToken)
 	assert.NoError(t, err)
 
 	assert.Equal(t, "jwt@email.com", claims.Email)
 	assert.Equal(t, "AuthService", claims.Issuer)
+
+	userID, err := claims.UserID()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, userID)
+
+	jwtWrapper.Keys = NewHMACKeySet("anotherkey")
+	_, err = jwtWrapper.ParseToken(encodedToken)
+	assert.Error(t, err)
+}
+
+func TestChallengeToken(t *testing.T) {
+	jwtWrapper := JwtWrapper{
+		Keys:       NewHMACKeySet("verysecretkey"),
+		Issuer:     "AuthService",
+		Expiration: 5 * time.Minute,
+	}
+
+	challenge, err := jwtWrapper.GenerateChallengeToken(1, true)
+	assert.NoError(t, err)
+
+	claims, err := jwtWrapper.ParseChallengeToken(challenge)
+	assert.NoError(t, err)
+	assert.True(t, claims.Remember)
+	userID, err := claims.UserID()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, userID)
+
+	// Neither token can be used as the other.
+	_, err = jwtWrapper.ParseToken(challenge)
+	assert.Error(t, err)
+
+	token, err := jwtWrapper.GenerateToken(1, "jwt@email.com", "session")
+	assert.NoError(t, err)
+	_, err = jwtWrapper.ParseChallengeToken(token)
+	assert.Error(t, err)
 }
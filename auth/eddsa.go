@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"errors"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEd25519 signs tokens with Ed25519, "EdDSA" in the alg Header.
+// jwt-go does not have it, so it is registered here.
+type SigningMethodEd25519 struct{}
+
+// SigningMethodEdDSA is the Ed25519 signing method.
+var SigningMethodEdDSA = &SigningMethodEd25519{}
+
+// errEd25519Verification is returned when an Ed25519 signature is invalid.
+var errEd25519Verification = errors.New("ed25519: verification error")
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+// Alg returns the alg Header of the signing method.
+func (m *SigningMethodEd25519) Alg() string {
+	return "EdDSA"
+}
+
+// Sign signs signingString with an ed25519.PrivateKey.
+func (m *SigningMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// Verify verifies the signature of signingString with an ed25519.PublicKey.
+func (m *SigningMethodEd25519) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return errEd25519Verification
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// minRSAKeyBits is the smallest RSA key accepted for signing tokens.
+const minRSAKeyBits = 2048
+
+// DefaultKeys are the keys tokens are signed and verified with.
+var DefaultKeys *KeySet
+
+// Key is a key tokens are signed or verified with. ID is the "kid" Header of
+// the tokens it signs, the RFC 7638 thumbprint of an asymmetric key.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet signs tokens with one Key and verifies tokens signed with any of its
+// Keys. Keeping the previous Key in the set while the tokens it signed are
+// still valid allows rotating the signing Key.
+type KeySet struct {
+	signing *Key
+	keys    []*Key
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKeySet returns a KeySet which signs and verifies HS256 tokens with a
+// shared secret.
+func NewHMACKeySet(secret string) *KeySet {
+	key := &Key{
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+	return &KeySet{signing: key, keys: []*Key{key}}
+}
+
+// NewKeySet returns a KeySet which signs with signing and verifies with
+// signing and the verification Keys.
+func NewKeySet(signing *Key, verification ...*Key) (*KeySet, error) {
+	if signing.signKey == nil {
+		return nil, errors.New("ERROR KEY: the signing key must be a private key")
+	}
+
+	return &KeySet{signing: signing, keys: append([]*Key{signing}, verification...)}, nil
+}
+
+// LoadKeySetFromEnv loads the keys configured in the environment.
+//
+// JWT_SIGNING_KEY is the path of a PEM encoded RSA or Ed25519 private key, which
+// signs the tokens with RS256 or EdDSA. JWT_VERIFICATION_KEYS is a comma
+// separated list of paths of PEM encoded keys whose tokens are still accepted,
+// like the previous signing key during rotation.
+//
+// Without JWT_SIGNING_KEY, tokens are signed with HS256 and JWT_SECRET.
+func LoadKeySetFromEnv() (*KeySet, error) {
+	signingFile := os.Getenv("JWT_SIGNING_KEY")
+	if signingFile == "" {
+		return NewHMACKeySet(os.Getenv("JWT_SECRET")), nil
+	}
+
+	signing, err := LoadKeyFile(signingFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var verification []*Key
+	for _, file := range strings.Split(os.Getenv("JWT_VERIFICATION_KEYS"), ",") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		key, err := LoadKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, key)
+	}
+	return NewKeySet(signing, verification...)
+}
+
+// LoadKeyFile loads a PEM encoded key, see ParseKeyPEM.
+func LoadKeyFile(path string) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR KEY: %s", err)
+	}
+
+	key, err := ParseKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s)", err, path)
+	}
+	return key, nil
+}
+
+// ParseKeyPEM parses a PEM encoded RSA or Ed25519 key. A private key can sign
+// and verify tokens, a public key can only verify them.
+func ParseKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("ERROR KEY: no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("ERROR KEY: unsupported PEM block %s", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ERROR KEY: %s", err)
+	}
+
+	var key *Key
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key = &Key{Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}
+	case *rsa.PublicKey:
+		key = &Key{Method: jwt.SigningMethodRS256, verifyKey: k}
+	case ed25519.PrivateKey:
+		key = &Key{Method: SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}
+	case ed25519.PublicKey:
+		key = &Key{Method: SigningMethodEdDSA, verifyKey: k}
+	default:
+		return nil, errors.New("ERROR KEY: only RSA and Ed25519 keys are supported")
+	}
+
+	if publicKey, ok := key.verifyKey.(*rsa.PublicKey); ok && publicKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("ERROR KEY: RSA keys must have at least %d bits", minRSAKeyBits)
+	}
+
+	key.ID = key.thumbprint()
+	return key, nil
+}
+
+// JWK returns the public part of an asymmetric Key as a JWK.
+func (k *Key) JWK() (JWK, bool) {
+	switch publicKey := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(publicKey),
+		}, true
+	}
+	return JWK{}, false
+}
+
+// thumbprint returns the RFC 7638 thumbprint of the Key's JWK.
+func (k *Key) thumbprint() string {
+	jwk, ok := k.JWK()
+	if !ok {
+		return ""
+	}
+
+	// The required members in lexicographic order, as json.Marshal sorts the
+	// keys of a map.
+	members := map[string]string{"kty": jwk.Kty}
+	switch jwk.Kty {
+	case "RSA":
+		members["n"] = jwk.N
+		members["e"] = jwk.E
+	case "OKP":
+		members["crv"] = jwk.Crv
+		members["x"] = jwk.X
+	}
+	canonical, _ := json.Marshal(members)
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Sign signs the claims with the signing Key.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	if s.signing.ID != "" {
+		token.Header["kid"] = s.signing.ID
+	}
+	return token.SignedString(s.signing.signKey)
+}
+
+// Keyfunc finds the Key to verify a token with by its "kid" Header. The token
+// must be signed with the Key's own method, so a public key can never be used
+// as an HMAC secret.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range s.keys {
+		if key.ID != kid {
+			continue
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.verifyKey, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// JWKS returns the public keys of the KeySet. An HMAC secret is never
+// published, so an HS256 KeySet has no keys.
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range s.keys {
+		if jwk, ok := key.JWK(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
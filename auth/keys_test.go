@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func privateKeyPEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicKeyPEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func testClaims() *JwtClaim {
+	return &JwtClaim{
+		Email: "jwt@email.com",
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "1",
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+	}
+}
+
+func TestKeySetSignsWithEachAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for alg, private := range map[string]interface{}{"RS256": rsaKey, "EdDSA": edKey} {
+		key, err := ParseKeyPEM(privateKeyPEM(t, private))
+		assert.NoError(t, err)
+		keys, err := NewKeySet(key)
+		assert.NoError(t, err)
+
+		signedToken, err := keys.Sign(testClaims())
+		assert.NoError(t, err)
+
+		token, err := jwt.ParseWithClaims(signedToken, &JwtClaim{}, keys.Keyfunc)
+		assert.NoError(t, err, alg)
+		assert.Equal(t, alg, token.Header["alg"])
+		assert.Equal(t, key.ID, token.Header["kid"])
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	_, oldPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	newPublic, newPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+	oldKey, err := ParseKeyPEM(privateKeyPEM(t, oldPrivate))
+	assert.NoError(t, err)
+	newKey, err := ParseKeyPEM(privateKeyPEM(t, newPrivate))
+	assert.NoError(t, err)
+
+	oldKeys, _ := NewKeySet(oldKey)
+	oldToken, err := oldKeys.Sign(testClaims())
+	assert.NoError(t, err)
+
+	// The old key still verifies after the new one signs.
+	rotated, _ := NewKeySet(newKey, oldKey)
+	_, err = jwt.ParseWithClaims(oldToken, &JwtClaim{}, rotated.Keyfunc)
+	assert.NoError(t, err)
+
+	// Until it is removed.
+	newKeys, _ := NewKeySet(newKey)
+	_, err = jwt.ParseWithClaims(oldToken, &JwtClaim{}, newKeys.Keyfunc)
+	assert.Error(t, err)
+
+	jwks := rotated.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, newKey.ID, jwks.Keys[0].Kid)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(newPublic), jwks.Keys[0].X)
+
+	// A public key verifies, but can not sign.
+	publicKey, err := ParseKeyPEM(publicKeyPEM(t, newPublic))
+	assert.NoError(t, err)
+	assert.Equal(t, newKey.ID, publicKey.ID)
+	_, err = NewKeySet(publicKey)
+	assert.Error(t, err)
+}
+
+func TestKeySetRejectsOtherAlgorithms(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key, err := ParseKeyPEM(privateKeyPEM(t, rsaKey))
+	assert.NoError(t, err)
+	keys, _ := NewKeySet(key)
+
+	// An HS256 token "signed" with the public key as secret.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	forged.Header["kid"] = key.ID
+	forgedToken, err := forged.SignedString(publicKeyPEM(t, &rsaKey.PublicKey))
+	assert.NoError(t, err)
+	_, err = jwt.ParseWithClaims(forgedToken, &JwtClaim{}, keys.Keyfunc)
+	assert.Error(t, err)
+
+	hmacToken, err := NewHMACKeySet("secret").Sign(testClaims())
+	assert.NoError(t, err)
+	_, err = jwt.ParseWithClaims(hmacToken, &JwtClaim{}, keys.Keyfunc)
+	assert.Error(t, err)
+
+	assert.Empty(t, NewHMACKeySet("secret").JWKS().Keys)
+}
+
+func TestKeyThumbprint(t *testing.T) {
+	// The example of RFC 7638, section 3.1.
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	assert.NoError(t, err)
+
+	key, err := ParseKeyPEM(publicKeyPEM(t, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}))
+	assert.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", key.ID)
+}
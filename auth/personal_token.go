@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"issue-tracker/models"
+	"strings"
+	"time"
+)
+
+// PersonalTokenPrefix starts every personal access token, which tells them
+// apart from JWTs.
+const PersonalTokenPrefix = "pat_"
+
+// personalTokenDisplayLength is how much of a personal access token is kept to
+// recognize it by.
+const personalTokenDisplayLength = 12
+
+// NewPersonalToken generates a personal access token. Returns the token for
+// the client to keep and the start of it to recognize it by. Only its
+// HashToken is stored.
+func NewPersonalToken() (string, string, error) {
+	token, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = PersonalTokenPrefix + token
+	return token, token[:personalTokenDisplayLength], nil
+}
+
+// IsPersonalToken checks whether the token is a personal access token.
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// ValidatePersonalToken fetches the personal access token and the User it was
+// created by.
+//
+// Like other tokens, it is rejected when the User is deactivated. Revoking the
+// User's tokens, when they log out everywhere or their password or 2FA is
+// reset, does not revoke it: it is listed with the User's personal access
+// tokens and revoked there, so scripts and CI keep working.
+func ValidatePersonalToken(token string) (*models.PersonalAccessToken, *models.User, error) {
+	var personalToken models.PersonalAccessToken
+	source, err := personalToken.FindActivePersonalAccessToken(HashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var user models.User
+	sourceUser := user.GetUserByID(int(source.UserID))
+	if sourceUser == nil {
+		return nil, nil, errors.New("Could not find User.")
+	}
+
+	if sourceUser.DeactivatedAt != nil {
+		return nil, nil, errors.New("Account is deactivated.")
+	}
+
+	if err := source.Touch(time.Now()); err != nil {
+		return nil, nil, err
+	}
+	return source, sourceUser, nil
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOpaqueToken(t *testing.T) {
+	first, err := NewOpaqueToken()
+	assert.NoError(t, err)
+	second, err := NewOpaqueToken()
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 43)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHashToken(t *testing.T) {
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", HashToken("hello"))
+	assert.NotEqual(t, HashToken("hello"), HashToken("hello "))
+}
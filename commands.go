@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Command is a command of the tracker binary. Every Command runs on the
+// database of DATABASE_DRIVER and DATABASE_URL, with the same configuration as
+// the server.
+type Command struct {
+	Summary string
+	Run     func(db *gorm.DB, args []string, out io.Writer) error
+}
+
+// commands are the Commands by name. Without a command the binary runs serve.
+var commands = map[string]Command{
+	"serve":   {Summary: "run the HTTP server", Run: serve},
+	"migrate": {Summary: "apply, roll back or list the database migrations", Run: runMigrate},
+	"seed":    {Summary: "load demo data for local development", Run: runSeed},
+	"user":    {Summary: "create users, change their role, disable them or reset their password", Run: runUser},
+	"issue":   {Summary: "export or import issues as JSON", Run: runIssue},
+	"token":   {Summary: "revoke personal access tokens and sessions", Run: runToken},
+}
+
+// printUsage lists the commands.
+func printUsage(out io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(out, "Usage: issue-tracker <command> [arguments]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-8s %s\n", name, commands[name].Summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Run a command with -h for its arguments.")
+}
+
+// isHelp checks whether the argument asks for the usage.
+func isHelp(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// newFlagSet returns a FlagSet which prints the usage to out.
+func newFlagSet(name string, usage string, out io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() { fmt.Fprintln(out, usage) }
+	return flags
+}
+
+// parseFlags parses the arguments, which must set the required flags and have
+// no other arguments.
+func parseFlags(flags *flag.FlagSet, args []string, required ...string) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return fmt.Errorf("ERROR %s: unknown arguments %q", strings.ToUpper(flags.Name()), flags.Args())
+	}
+	for _, name := range required {
+		if flags.Lookup(name).Value.String() == "" {
+			flags.Usage()
+			return fmt.Errorf("ERROR %s: -%s is required", strings.ToUpper(flags.Name()), name)
+		}
+	}
+	return nil
+}
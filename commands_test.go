@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"issue-tracker/database"
+	"issue-tracker/migrations"
+	"issue-tracker/models"
+	"issue-tracker/seeds"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupCommands points database.DB at a migrated in-memory SQLite database with
+// the demo data.
+func setupCommands(t *testing.T) *gorm.DB {
+	db, err := database.Open(database.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	database.DB = db
+	if err := migrations.MigrateTables(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seeds.Demo(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestUserCommand(t *testing.T) {
+	db := setupCommands(t)
+	// The command only uses the database it is given.
+	database.DB = nil
+	var out bytes.Buffer
+
+	err := runUser(db, []string{"create", "-email", "ops@email.com", "-name", "Ops", "-role", "admin"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Temporary password: ")
+
+	ops, err := findUserByEmail(db, "ops@email.com")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, ops.RoleID)
+	assert.True(t, ops.MustResetPassword)
+	assert.NotNil(t, ops.EmailVerifiedAt)
+
+	err = runUser(db, []string{"create", "-email", "ops@email.com"}, &out)
+	assert.Error(t, err, "-name and -role are required")
+
+	assert.NoError(t, runUser(db, []string{"set-role", "-email", "qa@demo.local", "-role", "2"}, &out))
+	qa, _ := findUserByEmail(db, "qa@demo.local")
+	assert.Equal(t, models.RoleDeveloper, qa.RoleID)
+
+	assert.NoError(t, runUser(db, []string{"disable", "-email", "qa@demo.local"}, &out))
+	qa, _ = findUserByEmail(db, "qa@demo.local")
+	assert.NotNil(t, qa.DeactivatedAt)
+	assert.Error(t, runUser(db, []string{"disable", "-email", "qa@demo.local"}, &out))
+
+	assert.NoError(t, runUser(db, []string{"reset-password", "-email", "dev@demo.local", "-password", "new password"}, &out))
+	dev, _ := findUserByEmail(db, "dev@demo.local")
+	assert.NoError(t, bcrypt.CompareHashAndPassword(dev.Password, []byte("new password")))
+	assert.False(t, dev.MustResetPassword, "a given password does not have to be changed")
+	assert.NotNil(t, dev.TokensRevokedAt)
+
+	out.Reset()
+	assert.NoError(t, runUser(db, []string{"reset-password", "-email", "dev@demo.local"}, &out))
+	assert.Contains(t, out.String(), "Temporary password: ")
+	dev, _ = findUserByEmail(db, "dev@demo.local")
+	assert.True(t, dev.MustResetPassword)
+
+	assert.Error(t, runUser(db, []string{"rename"}, &out))
+}
+
+func TestUserCommandIgnoresEmailCase(t *testing.T) {
+	db := setupCommands(t)
+	var out bytes.Buffer
+
+	err := runUser(db, []string{"create", "-email", " Ops@Corp.com ", "-name", "Ops", "-role", "qa", "-password", "secret"}, &out)
+	assert.NoError(t, err)
+
+	ops, err := models.NewGormStore(db).Users().FindByEmail("ops@corp.com")
+	if assert.NoError(t, err, "the e-mail is saved lowercased, as on registration") {
+		assert.Equal(t, "ops@corp.com", ops.Email)
+	}
+
+	assert.NoError(t, runUser(db, []string{"set-role", "-email", "OPS@corp.COM", "-role", "developer"}, &out))
+	assert.NoError(t, runUser(db, []string{"disable", "-email", "Ops@Corp.com"}, &out))
+}
+
+func TestIssueExportImport(t *testing.T) {
+	db := setupCommands(t)
+	file := filepath.Join(t.TempDir(), "issues.json")
+	var out bytes.Buffer
+
+	assert.NoError(t, runIssue(db, []string{"export", "-project", "demo", "-file", file}, &out))
+	data, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	var exported []exportedIssue
+	assert.NoError(t, json.Unmarshal(data, &exported))
+	if assert.NotEmpty(t, exported) {
+		assert.Equal(t, "DEMO-1", exported[0].Key)
+		assert.NotEmpty(t, exported[0].Replies)
+	}
+
+	ops := models.Project{Name: "Ops", Key: "OPS", OwnerID: 1}
+	assert.NoError(t, db.Create(&ops).Error)
+
+	// Nothing is imported when a reporter is unknown.
+	exported[len(exported)-1].Reporter = "gone@email.com"
+	unknown := filepath.Join(t.TempDir(), "unknown.json")
+	data, _ = json.Marshal(exported)
+	assert.NoError(t, ioutil.WriteFile(unknown, data, 0600))
+	assert.Error(t, runIssue(db, []string{"import", "-project", "OPS", "-file", unknown}, &out))
+
+	var imported int64
+	assert.NoError(t, db.Model(&models.Issue{}).Where("project_id = ?", ops.ID).Count(&imported).Error)
+	assert.Equal(t, int64(0), imported)
+
+	assert.NoError(t, runIssue(db, []string{"import", "-project", "OPS", "-file", unknown, "-reporter", "pm@demo.local"}, &out))
+	assert.NoError(t, db.Model(&models.Issue{}).Where("project_id = ?", ops.ID).Count(&imported).Error)
+	assert.Equal(t, int64(len(exported)), imported)
+
+	var first models.Issue
+	assert.NoError(t, db.Preload("Replies").Where("key = ?", "OPS-1").First(&first).Error)
+	assert.Equal(t, exported[0].Title, first.Title)
+	assert.Len(t, first.Replies, len(exported[0].Replies))
+	assert.True(t, first.CreatedAt.Equal(exported[0].CreatedAt))
+}
+
+func TestTokenCommand(t *testing.T) {
+	db := setupCommands(t)
+	// The command only uses the database it is given.
+	database.DB = nil
+	var out bytes.Buffer
+
+	dev, _ := findUserByEmail(db, "dev@demo.local")
+	for _, prefix := range []string{"pat_aaaaaaaa", "pat_bbbbbbbb"} {
+		token := models.PersonalAccessToken{UserID: dev.ID, Name: "ci", Scopes: "issues:read", Prefix: prefix, TokenHash: prefix}
+		assert.NoError(t, db.Create(&token).Error)
+	}
+
+	assert.Error(t, runToken(db, []string{"revoke"}, &out), "one of -id, -prefix or -user is required")
+	assert.NoError(t, runToken(db, []string{"revoke", "-prefix", "pat_aaaaaaaa"}, &out))
+
+	var left int64
+	assert.NoError(t, db.Model(&models.PersonalAccessToken{}).Count(&left).Error)
+	assert.Equal(t, int64(1), left)
+
+	assert.NoError(t, runToken(db, []string{"revoke", "-user", "dev@demo.local"}, &out))
+	assert.NoError(t, db.Model(&models.PersonalAccessToken{}).Count(&left).Error)
+	assert.Equal(t, int64(0), left)
+
+	dev, _ = findUserByEmail(db, "dev@demo.local")
+	assert.NotNil(t, dev.TokensRevokedAt)
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"issue-tracker/middlewares"
+	"issue-tracker/models"
+	"issue-tracker/webhooks"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IssueAssignForm takes the ID of the User who is assigned to an Issue.
+type IssueAssignForm struct {
+	UserID int `form:"user_id" binding:"required"`
+}
+
+// canAssign checks whether actor may assign or unassign a User. With
+// issue.assign.any the actor can assign everyone, with issue.assign.self only
+// themselves.
+//
+// Only Users with issue.assignable can be assigned, which AssignIssueHandler
+// checks. Anyone can be unassigned, so Users who lost the permission, were
+// deactivated or must reset their password do not stay on their Issues.
+func canAssign(actor models.PermissionSet, self bool) bool {
+	return actor.Has(models.PermIssueAssignAny) || (self && actor.Has(models.PermIssueAssignSelf))
+}
+
+// findAssignee fetches the User doing the request and the User being
+// assigned or unassigned, and checks whether the first may assign the second.
+// Aborts the request and returns false if not.
+func (s *Server) findAssignee(c *gin.Context, assigneeID int) (*models.User, *models.User, bool) {
+	actor, ok := currentUser(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	assignee, err := s.Store.Users().FindByID(uint(assigneeID))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, "Assignee not found.")
+		return nil, nil, false
+	}
+
+	if !canAssign(middlewares.Permissions(c), actor.ID == assignee.ID) {
+		returnErrorAndAbort(c, http.StatusForbidden, "User is unauthorized for this request.")
+		return nil, nil, false
+	}
+
+	return actor, assignee, true
+}
+
+// AssignIssueHandler assigns a Developer to an Issue.
+func (s *Server) AssignIssueHandler(c *gin.Context) {
+	source, err := s.findIssue(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var input IssueAssignForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	actor, assignee, ok := s.findAssignee(c, input.UserID)
+	if !ok {
+		return
+	}
+
+	assigneePermissions, err := s.Store.Users().Permissions(assignee)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !assigneePermissions.Has(models.PermIssueAssignable) {
+		returnErrorAndAbort(c, http.StatusForbidden, "User can not be assigned to issues.")
+		return
+	}
+
+	if err := s.Store.Issues().Assign(source, assignee, actor); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.Emit(models.EventIssueAssigned, source.ProjectID, actor, webhooks.Data{
+		"issue":    webhooks.Issue(source),
+		"assignee": webhooks.Actor{ID: assignee.ID, Name: assignee.Name},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"issueID":  source.ID,
+		"issueKey": source.Key,
+		"userID":   assignee.ID,
+		"msg":      "User has been assigned succesfully.",
+	})
+}
+
+// UnassignIssueHandler removes a Developer from an Issue's assignees.
+func (s *Server) UnassignIssueHandler(c *gin.Context) {
+	source, err := s.findIssue(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	assigneeID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "No user ID provided.")
+		return
+	}
+
+	actor, assignee, ok := s.findAssignee(c, assigneeID)
+	if !ok {
+		return
+	}
+
+	assigned, err := s.Store.Issues().IsAssigned(source, assignee.ID)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !assigned {
+		returnErrorAndAbort(c, http.StatusNotFound, "User is not assigned to this issue.")
+		return
+	}
+
+	if err := s.Store.Issues().Unassign(source, assignee, actor); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.Emit(models.EventIssueUnassigned, source.ProjectID, actor, webhooks.Data{
+		"issue":    webhooks.Issue(source),
+		"assignee": webhooks.Actor{ID: assignee.ID, Name: assignee.Name},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"issueID":  source.ID,
+		"issueKey": source.Key,
+		"userID":   assignee.ID,
+		"msg":      "User has been unassigned succesfully.",
+	})
+}
+
+// IndexAssignedIssueHandler shows the Issues assigned to a User, one page at a
+// time. Takes the same query parameters as IndexIssueHandler.
+//
+// /v1/protected/user/:id/assigned
+func (s *Server) IndexAssignedIssueHandler(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "No user ID provided.")
+		return
+	}
+
+	filter, err := bindIssueFilter(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.AssigneeID = userID
+
+	result, total, err := s.Store.Issues().Index(filter)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":   len(*result),
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+		"links": pageLinks(c, filter.Page, filter.Limit, total),
+		"data":  result,
+	})
+}
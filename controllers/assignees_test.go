@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"issue-tracker/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanAssign(t *testing.T) {
+	qa := models.PermissionSet{models.PermIssueAssignAny: true}
+	dev := models.PermissionSet{models.PermIssueAssignSelf: true, models.PermIssueAssignable: true}
+	viewer := models.PermissionSet{models.PermIssueRead: true}
+
+	assert.True(t, canAssign(qa, false))
+	assert.True(t, canAssign(dev, true))
+	assert.False(t, canAssign(dev, false), "developers can only assign themselves")
+	assert.False(t, canAssign(viewer, false))
+	assert.False(t, canAssign(viewer, true))
+}
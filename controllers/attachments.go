@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"fmt"
+	"issue-tracker/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AttachmentUploadForm takes the uploaded file. With a ReplyID the file is
+// attached to that Reply of the Issue.
+type AttachmentUploadForm struct {
+	ReplyID uint `form:"reply_id"`
+}
+
+// UploadAttachmentHandler attaches a file to an Issue or one of its Replies.
+//
+// Takes a multipart form with the file in "file".
+func UploadAttachmentHandler(c *gin.Context) {
+	// Leaves room for the other parts of the form.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxAttachmentSize+1<<20)
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	source, err := findIssueFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var input AttachmentUploadForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "ERROR FILE: a file is required")
+		return
+	}
+	if header.Size > models.MaxAttachmentSize {
+		returnErrorAndAbort(c, http.StatusRequestEntityTooLarge, models.ErrAttachmentTooLarge.Error())
+		return
+	}
+
+	attachment := models.Attachment{
+		IssueID:  source.ID,
+		UserID:   user.ID,
+		FileName: header.Filename,
+	}
+
+	if input.ReplyID != 0 {
+		var reply models.Reply
+		replySource := reply.FindReplyByID(input.ReplyID)
+		if replySource == nil || replySource.IssueID != source.ID {
+			returnErrorAndAbort(c, http.StatusNotFound, "Reply not found.")
+			return
+		}
+		attachment.ReplyID = &replySource.ID
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	err = attachment.SaveAttachment(file)
+	switch err {
+	case nil:
+	case models.ErrAttachmentTooLarge:
+		returnErrorAndAbort(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case models.ErrAttachmentType:
+		returnErrorAndAbort(c, http.StatusUnsupportedMediaType, err.Error())
+		return
+	default:
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"attachmentID": attachment.ID,
+		"data":         attachment,
+		"msg":          "File has been attached succesfully.",
+	})
+}
+
+// IndexAttachmentHandler shows the Attachments of an Issue and of its Replies.
+func IndexAttachmentHandler(c *gin.Context) {
+	var attachment models.Attachment
+
+	source, err := findIssueFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := attachment.IndexAttachments(source.ID)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":  len(*result),
+		"data": result,
+	})
+}
+
+// DownloadAttachmentHandler sends the content of an Attachment.
+//
+// The file is always sent as a download, never shown inline, so an uploaded
+// HTML or SVG file can not run in the tracker's origin.
+func DownloadAttachmentHandler(c *gin.Context) {
+	var attachment models.Attachment
+
+	source, err := findIssueFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	attachmentSource, err := attachment.FindAttachmentByID(source.ID, c.Param("attachmentId"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	content, err := attachmentSource.Open()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, attachmentSource.Size, attachmentSource.ContentType, content, map[string]string{
+		"Content-Disposition":    fmt.Sprintf("attachment; filename=%s", strconv.Quote(attachmentSource.FileName)),
+		"X-Content-Type-Options": "nosniff",
+		"X-Checksum-SHA256":      attachmentSource.Checksum,
+	})
+}
+
+// DeleteAttachmentHandler deletes an Attachment and its content.
+//
+// The User who uploaded the file can delete it with attachment.delete.own,
+// everyone else needs attachment.delete.any.
+func DeleteAttachmentHandler(c *gin.Context) {
+	var attachment models.Attachment
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	source, err := findIssueFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	attachmentSource, err := attachment.FindAttachmentByID(source.ID, c.Param("attachmentId"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if !canModify(c, attachmentSource.UserID == user.ID, models.PermAttachmentDelOwn, models.PermAttachmentDelAny) {
+		returnErrorAndAbort(c, http.StatusForbidden, "This user is not allowed to delete this attachment.")
+		return
+	}
+
+	if err := attachmentSource.DeleteAttachment(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "Unable to delete attachment")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{
+		"data": "deleted",
+		"msg":  "attachment is deleted successfully",
+	})
+}
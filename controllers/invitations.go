@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"fmt"
+	"issue-tracker/auth"
+	"issue-tracker/mailer"
+	"issue-tracker/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// invitationLifetime is how long an Invitation can be accepted.
+const invitationLifetime = 7 * 24 * time.Hour
+
+// InviteOnly allows registering only with an Invitation.
+var InviteOnly bool
+
+// InvitationForm takes the e-mail of the invitee and the ID of their Role.
+type InvitationForm struct {
+	Email  string `form:"email" binding:"required"`
+	RoleID int    `form:"role" binding:"required"`
+}
+
+// CreateInvitationHandler invites someone to register with a Role. The
+// invitation is e-mailed to them.
+func CreateInvitationHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input InvitationForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error generating invitation token")
+		return
+	}
+
+	invitation := models.Invitation{
+		Email:       input.Email,
+		RoleID:      input.RoleID,
+		InvitedByID: user.ID,
+		TokenHash:   auth.HashToken(token),
+		ExpiresAt:   time.Now().Add(invitationLifetime),
+	}
+	if err := invitation.ValidateInvitation(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := invitation.SaveInvitation(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sendMail(mailer.Message{
+		To:      invitation.Email,
+		Subject: "You are invited to Purge",
+		Body: fmt.Sprintf("Hi,\n\n%s invited you to join Purge as %s.\n\n%s\n\nThe invitation expires in %d days.\n",
+			user.Name, invitation.Role.Name,
+			tokenInstructions("INVITATION_URL", "register", token),
+			int(invitationLifetime.Hours()/24)),
+	}, fmt.Sprintf("invitation %d", invitation.ID))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invitationID": invitation.ID,
+		"msg":          "Invitation has been sent succesfully.",
+	})
+}
+
+// IndexInvitationHandler shows the Invitations which were not revoked.
+func IndexInvitationHandler(c *gin.Context) {
+	var invitation models.Invitation
+
+	result, err := invitation.IndexInvitations()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":  len(*result),
+		"data": result,
+	})
+}
+
+// DeleteInvitationHandler revokes an Invitation which was not accepted yet.
+func DeleteInvitationHandler(c *gin.Context) {
+	var invitation models.Invitation
+
+	source, err := invitation.FindInvitationByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if err := source.DeleteInvitation(); err != nil {
+		returnErrorAndAbort(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{
+		"data": "deleted",
+		"msg":  "invitation is deleted successfully",
+	})
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"issue-tracker/auth"
+	"issue-tracker/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createInvitation saves an Invitation to the e-mail as a Viewer, with the
+// token.
+func createInvitation(t *testing.T, email string, token string) *models.Invitation {
+	invitation := models.Invitation{
+		Email:     email,
+		RoleID:    models.RoleViewer,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := invitation.SaveInvitation(); err != nil {
+		t.Fatal(err)
+	}
+	return &invitation
+}
+
+func registerInvited(email string, role int, token string) int {
+	form := url.Values{"name": {"invitee"}, "email": {email}, "password": {"secret"},
+		"role": {strconv.Itoa(role)}, "invitation": {token}}
+	return register(form).Code
+}
+
+func TestRegisterWithInvitation(t *testing.T) {
+	setupSQLite(t)
+	useMemoryMailer(t)
+	createInvitation(t, "invitee@email.com", "invite")
+
+	assert.Equal(t, http.StatusForbidden, registerInvited("other@email.com", models.RoleQA, "invite"),
+		"the invitation is for another e-mail")
+
+	assert.Equal(t, http.StatusCreated, registerInvited("Invitee@email.com", models.RoleAdmin, "invite"))
+	user := (&models.User{Email: "invitee@email.com"}).GetUserByEmail()
+	if assert.NotNil(t, user) {
+		assert.Equal(t, models.RoleViewer, user.RoleID, "the submitted role is ignored")
+		assert.NotNil(t, user.EmailVerifiedAt)
+	}
+
+	assert.Equal(t, http.StatusForbidden, registerInvited("invitee@email.com", models.RoleViewer, "invite"),
+		"an accepted invitation can not be used again")
+}
+
+func TestRegisterWithRevokedInvitation(t *testing.T) {
+	setupSQLite(t)
+	useMemoryMailer(t)
+	invitation := createInvitation(t, "invitee@email.com", "invite")
+	assert.NoError(t, invitation.DeleteInvitation())
+
+	assert.Equal(t, http.StatusForbidden, registerInvited("invitee@email.com", models.RoleViewer, "invite"))
+}
+
+func TestRegisterInviteOnly(t *testing.T) {
+	setupSQLite(t)
+	useMemoryMailer(t)
+	defer func(inviteOnly bool) { InviteOnly = inviteOnly }(InviteOnly)
+	InviteOnly = true
+
+	rec := register(url.Values{"name": {"qa"}, "email": {"qa@email.com"}, "password": {"secret"}, "role": {"1"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	createInvitation(t, "invitee@email.com", "invite")
+	assert.Equal(t, http.StatusCreated, registerInvited("invitee@email.com", models.RoleViewer, "invite"))
+}
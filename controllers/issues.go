@@ -1,28 +1,68 @@
 package controllers
 
 import (
+	"fmt"
+	"issue-tracker/middlewares"
 	"issue-tracker/models"
+	"issue-tracker/webhooks"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // IssueCreateForm takes User's input on Issue Create form.
+//
+// Project is the Project's Key. It is only read on the global create route,
+// the project-scoped route takes the Key from the URL instead.
 type IssueCreateForm struct {
+	Project  string `form:"project"`
 	Title    string `form:"title" binding:"required"`
 	Body     string `form:"description" binding:"required"`
 	Severity string `form:"severity" binding:"required"`
 }
 
-// IssueUpdateForm is for Updating.
+// IssueUpdateForm is for Updating. The Status is changed with the transition
+// request instead, see TransitionIssueHandler.
 type IssueUpdateForm struct {
 	Title    string `form:"title" binding:"required"`
 	Body     string `form:"description" binding:"required"`
-	Status   string `form:"status" binding:"required"`
 	Severity string `form:"severity" binding:"required"`
 }
 
+// IssueIndexQuery takes the query parameters of the Issue Index.
+//
+// status and severity take comma separated values, for example status=1,2.
+// status takes Status IDs. label_any, label_all and label_none take comma
+// separated Label IDs.
+// Dates are either 2006-01-02 or RFC 3339. A date-only "to" value includes
+// the whole day.
+type IssueIndexQuery struct {
+	Page        int    `form:"page"`
+	Limit       int    `form:"limit"`
+	Status      string `form:"status"`
+	Severity    string `form:"severity"`
+	Author      int    `form:"author"`
+	Updater     int    `form:"updater"`
+	Assignee    int    `form:"assignee"`
+	LabelAny    string `form:"label_any"`
+	LabelAll    string `form:"label_all"`
+	LabelNone   string `form:"label_none"`
+	CreatedFrom string `form:"created_from"`
+	CreatedTo   string `form:"created_to"`
+	UpdatedFrom string `form:"updated_from"`
+	UpdatedTo   string `form:"updated_to"`
+	Sort        string `form:"sort"`
+	Order       string `form:"order"`
+}
+
+const (
+	defaultIndexLimit = 20
+	maxIndexLimit     = 100
+)
+
 // returnErrorAndAbort returns a JSON with "error": errorText in it. After that,
 // it aborts and stop the running function.
 //
@@ -34,8 +74,32 @@ func returnErrorAndAbort(ctx *gin.Context, code int, errorText string) {
 	ctx.Abort()
 }
 
+// currentUser returns the User doing the request, who was authenticated by
+// middlewares.AuthJWT. Without one, it returns the error to the client, aborts
+// and returns false.
+func currentUser(c *gin.Context) (*models.User, bool) {
+	user := middlewares.CurrentUser(c)
+	if user == nil {
+		returnErrorAndAbort(c, http.StatusUnauthorized, "User is not authenticated.")
+		return nil, false
+	}
+	return user, true
+}
+
+// hasPermission checks whether the User's Role has at least one of the
+// permissions. Only works on routes behind middlewares.RequirePermission.
+func hasPermission(c *gin.Context, permissions ...string) bool {
+	return middlewares.Permissions(c).Has(permissions...)
+}
+
+// canModify checks whether the User may act on something which is their own
+// (isOwner) with the own permission, or on anything with the any permission.
+func canModify(c *gin.Context, isOwner bool, own string, any string) bool {
+	return hasPermission(c, any) || (isOwner && hasPermission(c, own))
+}
+
 // CreateIssueHandler handles issue creation.
-func CreateIssueHandler(c *gin.Context) {
+func (s *Server) CreateIssueHandler(c *gin.Context) {
 	// Bind the input to variable.
 	var input IssueCreateForm
 	if err := c.ShouldBind(&input); err != nil {
@@ -44,41 +108,272 @@ func CreateIssueHandler(c *gin.Context) {
 	}
 
 	// Begin save Issue process.
-	headerUserID := c.Request.Header.Get("userID")
-	userID, err := strconv.Atoi(headerUserID)
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
 
+	project, err := s.findProject(c)
 	if err != nil {
-		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
 		return
 	}
+	if project == nil {
+		if input.Project == "" {
+			returnErrorAndAbort(c, http.StatusBadRequest, "Project is required.")
+			return
+		}
+		project, err = s.Store.Projects().FindByKey(input.Project)
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+			return
+		}
+	}
 
 	issue := models.Issue{
-		UserID:   userID,
+		UserID:   int(user.ID),
 		Title:    input.Title,
 		Body:     input.Body,
-		Status:   "1",
+		StatusID: models.StatusOpen,
 		Severity: input.Severity,
 	}
 	if err := issue.ValidateIssue(); err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := issue.SaveIssue(); err != nil {
+	if err := s.Store.Issues().Create(&issue, project); err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	s.Emit(models.EventIssueCreated, issue.ProjectID, user, webhooks.Issue(&issue))
 
 	c.JSON(http.StatusCreated, gin.H{
-		"issueId": issue.ID,
-		"msg":     "Data succesfully created.",
+		"issueId":  issue.ID,
+		"issueKey": issue.Key,
+		"msg":      "Data succesfully created.",
 	})
 }
 
-// IndexIssueHandler shows ALL issues. 😢😢😢😢😢
-func IndexIssueHandler(c *gin.Context) {
-	var issue models.Issue
-	result, err := issue.IndexIssues()
+// IndexIssueHandler shows issues, one page at a time.
+//
+// On the project-scoped route only the Issues of that Project are shown.
+// See IssueIndexQuery for the filters.
+func (s *Server) IndexIssueHandler(c *gin.Context) {
+	filter, err := bindIssueFilter(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	project, err := s.findProject(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if project != nil {
+		filter.ProjectID = project.ID
+	}
+
+	result, total, err := s.Store.Issues().Index(filter)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":   len(*result),
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+		"links": pageLinks(c, filter.Page, filter.Limit, total),
+		"data":  result,
+	})
+}
+
+// bindIssueFilter binds the IssueIndexQuery and turns it into a models.IssueFilter.
+func bindIssueFilter(c *gin.Context) (models.IssueFilter, error) {
+	var input IssueIndexQuery
+	if err := c.ShouldBindQuery(&input); err != nil {
+		return models.IssueFilter{}, err
+	}
+
+	status, err := splitIntList(input.Status)
+	if err != nil {
+		return models.IssueFilter{}, err
+	}
+	labelAny, err := splitIntList(input.LabelAny)
+	if err != nil {
+		return models.IssueFilter{}, err
+	}
+	labelAll, err := splitIntList(input.LabelAll)
+	if err != nil {
+		return models.IssueFilter{}, err
+	}
+	labelNone, err := splitIntList(input.LabelNone)
+	if err != nil {
+		return models.IssueFilter{}, err
+	}
+
+	filter := models.IssueFilter{
+		Status:     status,
+		Severity:   splitList(input.Severity),
+		AuthorID:   input.Author,
+		UpdaterID:  input.Updater,
+		AssigneeID: input.Assignee,
+		LabelAny:   labelAny,
+		LabelAll:   labelAll,
+		LabelNone:  labelNone,
+		Sort:       input.Sort,
+		Page:       input.Page,
+		Limit:      input.Limit,
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = "id"
+	}
+	if _, ok := models.IssueSortColumns[filter.Sort]; !ok {
+		return filter, fmt.Errorf("ERROR SORT: can not sort by %s", filter.Sort)
+	}
+
+	switch strings.ToLower(input.Order) {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, fmt.Errorf("ERROR ORDER: order must be asc or desc")
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = defaultIndexLimit
+	}
+	if filter.Limit > maxIndexLimit {
+		filter.Limit = maxIndexLimit
+	}
+
+	if filter.CreatedFrom, err = parseDateParam(input.CreatedFrom, false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseDateParam(input.CreatedTo, true); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedFrom, err = parseDateParam(input.UpdatedFrom, false); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedTo, err = parseDateParam(input.UpdatedTo, true); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// splitList splits a comma separated query value. Returns nil if it is empty.
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// splitIntList splits a comma separated query value of IDs.
+func splitIntList(value string) ([]int, error) {
+	var result []int
+	for _, item := range splitList(value) {
+		number, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: %s is not a valid ID", item)
+		}
+		result = append(result, number)
+	}
+	return result, nil
+}
+
+// parseDateParam parses a 2006-01-02 or RFC 3339 date. Returns nil if value is
+// empty.
+//
+// If endOfRange is true, a date-only value is moved to the start of the next
+// day so that the whole day is included by a "less than" comparison.
+func parseDateParam(value string, endOfRange bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
 
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR DATE: %s is not a valid date", value)
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// pageLinks builds the links to the next and previous page of the current
+// request. A link is null if there is no such page.
+func pageLinks(c *gin.Context, page int, limit int, total int64) gin.H {
+	link := func(target int) *string {
+		query := c.Request.URL.Query()
+		query.Set("page", strconv.Itoa(target))
+		query.Set("limit", strconv.Itoa(limit))
+		result := c.Request.URL.Path + "?" + query.Encode()
+		return &result
+	}
+
+	links := gin.H{"next": nil, "prev": nil}
+	if int64(page*limit) < total {
+		links["next"] = link(page + 1)
+	}
+	if page > 1 {
+		links["prev"] = link(page - 1)
+	}
+	return links
+}
+
+// IssueSearchQuery takes the query parameters of the Issue Search.
+type IssueSearchQuery struct {
+	Q     string `form:"q" binding:"required"`
+	Limit int    `form:"limit"`
+}
+
+// SearchIssueHandler searches Issues and their Replies by text.
+//
+// /v1/protected/issue/search?q=login+crash
+//
+// On the project-scoped route only the Issues of that Project are searched.
+func (s *Server) SearchIssueHandler(c *gin.Context) {
+	var projectID uint
+
+	var input IssueSearchQuery
+	if err := c.ShouldBindQuery(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.Limit < 1 {
+		input.Limit = defaultIndexLimit
+	}
+	if input.Limit > maxIndexLimit {
+		input.Limit = maxIndexLimit
+	}
+
+	project, err := s.findProject(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if project != nil {
+		projectID = project.ID
+	}
+
+	result, err := s.Store.Issues().Search(input.Q, projectID, input.Limit)
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
@@ -92,37 +387,86 @@ func IndexIssueHandler(c *gin.Context) {
 
 // ShowIssueHandler fetch ONE issue by ID.
 //
-// /v1/protected/issue/:id
+// /v1/protected/issue/show/:id
 //
-// For example: /v1/protected/issue/1
-func ShowIssueHandler(c *gin.Context) {
-	var issue models.Issue
+// For example: /v1/protected/issue/show/1
+//
+// or by its Number inside a Project, for example /v1/protected/project/WEB/issue/show/42
+func (s *Server) ShowIssueHandler(c *gin.Context) {
+	source, err := s.findIssue(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, replies, err := s.Store.Issues().Show(source)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	assignees, err := s.Store.Issues().Assignees(source)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	id := c.Param("id")
-	result, replies, err := issue.FindIssueAndRepliesByID(id)
+	labels, err := s.Store.Issues().Labels(source)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
+	attachments, err := s.Store.Issues().Attachments(source)
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"issue":   result,
-		"replies": replies,
+		"issue":       result,
+		"assignees":   assignees,
+		"labels":      labels,
+		"attachments": attachments,
+		"replies":     replies,
+	})
+}
+
+// IssueHistoryHandler shows every recorded change of an Issue, oldest first.
+//
+// /v1/protected/issue/show/:id/history
+func (s *Server) IssueHistoryHandler(c *gin.Context) {
+	source, err := s.findIssue(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := s.Store.Issues().History(source)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"issueID":  source.ID,
+		"issueKey": source.Key,
+		"qty":      len(*result),
+		"data":     result,
 	})
 }
 
 // UpdateIssueHandler is used for updating. Works similarly to CreateIssueHandler.
 //
-// Only the poster and developer can Update an Issue.
-func UpdateIssueHandler(c *gin.Context) {
+// The poster can Update an Issue with issue.edit.own, everyone else needs
+// issue.edit.any.
+func (s *Server) UpdateIssueHandler(c *gin.Context) {
 	var issue models.Issue
-	var user models.User
 
-	// Get Issue ID from param
+	// Get Issue from param
 	// For example, update/3
 	// get that 3.
-	id := c.Param("id")
-	source, err := issue.FindOneIssueByID(id)
+	source, err := s.findIssue(c)
 
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
@@ -135,32 +479,20 @@ func UpdateIssueHandler(c *gin.Context) {
 		return
 	}
 
-	HeaderUserID := c.Request.Header.Get("userID")
-	userID, err := strconv.Atoi(HeaderUserID)
-	if err != nil {
-		returnErrorAndAbort(c, http.StatusBadRequest, "couldn't parse UserID into Int")
-		return
-	}
-
-	userSource := user.GetUserByID(userID)
-	if userSource == nil {
-		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+	userSource, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
 	// Checks whether User with the same ID can do this request.
-	if userID != source.UserID {
-		// Checks whether a User with different ID as the poster/author is a Developer.
-		if userSource.RoleID != 2 {
-			returnErrorAndAbort(c, http.StatusBadRequest, "User is unauthorized for this request.")
-			return
-		}
+	if !canModify(c, int(userSource.ID) == source.UserID, models.PermIssueEditOwn, models.PermIssueEditAny) {
+		returnErrorAndAbort(c, http.StatusBadRequest, "User is unauthorized for this request.")
+		return
 	}
 
 	issue = models.Issue{
 		Title:             input.Title,
 		Body:              input.Body,
-		Status:            input.Status,
 		Severity:          input.Severity,
 		UpdatedByUserID:   int(userSource.ID),
 		UpdatedByUserName: userSource.Name,
@@ -170,51 +502,46 @@ func UpdateIssueHandler(c *gin.Context) {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := issue.UpdateIssue(source); err != nil {
-		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	idNum, err := strconv.Atoi(id)
-	if err != nil {
+	if err := s.Store.Issues().Update(source, &issue); err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	s.Emit(models.EventIssueUpdated, source.ProjectID, userSource, webhooks.Issue(source))
 
 	c.JSON(http.StatusOK, gin.H{
-		"issueID": idNum,
-		"msg":     "Data has been updated succesfully.",
+		"issueID":  source.ID,
+		"issueKey": source.Key,
+		"msg":      "Data has been updated succesfully.",
 	})
 }
 
 // DeleteIssueHandler deletes an Issue by ID.
-func DeleteIssueHandler(c *gin.Context) {
-	var issue models.Issue
-
-	id := c.Param("id")
-	source, err := issue.FindOneIssueByID(id)
+//
+// The poster can Delete an Issue with issue.delete.own, everyone else needs
+// issue.delete.any.
+func (s *Server) DeleteIssueHandler(c *gin.Context) {
+	source, err := s.findIssue(c)
 
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	HeaderUserID := c.Request.Header.Get("userID")
-	userID, err := strconv.Atoi(HeaderUserID)
-	if err != nil {
-		returnErrorAndAbort(c, http.StatusBadRequest, "couldn't parse UserID into Int")
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
-	if userID != source.UserID {
+	if !canModify(c, int(user.ID) == source.UserID, models.PermIssueDeleteOwn, models.PermIssueDeleteAny) {
 		returnErrorAndAbort(c, http.StatusBadRequest, "User is unauthorized for this request.")
 		return
 	}
 
-	if err := source.DeleteIssue(); err != nil {
+	if err := s.Store.Issues().Delete(source); err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, "Unable to delete issue")
 		return
 	}
+	s.Emit(models.EventIssueDeleted, source.ProjectID, user, webhooks.Issue(source))
 
 	c.JSON(http.StatusNoContent, gin.H{
 		"data": "deleted",
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContext(target string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", target, nil)
+	return c
+}
+
+func TestBindIssueFilter(t *testing.T) {
+	c := newTestContext("/v1/protected/issue/index?status=1,6&severity=3&sort=user_name&order=desc&limit=500&created_to=2021-02-01")
+
+	filter, err := bindIssueFilter(c)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{1, 6}, filter.Status)
+	assert.Equal(t, []string{"3"}, filter.Severity)
+	assert.Equal(t, "user_name", filter.Sort)
+	assert.True(t, filter.Desc)
+	assert.Equal(t, 1, filter.Page)
+	assert.Equal(t, maxIndexLimit, filter.Limit)
+	assert.Equal(t, "2021-02-02", filter.CreatedTo.Format("2006-01-02"))
+}
+
+func TestBindIssueFilterRejectsUnknownSort(t *testing.T) {
+	c := newTestContext("/v1/protected/issue/index?sort=password")
+
+	_, err := bindIssueFilter(c)
+	assert.Error(t, err)
+}
+
+func TestPageLinks(t *testing.T) {
+	c := newTestContext("/v1/protected/issue/index?status=1&page=2&limit=10")
+
+	links := pageLinks(c, 2, 10, 25)
+	assert.Equal(t, "/v1/protected/issue/index?limit=10&page=3&status=1", *links["next"].(*string))
+	assert.Equal(t, "/v1/protected/issue/index?limit=10&page=1&status=1", *links["prev"].(*string))
+
+	links = pageLinks(c, 3, 10, 25)
+	assert.Nil(t, links["next"])
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"issue-tracker/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public keys tokens are signed with, so other
+// services can verify the tokens without sharing a secret.
+//
+// The "kid" Header of a token names the key it was signed with.
+func JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.DefaultKeys.JWKS())
+}
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"issue-tracker/models"
+	"issue-tracker/webhooks"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LabelCreateForm takes User's input on Label Create form.
+//
+// Project is the Key of the Project the Label belongs to. Without it the Label
+// is global.
+type LabelCreateForm struct {
+	Name        string `form:"name" binding:"required"`
+	Color       string `form:"color" binding:"required"`
+	Description string `form:"description"`
+	Project     string `form:"project"`
+}
+
+// LabelUpdateForm is for Updating. A Label can not be moved to another Project.
+type LabelUpdateForm struct {
+	Name        string `form:"name" binding:"required"`
+	Color       string `form:"color" binding:"required"`
+	Description string `form:"description"`
+}
+
+// IssueLabelForm takes the ID of the Label added to an Issue.
+type IssueLabelForm struct {
+	LabelID int `form:"label_id" binding:"required"`
+}
+
+// canManageLabel checks whether the User doing the request may create,
+// update or delete a Label. Aborts the request and returns false if not.
+//
+// Project Labels are managed like the Project itself, with project.edit.own or
+// project.edit.any. Global Labels need label.manage.
+func canManageLabel(c *gin.Context, projectID *uint) bool {
+	user, ok := currentUser(c)
+	if !ok {
+		return false
+	}
+
+	allowed := hasPermission(c, models.PermLabelManage)
+	if projectID != nil {
+		var project models.Project
+		source, err := project.FindProjectByID(*projectID)
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+			return false
+		}
+		allowed = canModify(c, source.OwnerID == int(user.ID), models.PermProjectEditOwn, models.PermProjectEditAny)
+	}
+
+	if !allowed {
+		returnErrorAndAbort(c, http.StatusForbidden, "User is unauthorized for this request.")
+		return false
+	}
+	return true
+}
+
+// CreateLabelHandler handles Label creation.
+func CreateLabelHandler(c *gin.Context) {
+	var input LabelCreateForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	label := models.Label{
+		Name:        input.Name,
+		Color:       input.Color,
+		Description: input.Description,
+	}
+
+	if input.Project != "" {
+		var project models.Project
+		source, err := project.FindProjectByKey(input.Project)
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+			return
+		}
+		label.ProjectID = &source.ID
+	}
+
+	if !canManageLabel(c, label.ProjectID) {
+		return
+	}
+
+	if err := label.ValidateLabel(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := label.SaveLabel(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"labelID": label.ID,
+		"msg":     "Data succesfully created.",
+	})
+}
+
+// IndexLabelHandler shows the Labels.
+//
+// With the query parameter project (a Project's Key) only the Labels usable in
+// that Project are shown.
+func IndexLabelHandler(c *gin.Context) {
+	var label models.Label
+	var projectID uint
+
+	if key := c.Query("project"); key != "" {
+		var project models.Project
+		source, err := project.FindProjectByKey(key)
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+			return
+		}
+		projectID = source.ID
+	}
+
+	result, err := label.IndexLabels(projectID)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":  len(*result),
+		"data": result,
+	})
+}
+
+// ShowLabelHandler fetch ONE label by ID.
+func ShowLabelHandler(c *gin.Context) {
+	var label models.Label
+
+	result, err := label.FindLabelByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
+
+// UpdateLabelHandler updates a Label's name, color and description.
+func UpdateLabelHandler(c *gin.Context) {
+	var label models.Label
+
+	source, err := label.FindLabelByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	var input LabelUpdateForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !canManageLabel(c, source.ProjectID) {
+		return
+	}
+
+	label = models.Label{
+		Model:       source.Model,
+		ProjectID:   source.ProjectID,
+		Name:        input.Name,
+		Color:       input.Color,
+		Description: input.Description,
+	}
+	if err := label.ValidateLabel(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := label.UpdateLabel(source); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"labelID": source.ID,
+		"msg":     "Data has been updated succesfully.",
+	})
+}
+
+// DeleteLabelHandler deletes a Label by ID and removes it from every Issue.
+func DeleteLabelHandler(c *gin.Context) {
+	var label models.Label
+
+	source, err := label.FindLabelByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if !canManageLabel(c, source.ProjectID) {
+		return
+	}
+
+	if err := source.DeleteLabel(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "Unable to delete label")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{
+		"data": "deleted",
+		"msg":  "label is deleted successfully",
+	})
+}
+
+// AddIssueLabelHandler adds a Label to an Issue.
+func AddIssueLabelHandler(c *gin.Context) {
+	var label models.Label
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	source, err := findIssueFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var input IssueLabelForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	labelSource, err := label.FindLabelByID(strconv.Itoa(input.LabelID))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if !labelSource.UsableIn(source.ProjectID) {
+		returnErrorAndAbort(c, http.StatusBadRequest, "Label belongs to another project.")
+		return
+	}
+
+	if err := source.AddLabel(labelSource, user); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	webhooks.Emit(models.EventIssueLabeled, source.ProjectID, user, webhooks.Data{
+		"issue": webhooks.Issue(source),
+		"label": labelSource,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"issueID":  source.ID,
+		"issueKey": source.Key,
+		"labelID":  labelSource.ID,
+		"msg":      "Label has been added succesfully.",
+	})
+}
+
+// RemoveIssueLabelHandler removes a Label from an Issue.
+func RemoveIssueLabelHandler(c *gin.Context) {
+	var label models.Label
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	source, err := findIssueFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	labelSource, err := label.FindLabelByID(c.Param("labelId"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if err := source.RemoveLabel(labelSource, user); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	webhooks.Emit(models.EventIssueUnlabeled, source.ProjectID, user, webhooks.Data{
+		"issue": webhooks.Issue(source),
+		"label": labelSource,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"issueID":  source.ID,
+		"issueKey": source.Key,
+		"labelID":  labelSource.ID,
+		"msg":      "Label has been removed succesfully.",
+	})
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"fmt"
+	"issue-tracker/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// invalidCredentials is the error for a wrong e-mail or password, which does
+// not tell whether the e-mail has an account.
+const invalidCredentials = "Invalid email or password."
+
+// loginLockout is how long failed logins are counted, and how long a locked
+// out account or IP has to wait.
+const loginLockout = 15 * time.Minute
+
+// loginThrottle slows down guessing the passwords of an account or from an IP.
+//
+// After delayAfter failed logins, the next login has to wait one second after
+// the last failure, doubling with every failure up to maxDelay. After
+// lockAfter failed logins, it has to wait loginLockout.
+type loginThrottle struct {
+	delayAfter int64
+	lockAfter  int64
+	maxDelay   time.Duration
+}
+
+var (
+	// accountThrottle applies to the logins with an e-mail.
+	accountThrottle = loginThrottle{delayAfter: 3, lockAfter: 10, maxDelay: time.Minute}
+	// ipThrottle applies to the logins from an IP, for any e-mail. It is looser
+	// since many Users can share an IP.
+	ipThrottle = loginThrottle{delayAfter: 20, lockAfter: 100, maxDelay: time.Minute}
+)
+
+// dummyPasswordHash is compared with the password for an unknown e-mail, so it
+// takes as long as for an existing one.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// wait returns how long after the last failure the next login has to wait.
+func (t loginThrottle) wait(failures int64) time.Duration {
+	if failures >= t.lockAfter {
+		return loginLockout
+	}
+	if failures < t.delayAfter {
+		return 0
+	}
+
+	delay := time.Second
+	for i := t.delayAfter; i < failures && delay < t.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.maxDelay {
+		delay = t.maxDelay
+	}
+	return delay
+}
+
+// retryAfter returns how long from now the next login has to wait.
+func (t loginThrottle) retryAfter(failures *models.LoginFailures, now time.Time) time.Duration {
+	wait := t.wait(failures.Count)
+	if wait == 0 {
+		return 0
+	}
+
+	remaining := failures.Last.Add(wait).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// loginAttemptKey is the key of the pending LoginAttempt in the gin.Context.
+const loginAttemptKey = "loginAttempt"
+
+// checkLoginThrottle checks whether a login with the e-mail is allowed now.
+// user is the User with the e-mail, if any. Aborts the request with 429 and
+// returns false if it has to wait.
+//
+// The login is saved as pending before the failures are counted, and counts as
+// a failure until recordLoginAttempt saves its result, or finishLoginAttempt
+// saves it as an error. So concurrent logins
+// are throttled as if they came one after the other.
+//
+// The e-mail is throttled whether it has an account or not, so the response
+// does not tell.
+func checkLoginThrottle(c *gin.Context, email string, user *models.User) bool {
+	attempt := newLoginAttempt(c, email, user, models.LoginPending)
+	if err := attempt.SaveLoginAttempt(); err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	c.Set(loginAttemptKey, attempt)
+
+	now := time.Now()
+	since := now.Add(-loginLockout)
+
+	accountFailures, err := attempt.AccountFailures(email, since)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	ipFailures, err := attempt.IPFailures(c.ClientIP(), since)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+
+	retryAfter := accountThrottle.retryAfter(accountFailures, now)
+	if ipRetryAfter := ipThrottle.retryAfter(ipFailures, now); ipRetryAfter > retryAfter {
+		retryAfter = ipRetryAfter
+	}
+	if retryAfter == 0 {
+		return true
+	}
+
+	recordLoginAttempt(c, email, user, models.LoginThrottled)
+
+	c.Header("Retry-After", fmt.Sprint(int(retryAfter.Seconds())+1))
+	returnErrorAndAbort(c, http.StatusTooManyRequests, "Too many failed login attempts. Please try again later.")
+	return false
+}
+
+// recordLoginAttempt saves the audit record of a login. The result of the
+// login checkLoginThrottle saved as pending is saved on it.
+func recordLoginAttempt(c *gin.Context, email string, user *models.User, result string) {
+	if value, ok := c.Get(loginAttemptKey); ok {
+		attempt := value.(*models.LoginAttempt)
+		delete(c.Keys, loginAttemptKey)
+		if user != nil {
+			attempt.UserID = &user.ID
+		}
+		if err := attempt.SaveResult(result); err != nil {
+			log.Printf("ERROR LOGIN AUDIT: %s", err.Error())
+		}
+		return
+	}
+
+	if err := newLoginAttempt(c, email, user, result).SaveLoginAttempt(); err != nil {
+		log.Printf("ERROR LOGIN AUDIT: %s", err.Error())
+	}
+}
+
+// finishLoginAttempt saves the login checkLoginThrottle saved as pending as an
+// error, if the handler returned without its result. Deferred by the login
+// handlers, so a login which fails on the server does not count as a failed
+// guess.
+func finishLoginAttempt(c *gin.Context) {
+	if _, ok := c.Get(loginAttemptKey); ok {
+		recordLoginAttempt(c, "", nil, models.LoginError)
+	}
+}
+
+func newLoginAttempt(c *gin.Context, email string, user *models.User, result string) *models.LoginAttempt {
+	attempt := models.LoginAttempt{
+		Email:     email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   result == models.LoginSucceeded,
+		Result:    result,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+	return &attempt
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"issue-tracker/database"
+	"issue-tracker/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginThrottleWait(t *testing.T) {
+	throttle := loginThrottle{delayAfter: 3, lockAfter: 10, maxDelay: time.Minute}
+
+	assert.Equal(t, time.Duration(0), throttle.wait(0))
+	assert.Equal(t, time.Duration(0), throttle.wait(2))
+	assert.Equal(t, time.Second, throttle.wait(3))
+	assert.Equal(t, 2*time.Second, throttle.wait(4))
+	assert.Equal(t, 32*time.Second, throttle.wait(8))
+	assert.Equal(t, time.Minute, throttle.wait(9), "the delay is capped")
+	assert.Equal(t, loginLockout, throttle.wait(10))
+	assert.Equal(t, loginLockout, throttle.wait(1000))
+}
+
+func TestLoginThrottleRetryAfter(t *testing.T) {
+	throttle := loginThrottle{delayAfter: 3, lockAfter: 10, maxDelay: time.Minute}
+	now := time.Now()
+
+	failures := &models.LoginFailures{Count: 4, Last: now.Add(-time.Second)}
+	assert.Equal(t, time.Second, throttle.retryAfter(failures, now))
+
+	failures.Last = now.Add(-time.Minute)
+	assert.Equal(t, time.Duration(0), throttle.retryAfter(failures, now))
+
+	failures = &models.LoginFailures{Count: 10, Last: now.Add(-5 * time.Minute)}
+	assert.Equal(t, 10*time.Minute, throttle.retryAfter(failures, now))
+}
+
+func TestLoginThrottleConcurrentFailures(t *testing.T) {
+	setupSQLite(t)
+	user := createVerifiedUser(t, "qa@email.com", "right password", models.RoleQA)
+	// Checking the password takes as long as in production, so the guesses
+	// overlap.
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("right password"), bcrypt.DefaultCost)
+	database.DB.Model(user).Update("password", hashed)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/login", LoginHandler)
+
+	const guesses = 10
+	codes := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			form := url.Values{"email": {"qa@email.com"}, "password": {"wrong password"}}
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			codes <- rec.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, int(accountThrottle.delayAfter), counts[http.StatusUnauthorized],
+		"only the guesses before the delay are checked, however many run at once")
+	assert.Equal(t, guesses-int(accountThrottle.delayAfter), counts[http.StatusTooManyRequests])
+
+	var pending int64
+	database.DB.Model(&models.LoginAttempt{}).Where("result = ?", models.LoginPending).Count(&pending)
+	assert.Zero(t, pending, "every attempt has its result saved")
+}
+
+func TestLoginThrottleServerErrors(t *testing.T) {
+	setupSQLite(t)
+	user := createVerifiedUser(t, "qa@email.com", "right password", models.RoleQA)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// Fails on the server after the login is saved as pending.
+	r.POST("/failing", func(c *gin.Context) {
+		defer finishLoginAttempt(c)
+		if !checkLoginThrottle(c, user.Email, user) {
+			return
+		}
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error signing token")
+	})
+	r.POST("/login", LoginHandler)
+
+	post := func(path string) int {
+		form := url.Values{"email": {"qa@email.com"}, "password": {"right password"}}
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < int(accountThrottle.lockAfter)+1; i++ {
+		assert.Equal(t, http.StatusInternalServerError, post("/failing"))
+	}
+	assert.Equal(t, http.StatusCreated, post("/login"), "server errors do not lock out the account")
+
+	var errors int64
+	database.DB.Model(&models.LoginAttempt{}).Where("result = ?", models.LoginError).Count(&errors)
+	assert.Equal(t, accountThrottle.lockAfter+1, errors)
+}
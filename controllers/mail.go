@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"fmt"
+	"issue-tracker/mailer"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// tokenInstructions tells how to use an e-mailed token. With the URL in the
+// urlEnv environment variable (a page of the front) it is a link with the
+// token in the "token" query parameter, otherwise only the token.
+func tokenInstructions(urlEnv string, action string, token string) string {
+	pageURL := os.Getenv(urlEnv)
+	if pageURL == "" {
+		return fmt.Sprintf("Use this code to %s:\n\n%s", action, token)
+	}
+
+	separator := "?"
+	if strings.Contains(pageURL, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("Open this link to %s:\n\n%s%stoken=%s", action, pageURL, separator, url.QueryEscape(token))
+}
+
+// sendMail sends the Message in the background, so how long a response takes
+// does not tell whether an e-mail was sent. Failures are logged.
+func sendMail(msg mailer.Message, purpose string) {
+	go func() {
+		if err := mailer.Default.Send(msg); err != nil {
+			log.Printf("ERROR MAIL: %s: %s", purpose, err)
+		}
+	}()
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"issue-tracker/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationIndexQuery takes the query parameters of the Notification Index.
+type NotificationIndexQuery struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
+}
+
+// IndexNotificationHandler shows the User's Notifications, unread first.
+//
+// /v1/protected/notification/index?page=2&limit=50
+func IndexNotificationHandler(c *gin.Context) {
+	var notification models.Notification
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input NotificationIndexQuery
+	if err := c.ShouldBindQuery(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.Page < 1 {
+		input.Page = 1
+	}
+	if input.Limit < 1 {
+		input.Limit = defaultIndexLimit
+	}
+	if input.Limit > maxIndexLimit {
+		input.Limit = maxIndexLimit
+	}
+
+	result, total, err := notification.IndexNotifications(int(user.ID), input.Page, input.Limit)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":   len(*result),
+		"total": total,
+		"page":  input.Page,
+		"limit": input.Limit,
+		"links": pageLinks(c, input.Page, input.Limit, total),
+		"data":  result,
+	})
+}
+
+// UnreadCountNotificationHandler shows how many Notifications the User has
+// not read yet.
+func UnreadCountNotificationHandler(c *gin.Context) {
+	var notification models.Notification
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	count, err := notification.CountUnread(int(user.ID))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"unread": count,
+	})
+}
+
+// ReadNotificationHandler marks one of the User's Notifications as read.
+func ReadNotificationHandler(c *gin.Context) {
+	var notification models.Notification
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	source, err := notification.FindNotificationByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	// Someone else's Notification is reported as missing.
+	if source.UserID != int(user.ID) {
+		returnErrorAndAbort(c, http.StatusNotFound, "Notification not found.")
+		return
+	}
+
+	if err := source.MarkRead(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": source,
+		"msg":  "Notification has been marked as read.",
+	})
+}
+
+// ReadAllNotificationHandler marks every unread Notification of the User as
+// read.
+func ReadAllNotificationHandler(c *gin.Context) {
+	var notification models.Notification
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	count, err := notification.MarkAllRead(int(user.ID))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty": count,
+		"msg": "All notifications have been marked as read.",
+	})
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"fmt"
+	"issue-tracker/auth"
+	"issue-tracker/mailer"
+	"issue-tracker/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// passwordResetLifetime is how long a password reset token can be used.
+	passwordResetLifetime = time.Hour
+	// passwordResetInterval is how long a User waits for another password
+	// reset e-mail, so the endpoint can not flood their inbox.
+	passwordResetInterval = time.Minute
+)
+
+// ForgotPasswordForm takes the e-mail of the User who forgot their password.
+type ForgotPasswordForm struct {
+	Email string `form:"email" binding:"required"`
+}
+
+// ResetPasswordForm takes the e-mailed token and the new password.
+type ResetPasswordForm struct {
+	Token           string `form:"token" binding:"required"`
+	NewPassword     string `form:"new_password" binding:"required"`
+	ConfirmPassword string `form:"confirm_password" binding:"required"`
+}
+
+// passwordResetMessage writes the e-mail with the password reset token.
+//
+// With PASSWORD_RESET_URL (the front's reset page) the e-mail has a link with
+// the token, otherwise only the token.
+func passwordResetMessage(user *models.User, token string) mailer.Message {
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n%s\n\nIt expires in %d minutes and can be used once. "+
+			"If you did not ask to reset your password, you can ignore this e-mail.\n",
+			user.Name, tokenInstructions("PASSWORD_RESET_URL", "reset your password", token),
+			int(passwordResetLifetime.Minutes())),
+	}
+}
+
+// ForgotPasswordHandler e-mails a password reset token to the User.
+//
+// The response is the same whether the User exists or not, so the endpoint can
+// not be used to find out who has an account.
+func ForgotPasswordHandler(c *gin.Context) {
+	var input ForgotPasswordForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := gin.H{
+		"msg": "If the e-mail belongs to an account, a password reset link has been sent to it.",
+	}
+
+	userEmail := models.User{
+		Email: strings.ToLower(input.Email),
+	}
+	user := userEmail.GetUserByEmail()
+	if user == nil || user.DeactivatedAt != nil {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	recent, err := resetToken.RecentlyIssued(user.ID, time.Now().Add(-passwordResetInterval))
+	if err != nil || recent {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error generating password reset token")
+		return
+	}
+
+	resetToken = models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetLifetime),
+	}
+	if err := resetToken.SavePasswordResetToken(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sendMail(passwordResetMessage(user, token), fmt.Sprintf("password reset for user %d", user.ID))
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// ResetPasswordHandler sets a new password with an e-mailed password reset
+// token. The User is logged out of every session.
+func ResetPasswordHandler(c *gin.Context) {
+	var resetToken models.PasswordResetToken
+
+	var input ResetPasswordForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.NewPassword != input.ConfirmPassword {
+		returnErrorAndAbort(c, http.StatusBadRequest, "Failed to confirm password.")
+		return
+	}
+
+	newPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "Failed to encrypt password.")
+		return
+	}
+
+	user, err := resetToken.ResetPassword(auth.HashToken(input.Token), newPassword)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userID": user.ID,
+		"msg":    "Password has been reset. Please log in again.",
+	})
+}
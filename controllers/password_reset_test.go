@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"issue-tracker/auth"
+	"issue-tracker/database"
+	"issue-tracker/models"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func resetPassword(token string, password string) int {
+	form := url.Values{"token": {token}, "new_password": {password}, "confirm_password": {password}}
+	return serve(ResetPasswordHandler, "/reset", nil, nil, "POST", "/reset", form).Code
+}
+
+func TestPasswordReset(t *testing.T) {
+	setupSQLite(t)
+	memory := useMemoryMailer(t)
+	user := createVerifiedUser(t, "qa@email.com", "old password", models.RoleQA)
+
+	_, err := startSession(user, false)
+	assert.NoError(t, err)
+
+	rec := serve(ForgotPasswordHandler, "/forgot", nil, nil, "POST", "/forgot", url.Values{"email": {"QA@email.com"}})
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	token := mailedToken(t, memory, "qa@email.com")
+
+	assert.Equal(t, http.StatusOK, resetPassword(token, "new password"))
+
+	var saved models.User
+	assert.NoError(t, database.DB.First(&saved, user.ID).Error)
+	assert.NoError(t, bcrypt.CompareHashAndPassword(saved.Password, []byte("new password")))
+	assert.NotNil(t, saved.TokensRevokedAt, "access tokens issued before are refused")
+
+	var active int64
+	database.DB.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&active)
+	assert.Zero(t, active, "every session is logged out")
+
+	assert.Equal(t, http.StatusBadRequest, resetPassword(token, "other password"), "the token is used once")
+	assert.NoError(t, database.DB.First(&saved, user.ID).Error)
+	assert.NoError(t, bcrypt.CompareHashAndPassword(saved.Password, []byte("new password")))
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	setupSQLite(t)
+	user := createVerifiedUser(t, "qa@email.com", "old password", models.RoleQA)
+
+	token := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken("expired"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	assert.NoError(t, token.SavePasswordResetToken())
+
+	_, err := token.ResetPassword(auth.HashToken("expired"), []byte("hash"))
+	assert.Equal(t, models.ErrPasswordResetInvalid, err)
+	assert.Equal(t, http.StatusBadRequest, resetPassword("expired", "new password"))
+	assert.Equal(t, http.StatusBadRequest, resetPassword("unknown", "new password"))
+}
+
+func TestPasswordResetNewTokenInvalidatesOlder(t *testing.T) {
+	setupSQLite(t)
+	user := createVerifiedUser(t, "qa@email.com", "old password", models.RoleQA)
+
+	for _, value := range []string{"first", "second"} {
+		token := models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: auth.HashToken(value),
+			ExpiresAt: time.Now().Add(passwordResetLifetime),
+		}
+		assert.NoError(t, token.SavePasswordResetToken())
+	}
+
+	assert.Equal(t, http.StatusBadRequest, resetPassword("first", "new password"))
+	assert.Equal(t, http.StatusOK, resetPassword("second", "new password"))
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	setupSQLite(t)
+	memory := useMemoryMailer(t)
+	createVerifiedUser(t, "qa@email.com", "old password", models.RoleQA)
+
+	known := serve(ForgotPasswordHandler, "/forgot", nil, nil, "POST", "/forgot", url.Values{"email": {"qa@email.com"}})
+	unknown := serve(ForgotPasswordHandler, "/forgot", nil, nil, "POST", "/forgot", url.Values{"email": {"nobody@email.com"}})
+
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+
+	mailedToken(t, memory, "qa@email.com")
+	_, sent := memory.Last("nobody@email.com")
+	assert.False(t, sent)
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"issue-tracker/auth"
+	"issue-tracker/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPersonalTokenDays is the longest expiry of a personal access token.
+const maxPersonalTokenDays = 365
+
+// PersonalTokenCreateForm takes User's input on Personal Access Token Create
+// form.
+//
+// Scopes is a comma separated list, for example "issues:read,issues:write".
+// Without ExpiresInDays the token does not expire.
+type PersonalTokenCreateForm struct {
+	Name          string `form:"name" binding:"required"`
+	Scopes        string `form:"scopes" binding:"required"`
+	ExpiresInDays int    `form:"expires_in_days"`
+}
+
+// CreatePersonalTokenHandler creates a personal access token for the User. The
+// token is only shown in the response.
+func CreatePersonalTokenHandler(c *gin.Context) {
+	var input PersonalTokenCreateForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	scopes, err := models.ParseTokenScopes(input.Scopes)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.ExpiresInDays < 0 || input.ExpiresInDays > maxPersonalTokenDays {
+		returnErrorAndAbort(c, http.StatusBadRequest, "expires_in_days must be between 1 and 365, or empty.")
+		return
+	}
+
+	token, prefix, err := auth.NewPersonalToken()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error generating personal access token")
+		return
+	}
+
+	personalToken := models.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      input.Name,
+		Scopes:    strings.Join(scopes, ","),
+		Prefix:    prefix,
+		TokenHash: auth.HashToken(token),
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		personalToken.ExpiresAt = &expiresAt
+	}
+
+	if err := personalToken.SavePersonalAccessToken(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token": token,
+		"data":  personalToken,
+		"msg":   "Personal access token created. Copy it now, it is shown only once.",
+	})
+}
+
+// IndexPersonalTokenHandler shows the User's personal access tokens.
+func IndexPersonalTokenHandler(c *gin.Context) {
+	var personalToken models.PersonalAccessToken
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	result, err := personalToken.IndexPersonalAccessTokens(user.ID)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":    len(*result),
+		"scopes": models.TokenScopeNames(),
+		"data":   result,
+	})
+}
+
+// DeletePersonalTokenHandler revokes one of the User's personal access tokens.
+func DeletePersonalTokenHandler(c *gin.Context) {
+	var personalToken models.PersonalAccessToken
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	source, err := personalToken.FindPersonalAccessTokenByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	// Someone else's token is reported as missing.
+	if source.UserID != user.ID {
+		returnErrorAndAbort(c, http.StatusNotFound, "Personal access token not found.")
+		return
+	}
+
+	if err := source.DeletePersonalAccessToken(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "Unable to revoke personal access token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg": "Personal access token has been revoked.",
+	})
+}
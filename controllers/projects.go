@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"issue-tracker/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProjectCreateForm takes User's input on Project Create form.
+type ProjectCreateForm struct {
+	Name        string `form:"name" binding:"required"`
+	Key         string `form:"key" binding:"required"`
+	Description string `form:"description"`
+}
+
+// ProjectUpdateForm is for Updating. The Key of a Project can not be changed.
+type ProjectUpdateForm struct {
+	Name        string `form:"name" binding:"required"`
+	Description string `form:"description"`
+}
+
+// findProjectFromParams fetches the Project by the :key Param from URL.
+// Returns nil if the route has no :key Param.
+//
+// It and findIssueFromParams are for the handlers which are not methods of
+// Server yet, see Server.findProject and Server.findIssue.
+func findProjectFromParams(c *gin.Context) (*models.Project, error) {
+	key := c.Param("key")
+	if key == "" {
+		return nil, nil
+	}
+
+	var project models.Project
+	return project.FindProjectByKey(key)
+}
+
+// findIssueFromParams fetches the Issue by the :id Param from URL.
+//
+// On project-scoped routes (/project/:key/issue/...) :id is the Issue's Number
+// inside the Project, otherwise it is the Issue's ID.
+func findIssueFromParams(c *gin.Context) (*models.Issue, error) {
+	var issue models.Issue
+
+	project, err := findProjectFromParams(c)
+	if err != nil {
+		return nil, err
+	}
+	if project != nil {
+		return issue.FindIssueByProjectNumber(project.ID, c.Param("id"))
+	}
+
+	return issue.FindOneIssueByID(c.Param("id"))
+}
+
+// CreateProjectHandler handles project creation. The User who creates the
+// Project becomes its owner.
+func CreateProjectHandler(c *gin.Context) {
+	var input ProjectCreateForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	project := models.Project{
+		Name:        input.Name,
+		Key:         input.Key,
+		Description: input.Description,
+		OwnerID:     int(user.ID),
+	}
+	if err := project.ValidateProject(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := project.SaveProject(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"projectKey": project.Key,
+		"msg":        "Data succesfully created.",
+	})
+}
+
+// IndexProjectHandler shows all projects.
+func IndexProjectHandler(c *gin.Context) {
+	var project models.Project
+	result, err := project.IndexProjects()
+
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":  len(*result),
+		"data": result,
+	})
+}
+
+// ShowProjectHandler fetch ONE project by Key.
+//
+// /v1/protected/project/:key/show
+//
+// For example: /v1/protected/project/WEB/show
+func ShowProjectHandler(c *gin.Context) {
+	project, err := findProjectFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": project,
+	})
+}
+
+// UpdateProjectHandler updates a Project's name and description.
+//
+// The owner can Update a Project with project.edit.own, everyone else needs
+// project.edit.any.
+func UpdateProjectHandler(c *gin.Context) {
+	source, err := findProjectFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	var input ProjectUpdateForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !canModify(c, int(user.ID) == source.OwnerID, models.PermProjectEditOwn, models.PermProjectEditAny) {
+		returnErrorAndAbort(c, http.StatusForbidden, "User is unauthorized for this request.")
+		return
+	}
+
+	project := models.Project{
+		Name:        input.Name,
+		Key:         source.Key,
+		Description: input.Description,
+	}
+	if err := project.ValidateProject(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := project.UpdateProject(source); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"projectKey": source.Key,
+		"msg":        "Data has been updated succesfully.",
+	})
+}
+
+// DeleteProjectHandler deletes a Project by Key.
+//
+// The owner can Delete a Project with project.delete.own, everyone else needs
+// project.delete.any. Only a Project without Issues can be deleted.
+func DeleteProjectHandler(c *gin.Context) {
+	source, err := findProjectFromParams(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !canModify(c, int(user.ID) == source.OwnerID, models.PermProjectDeleteOwn, models.PermProjectDeleteAny) {
+		returnErrorAndAbort(c, http.StatusForbidden, "User is unauthorized for this request.")
+		return
+	}
+
+	if err := source.DeleteProject(); err != nil {
+		returnErrorAndAbort(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{
+		"data": "deleted",
+		"msg":  "project is deleted successfully",
+	})
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"issue-tracker/models"
+	"issue-tracker/webhooks"
 	"net/http"
 	"strconv"
 
@@ -14,48 +15,43 @@ type ReplyCreateUpdateForm struct {
 }
 
 // CreateReplyHandler handles Reply creation
+// and notifies the Issue's watchers.
 //
-// Needs "id" as param and "userID" as Header.
-func CreateReplyHandler(c *gin.Context) {
-	issueID, err := strconv.ParseUint(c.Param("id"), 10, 0)
-	if err != nil {
-		returnErrorAndAbort(c, http.StatusBadRequest, "Issue invalid.")
+// Needs "id" as param.
+func (s *Server) CreateReplyHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
-	userID, err := strconv.ParseUint(c.GetHeader("userID"), 10, 0)
-	if err != nil {
-		returnErrorAndAbort(c, http.StatusForbidden, "User invalid.")
-	}
-
 	var input ReplyCreateUpdateForm
 	if err := c.ShouldBind(&input); err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var issue models.Issue
-	iss, err := issue.FindOneIssueByID(strconv.Itoa(int(issueID)))
+	iss, err := s.findIssue(c)
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if iss.Status != "1" {
+	if iss.Status.IsClosed {
 		returnErrorAndAbort(c, http.StatusNotAcceptable, "Issue is already closed!")
 		return
 	}
 
 	reply := models.Reply{
-		UserID:  uint(userID),
-		IssueID: uint(issueID),
+		UserID:  user.ID,
+		IssueID: iss.ID,
 		Body:    input.Body,
 	}
-	err = reply.SaveReply()
+	err = s.Store.Replies().Create(&reply, iss, user)
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	s.Emit(models.EventReplyCreated, iss.ProjectID, user, webhooks.Reply(&reply, iss))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"replyID": reply.ID,
@@ -64,10 +60,9 @@ func CreateReplyHandler(c *gin.Context) {
 }
 
 // UpdateReplyHandler handles the Update request.
-func UpdateReplyHandler(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.GetHeader("userID"), 10, 0)
-	if err != nil {
-		returnErrorAndAbort(c, http.StatusForbidden, "User invalid.")
+func (s *Server) UpdateReplyHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
@@ -77,14 +72,13 @@ func UpdateReplyHandler(c *gin.Context) {
 		return
 	}
 
-	var reply models.Reply
-	replySource := reply.FindReplyByID(uint(replyID))
-	if replySource == nil {
+	replySource, err := s.Store.Replies().FindByID(uint(replyID))
+	if err != nil {
 		returnErrorAndAbort(c, http.StatusNotFound, "Reply not found.")
 		return
 	}
 
-	if userID != uint64(replySource.UserID) {
+	if !canModify(c, user.ID == replySource.UserID, models.PermReplyEditOwn, models.PermReplyEditAny) {
 		returnErrorAndAbort(c, http.StatusForbidden, "This user is not allowed to update this Reply.")
 		return
 	}
@@ -99,11 +93,12 @@ func UpdateReplyHandler(c *gin.Context) {
 		Body: input.Body,
 	}
 
-	err = updateReply.UpdateReply(replySource)
+	err = s.Store.Replies().Update(replySource, &updateReply)
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusNotAcceptable, err.Error())
 		return
 	}
+	s.emitReplyEvent(models.EventReplyUpdated, replySource, user)
 
 	c.JSON(http.StatusOK, gin.H{
 		"msg": "Data successfully updated.",
@@ -112,10 +107,9 @@ func UpdateReplyHandler(c *gin.Context) {
 }
 
 // DeleteReplyHandler handles a Deletion of a Reply.
-func DeleteReplyHandler(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.GetHeader("userID"), 10, 0)
-	if err != nil {
-		returnErrorAndAbort(c, http.StatusForbidden, "User invalid.")
+func (s *Server) DeleteReplyHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
 		return
 	}
 
@@ -125,25 +119,35 @@ func DeleteReplyHandler(c *gin.Context) {
 		return
 	}
 
-	var reply models.Reply
-	replySource := reply.FindReplyByID(uint(replyID))
-	if replySource == nil {
+	replySource, err := s.Store.Replies().FindByID(uint(replyID))
+	if err != nil {
 		returnErrorAndAbort(c, http.StatusNotFound, "Reply not found.")
 		return
 	}
 
-	if userID != uint64(replySource.UserID) {
+	if !canModify(c, user.ID == replySource.UserID, models.PermReplyDeleteOwn, models.PermReplyDeleteAny) {
 		returnErrorAndAbort(c, http.StatusForbidden, "This user is not allowed to delete this Reply.")
 		return
 	}
 
-	err = replySource.DeleteReply()
+	err = s.Store.Replies().Delete(replySource)
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusNotAcceptable, err.Error())
 		return
 	}
+	s.emitReplyEvent(models.EventReplyDeleted, replySource, user)
 
 	c.JSON(http.StatusNoContent, gin.H{
 		"msg": "Data successfully deleted.",
 	})
 }
+
+// emitReplyEvent emits a Webhook event of a Reply, caused by the actor. A
+// moderator can edit or delete the Reply of another User.
+func (s *Server) emitReplyEvent(event string, reply *models.Reply, actor *models.User) {
+	source, err := s.Store.Issues().FindByID(reply.IssueID)
+	if err != nil {
+		return
+	}
+	s.Emit(event, source.ProjectID, actor, webhooks.Reply(reply, source))
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"issue-tracker/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleForm takes User's input on Role Create and Update form.
+//
+// Permissions is a comma separated list of Permission names, for example
+// "issue.read,reply.create". It replaces the Role's Permissions on Update.
+type RoleForm struct {
+	Name         string `form:"name" binding:"required"`
+	SelfRegister bool   `form:"self_register"`
+	Permissions  string `form:"permissions"`
+}
+
+// bindRole binds the RoleForm and fetches the Permissions it names.
+func bindRole(c *gin.Context) (*models.Role, error) {
+	var permission models.Permission
+
+	var input RoleForm
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, err
+	}
+
+	permissions, err := permission.FindPermissionsByName(splitList(input.Permissions))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Role{
+		Name:         input.Name,
+		SelfRegister: input.SelfRegister,
+		Permissions:  permissions,
+	}, nil
+}
+
+// IndexPermissionHandler shows every Permission a Role can have.
+func IndexPermissionHandler(c *gin.Context) {
+	var permission models.Permission
+
+	result, err := permission.IndexPermissions()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":  len(*result),
+		"data": result,
+	})
+}
+
+// IndexRoleHandler shows every Role with its Permissions.
+func IndexRoleHandler(c *gin.Context) {
+	var role models.Role
+
+	result, err := role.IndexRoles()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":  len(*result),
+		"data": result,
+	})
+}
+
+// CreateRoleHandler creates a Role with a set of Permissions.
+func CreateRoleHandler(c *gin.Context) {
+	role, err := bindRole(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := role.ValidateRole(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := role.SaveRole(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"roleID": role.ID,
+		"msg":    "Data succesfully created.",
+	})
+}
+
+// UpdateRoleHandler updates a Role and replaces its Permissions.
+func UpdateRoleHandler(c *gin.Context) {
+	var role models.Role
+
+	source, err := role.FindRoleByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	update, err := bindRole(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	update.ID = source.ID
+	if err := update.ValidateRole(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := update.UpdateRole(source); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roleID": source.ID,
+		"msg":    "Data has been updated succesfully.",
+	})
+}
+
+// DeleteRoleHandler deletes a Role which no User has.
+func DeleteRoleHandler(c *gin.Context) {
+	var role models.Role
+
+	source, err := role.FindRoleByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if err := source.DeleteRole(); err != nil {
+		returnErrorAndAbort(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{
+		"data": "deleted",
+		"msg":  "role is deleted successfully",
+	})
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"fmt"
+	"issue-tracker/models"
+	"issue-tracker/webhooks"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server holds what its handlers depend on, so they can run on another
+// models.Store, like the memstore in tests.
+//
+// The handlers of Issues, their Replies, assignees, workflow transitions and
+// history, and of User profiles and account management are methods of
+// Server. The handlers of what the Store does not keep use the models
+// directly: registration, logging in, sessions, password resets, e-mail
+// verification, invitations, 2FA enrollment, personal access tokens, the login
+// audit, Projects, Labels, Attachments, Roles, Webhooks and notifications.
+type Server struct {
+	Store models.Store
+	// Emit sends a Webhook event, see webhooks.Emit.
+	Emit func(event string, projectID uint, actor *models.User, data interface{})
+}
+
+// NewServer returns a Server on the Store, which sends Webhook events.
+func NewServer(store models.Store) *Server {
+	return &Server{
+		Store: store,
+		Emit:  webhooks.Emit,
+	}
+}
+
+// findProject fetches the Project by the :key Param from URL, like
+// findProjectFromParams.
+func (s *Server) findProject(c *gin.Context) (*models.Project, error) {
+	key := c.Param("key")
+	if key == "" {
+		return nil, nil
+	}
+	return s.Store.Projects().FindByKey(key)
+}
+
+// findIssue fetches the Issue by the :id Param from URL, like
+// findIssueFromParams.
+func (s *Server) findIssue(c *gin.Context) (*models.Issue, error) {
+	project, err := s.findProject(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if project != nil {
+		number, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: Could not find issue with number: %s", c.Param("id"))
+		}
+		return s.Store.Issues().FindByProjectNumber(project.ID, number)
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not find issue with ID: %s", c.Param("id"))
+	}
+	return s.Store.Issues().FindByID(uint(id))
+}
@@ -0,0 +1,348 @@
+package controllers
+
+import (
+	"issue-tracker/memstore"
+	"issue-tracker/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestServer returns a Server on a memstore which records the Webhook
+// events instead of sending them.
+func newTestServer() (*Server, *[]string) {
+	var events []string
+	server := NewServer(memstore.New())
+	server.Emit = func(event string, projectID uint, actor *models.User, data interface{}) {
+		events = append(events, event)
+	}
+	return server, &events
+}
+
+// serve runs the handler as the User with the permissions, like AuthJWT and
+// RequirePermission would.
+func serve(handler gin.HandlerFunc, path string, user *models.User, permissions models.PermissionSet,
+	method string, target string, form url.Values) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, path, func(c *gin.Context) {
+		c.Set("user", user)
+		c.Set("permissions", permissions)
+	}, handler)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReplyHandlers(t *testing.T) {
+	server, events := newTestServer()
+	store := server.Store
+
+	author := &models.User{Name: "qa", Email: "qa@email.com"}
+	other := &models.User{Name: "dev", Email: "dev@email.com"}
+	assert.NoError(t, store.Users().Create(author))
+	assert.NoError(t, store.Users().Create(other))
+
+	issue := &models.Issue{Title: "Crash", Severity: "3", UserID: int(author.ID)}
+	assert.NoError(t, store.Issues().Create(issue, &models.Project{Key: "WEB"}))
+	assert.Equal(t, "WEB-1", issue.Key)
+
+	closed := &models.Issue{Title: "Done", Severity: "1", Status: models.Status{IsClosed: true}}
+	assert.NoError(t, store.Issues().Create(closed, &models.Project{Key: "WEB"}))
+
+	own := models.PermissionSet{models.PermReplyCreate: true, models.PermReplyEditOwn: true, models.PermReplyDeleteOwn: true}
+	form := url.Values{"description": {"Happens on every start."}}
+
+	rec := serve(server.CreateReplyHandler, "/issue/show/:id/reply", author, own,
+		"POST", "/issue/show/"+itoa(closed.ID)+"/reply", form)
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+
+	rec = serve(server.CreateReplyHandler, "/issue/show/:id/reply", author, own,
+		"POST", "/issue/show/"+itoa(issue.ID)+"/reply", form)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, []string{models.EventReplyCreated}, *events)
+
+	replyID := itoa(closed.ID + 1)
+	updatePath := "/issue/show/:id/update-reply/:replyId"
+	updateTarget := "/issue/show/" + itoa(issue.ID) + "/update-reply/" + replyID
+	edit := url.Values{"description": {"Only on the first start."}}
+
+	rec = serve(server.UpdateReplyHandler, updatePath, other, own, "PATCH", updateTarget, edit)
+	assert.Equal(t, http.StatusForbidden, rec.Code, "only the author may edit with reply.edit.own")
+
+	rec = serve(server.UpdateReplyHandler, updatePath, author, own, "PATCH", updateTarget, edit)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	reply, err := store.Replies().FindByID(closed.ID + 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Only on the first start.", reply.Body)
+
+	// The Webhook actor is the moderator deleting the Reply, not its author.
+	var actor *models.User
+	emit := server.Emit
+	server.Emit = func(event string, projectID uint, user *models.User, data interface{}) {
+		actor = user
+		emit(event, projectID, user, data)
+	}
+	moderator := models.PermissionSet{models.PermReplyDeleteAny: true}
+	deletePath := "/issue/show/:id/delete-reply/:replyId"
+	deleteTarget := "/issue/show/" + itoa(issue.ID) + "/delete-reply/" + replyID
+	rec = serve(server.DeleteReplyHandler, deletePath, other, moderator, "DELETE", deleteTarget, nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, other, actor)
+
+	_, err = store.Replies().FindByID(closed.ID + 1)
+	assert.Error(t, err)
+	assert.Equal(t, []string{models.EventReplyCreated, models.EventReplyUpdated, models.EventReplyDeleted}, *events)
+}
+
+func TestUpdateIssueHandler(t *testing.T) {
+	server, events := newTestServer()
+	store := server.Store
+
+	author := &models.User{Name: "qa", Email: "qa@email.com"}
+	assert.NoError(t, store.Users().Create(author))
+	issue := &models.Issue{Title: "Crash", Body: "On start", Severity: "3", UserID: int(author.ID)}
+	assert.NoError(t, store.Issues().Create(issue, &models.Project{Key: "WEB"}))
+
+	path := "/issue/update/:id"
+	target := "/issue/update/" + itoa(issue.ID)
+	form := url.Values{"title": {"Crash on start"}, "description": {"On start"}, "severity": {"9"}}
+
+	rec := serve(server.UpdateIssueHandler, path, author, models.PermissionSet{models.PermIssueEditOwn: true}, "PATCH", target, form)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "severity must be between 1 - 3")
+
+	form.Set("severity", "2")
+	rec = serve(server.UpdateIssueHandler, path, author, models.PermissionSet{}, "PATCH", target, form)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "the User has no edit permission")
+
+	rec = serve(server.UpdateIssueHandler, path, author, models.PermissionSet{models.PermIssueEditOwn: true}, "PATCH", target, form)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	source, err := store.Issues().FindByID(issue.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Crash on start", source.Title)
+	assert.Equal(t, "2", source.Severity)
+	assert.Equal(t, "qa", source.UpdatedByUserName)
+	assert.Equal(t, []string{models.EventIssueUpdated}, *events)
+}
+
+func TestCreateIssueHandler(t *testing.T) {
+	server, events := newTestServer()
+	store := server.Store
+
+	author := &models.User{Name: "qa", Email: "qa@email.com"}
+	assert.NoError(t, store.Users().Create(author))
+	assert.NoError(t, store.Projects().Create(&models.Project{Name: "Website", Key: "WEB"}))
+	assert.NoError(t, store.Projects().Create(&models.Project{Name: "API", Key: "API"}))
+
+	create := models.PermissionSet{models.PermIssueCreate: true}
+	form := url.Values{"title": {"Crash"}, "description": {"On start"}, "severity": {"3"}}
+
+	rec := serve(server.CreateIssueHandler, "/issue/create", author, create, "POST", "/issue/create", form)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "the global route needs a Project")
+
+	form.Set("project", "CLI")
+	rec = serve(server.CreateIssueHandler, "/issue/create", author, create, "POST", "/issue/create", form)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	form.Set("project", "web")
+	rec = serve(server.CreateIssueHandler, "/issue/create", author, create, "POST", "/issue/create", form)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"issueKey":"WEB-1"`)
+
+	// The project-scoped route takes the Project from the URL.
+	scoped := "/project/:key/issue/create"
+	rec = serve(server.CreateIssueHandler, scoped, author, create, "POST", "/project/api/issue/create", form)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"issueKey":"API-1"`)
+
+	rec = serve(server.CreateIssueHandler, scoped, author, create, "POST", "/project/CLI/issue/create", form)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, []string{models.EventIssueCreated, models.EventIssueCreated}, *events)
+
+	// On project-scoped routes :id is the Number inside the Project.
+	read := models.PermissionSet{models.PermIssueRead: true}
+	rec = serve(server.ShowIssueHandler, "/project/:key/issue/show/:id", author, read, "GET", "/project/API/issue/show/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Key":"API-1"`)
+
+	rec = serve(server.ShowIssueHandler, "/project/:key/issue/show/:id", author, read, "GET", "/project/API/issue/show/2", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestIssueReadHandlers(t *testing.T) {
+	server, _ := newTestServer()
+	store := server.Store
+
+	author := &models.User{Name: "qa", Email: "qa@email.com", RoleID: models.RoleQA}
+	assert.NoError(t, store.Users().Create(author))
+	crash := &models.Issue{Title: "Crash", Body: "On start", Severity: "3", UserID: int(author.ID), StatusID: models.StatusOpen}
+	assert.NoError(t, store.Issues().Create(crash, &models.Project{Model: gorm.Model{ID: 1}, Key: "WEB"}))
+	typo := &models.Issue{Title: "Typo", Body: "In the footer", Severity: "1", UserID: int(author.ID), StatusID: models.StatusTriaged}
+	assert.NoError(t, store.Issues().Create(typo, &models.Project{Model: gorm.Model{ID: 2}, Key: "API"}))
+	reply := &models.Reply{UserID: author.ID, IssueID: typo.ID, Body: "Also in the header."}
+	assert.NoError(t, store.Replies().Create(reply, typo, author))
+
+	read := models.PermissionSet{models.PermIssueRead: true}
+
+	rec := serve(server.IndexIssueHandler, "/issue/index", author, read, "GET", "/issue/index?status=2", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"total":1`)
+	assert.Contains(t, rec.Body.String(), `"Key":"API-1"`)
+	assert.Contains(t, rec.Body.String(), `"StatusName":"Triaged"`)
+
+	rec = serve(server.SearchIssueHandler, "/issue/search", author, read, "GET", "/issue/search?q=header", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"MatchedIn":"reply"`)
+	assert.NotContains(t, rec.Body.String(), `"Key":"WEB-1"`)
+
+	rec = serve(server.ShowIssueHandler, "/issue/show/:id", author, read, "GET", "/issue/show/"+itoa(typo.ID), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Replier":"qa"`)
+
+	rec = serve(server.ShowIssueHandler, "/issue/show/:id", author, read, "GET", "/issue/show/999", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestTransitionIssueHandler(t *testing.T) {
+	server, events := newTestServer()
+	store := server.Store
+
+	qa := &models.User{Name: "qa", Email: "qa@email.com", RoleID: models.RoleQA}
+	assert.NoError(t, store.Users().Create(qa))
+	issue := &models.Issue{Title: "Crash", Severity: "3", UserID: int(qa.ID), StatusID: models.StatusOpen}
+	assert.NoError(t, store.Issues().Create(issue, &models.Project{Key: "WEB"}))
+
+	path := "/issue/show/:id/transition"
+	target := "/issue/show/" + itoa(issue.ID) + "/transition"
+	transition := models.PermissionSet{models.PermIssueTransition: true}
+
+	rec := serve(server.TransitionIssueHandler, path, qa, transition, "POST", target, url.Values{"status": {"2"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(server.TransitionIssueHandler, path, qa, transition, "POST", target, url.Values{"status": {"3"}})
+	assert.Equal(t, http.StatusConflict, rec.Code, "only Developers start the work")
+
+	transition[models.PermIssueTransitionAny] = true
+	rec = serve(server.TransitionIssueHandler, path, qa, transition, "POST", target, url.Values{"status": {"3"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	source, err := store.Issues().FindByID(issue.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(models.StatusInProgress), source.StatusID)
+	assert.Equal(t, []string{models.EventIssueTransition, models.EventIssueTransition}, *events)
+}
+
+func TestAssignIssueHandlers(t *testing.T) {
+	server, events := newTestServer()
+	store := server.Store
+
+	qa := &models.User{Name: "qa", Email: "qa@email.com", RoleID: models.RoleQA}
+	dev := &models.User{Name: "dev", Email: "dev@email.com", RoleID: models.RoleDeveloper}
+	assert.NoError(t, store.Users().Create(qa))
+	assert.NoError(t, store.Users().Create(dev))
+	issue := &models.Issue{Title: "Crash", Severity: "3", UserID: int(qa.ID), StatusID: models.StatusOpen}
+	assert.NoError(t, store.Issues().Create(issue, &models.Project{Key: "WEB"}))
+
+	assignPath := "/issue/show/:id/assign"
+	assignTarget := "/issue/show/" + itoa(issue.ID) + "/assign"
+	assignAny := models.PermissionSet{models.PermIssueAssignAny: true}
+
+	rec := serve(server.AssignIssueHandler, assignPath, qa, assignAny, "POST", assignTarget, url.Values{"user_id": {itoa(qa.ID)}})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "QA can not be assigned")
+
+	rec = serve(server.AssignIssueHandler, assignPath, qa, assignAny, "POST", assignTarget, url.Values{"user_id": {itoa(dev.ID)}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assigned, err := store.Issues().IsAssigned(issue, dev.ID)
+	assert.NoError(t, err)
+	assert.True(t, assigned)
+
+	rec = serve(server.IndexAssignedIssueHandler, "/user/:id/assigned", dev, models.PermissionSet{models.PermIssueRead: true},
+		"GET", "/user/"+itoa(dev.ID)+"/assigned", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"total":1`)
+
+	unassignPath := "/issue/show/:id/assign/:userId"
+	unassignTarget := "/issue/show/" + itoa(issue.ID) + "/assign/" + itoa(dev.ID)
+	rec = serve(server.UnassignIssueHandler, unassignPath, qa, assignAny, "DELETE", unassignTarget, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(server.UnassignIssueHandler, unassignPath, qa, assignAny, "DELETE", unassignTarget, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(server.AssignIssueHandler, assignPath, qa, assignAny, "POST", assignTarget, url.Values{"user_id": {itoa(dev.ID)}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, store.Users().Deactivate(dev))
+	rec = serve(server.AssignIssueHandler, assignPath, qa, assignAny, "POST", assignTarget, url.Values{"user_id": {itoa(dev.ID)}})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "a deactivated User can not be assigned")
+	rec = serve(server.UnassignIssueHandler, unassignPath, qa, assignAny, "DELETE", unassignTarget, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, "a deactivated User can still be unassigned")
+	assigned, err = store.Issues().IsAssigned(issue, dev.ID)
+	assert.NoError(t, err)
+	assert.False(t, assigned)
+
+	assert.Equal(t, []string{models.EventIssueAssigned, models.EventIssueUnassigned,
+		models.EventIssueAssigned, models.EventIssueUnassigned}, *events)
+}
+
+func TestUserManagementHandlers(t *testing.T) {
+	server, _ := newTestServer()
+	store := server.Store
+
+	admin := &models.User{Name: "admin", Email: "admin@email.com", RoleID: models.RoleAdmin}
+	qa := &models.User{Name: "qa", Email: "qa@email.com", RoleID: models.RoleQA}
+	assert.NoError(t, store.Users().Create(admin))
+	assert.NoError(t, store.Users().Create(qa))
+
+	manage := models.PermissionSet{models.PermUserManage: true}
+	manageUser := func(handler gin.HandlerFunc, action string, id uint, form url.Values) int {
+		return serve(handler, "/user/:id/"+action, admin, manage, "PATCH", "/user/"+itoa(id)+"/"+action, form).Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, manageUser(server.DeactivateUserHandler, "deactivate", admin.ID, nil),
+		"Users can not manage their own account")
+	assert.Equal(t, http.StatusNotFound, manageUser(server.DeactivateUserHandler, "deactivate", 999, nil))
+	assert.Equal(t, http.StatusOK, manageUser(server.DeactivateUserHandler, "deactivate", qa.ID, nil))
+	assert.Equal(t, http.StatusConflict, manageUser(server.DeactivateUserHandler, "deactivate", qa.ID, nil))
+
+	source, err := store.Users().FindByID(qa.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, source.DeactivatedAt)
+	assert.NotNil(t, source.TokensRevokedAt)
+	permissions, err := store.Users().Permissions(source)
+	assert.NoError(t, err)
+	assert.Empty(t, permissions, "a deactivated User has no Permissions")
+
+	rec := serve(server.IndexUserHandler, "/users/index", admin, manage, "GET", "/users/index?active=false", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"total":1`)
+	assert.Contains(t, rec.Body.String(), `"Email":"qa@email.com"`)
+
+	assert.Equal(t, http.StatusOK, manageUser(server.ReactivateUserHandler, "reactivate", qa.ID, nil))
+	assert.Equal(t, http.StatusBadRequest, manageUser(server.ChangeUserRoleHandler, "role", qa.ID, url.Values{"role": {"99"}}))
+	assert.Equal(t, http.StatusOK, manageUser(server.ChangeUserRoleHandler, "role", qa.ID, url.Values{"role": {"2"}}))
+	assert.Equal(t, http.StatusOK, manageUser(server.ForcePasswordResetHandler, "force-password-reset", qa.ID, nil))
+
+	source, err = store.Users().FindByID(qa.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, source.DeactivatedAt)
+	assert.Equal(t, models.RoleDeveloper, source.RoleID)
+	permissions, err = store.Users().Permissions(source)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PermissionSet{models.PermProfileEdit: true}, permissions,
+		"a User who must reset their password can only change it")
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
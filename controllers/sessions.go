@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"issue-tracker/auth"
+	"issue-tracker/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Lifetimes of the tokens. An access token is short-lived and is renewed with
+// the refresh token, whose lifetime starts over on every refresh.
+const (
+	accessTokenLifetime            = 15 * time.Minute
+	refreshTokenLifetime           = 24 * time.Hour
+	rememberedRefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// RefreshForm takes the refresh token to exchange.
+type RefreshForm struct {
+	RefreshToken string `form:"refresh_token" binding:"required"`
+}
+
+// newRefreshToken generates a refresh token for the client and the
+// RefreshToken to store in its place.
+func newRefreshToken(remember bool) (string, *models.RefreshToken, error) {
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	lifetime := refreshTokenLifetime
+	if remember {
+		lifetime = rememberedRefreshTokenLifetime
+	}
+
+	return token, &models.RefreshToken{
+		TokenHash: auth.HashToken(token),
+		Remember:  remember,
+		ExpiresAt: time.Now().Add(lifetime),
+	}, nil
+}
+
+// startSession logs the User in on a new session. Returns the tokens for the
+// response.
+func startSession(user *models.User, remember bool) (gin.H, error) {
+	sessionID, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token, refresh, err := newRefreshToken(remember)
+	if err != nil {
+		return nil, err
+	}
+	refresh.UserID = user.ID
+	refresh.SessionID = sessionID
+	if err := refresh.SaveRefreshToken(); err != nil {
+		return nil, err
+	}
+
+	return sessionTokens(user, token, refresh)
+}
+
+// sessionTokens signs an access token for the session of refresh.
+func sessionTokens(user *models.User, token string, refresh *models.RefreshToken) (gin.H, error) {
+	jwtWrapper := auth.JwtWrapper{
+		Keys:       auth.DefaultKeys,
+		Issuer:     "AuthService",
+		Expiration: accessTokenLifetime,
+	}
+
+	signedToken, err := jwtWrapper.GenerateToken(user.ID, user.Email, refresh.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":            signedToken,
+		"expiresIn":        int(accessTokenLifetime.Seconds()),
+		"refreshToken":     token,
+		"refreshExpiresAt": refresh.ExpiresAt,
+	}, nil
+}
+
+// RefreshHandler exchanges a refresh token for a new access token and a new
+// refresh token. The old refresh token can not be used again.
+//
+// Using a refresh token which was already exchanged ends its whole session,
+// since the token may have been stolen.
+func RefreshHandler(c *gin.Context) {
+	var refreshToken models.RefreshToken
+
+	var input RefreshForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, err := refreshToken.FindRefreshTokenByHash(auth.HashToken(input.RefreshToken))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusUnauthorized, "Invalid refresh token.")
+		return
+	}
+
+	if source.ReplacedByID != nil {
+		source.RevokeSession(source.UserID, source.SessionID)
+		returnErrorAndAbort(c, http.StatusUnauthorized, "Refresh token has already been used. Please log in again.")
+		return
+	}
+	if !source.Active(time.Now()) {
+		returnErrorAndAbort(c, http.StatusUnauthorized, "Session has ended. Please log in again.")
+		return
+	}
+
+	var user models.User
+	sourceUser := user.GetUserByID(int(source.UserID))
+	if sourceUser == nil {
+		returnErrorAndAbort(c, http.StatusUnauthorized, "User not found.")
+		return
+	}
+	if sourceUser.DeactivatedAt != nil {
+		returnErrorAndAbort(c, http.StatusForbidden, "Account is deactivated.")
+		return
+	}
+
+	token, next, err := newRefreshToken(source.Remember)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error generating refresh token")
+		return
+	}
+
+	err = source.Rotate(next)
+	switch err {
+	case nil:
+	case models.ErrRefreshTokenReused:
+		source.RevokeSession(source.UserID, source.SessionID)
+		returnErrorAndAbort(c, http.StatusUnauthorized, "Refresh token has already been used. Please log in again.")
+		return
+	default:
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := sessionTokens(sourceUser, token, next)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error signing token")
+		return
+	}
+	response["userID"] = sourceUser.ID
+	response["mustResetPassword"] = sourceUser.MustResetPassword
+
+	c.JSON(http.StatusOK, response)
+}
+
+// LogoutHandler ends the session of the access token. Its refresh token can no
+// longer be used and its access tokens are rejected.
+func LogoutHandler(c *gin.Context) {
+	var refreshToken models.RefreshToken
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := refreshToken.RevokeSession(user.ID, c.GetString("sessionID")); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg": "Logged out successfully.",
+	})
+}
+
+// LogoutAllHandler ends every session of the User, on every device.
+//
+// Personal access tokens are kept. They are revoked one by one with
+// DeletePersonalTokenHandler.
+func LogoutAllHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := user.LogoutEverywhere(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg": "Logged out of every session successfully.",
+	})
+}
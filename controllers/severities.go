@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"issue-tracker/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IndexSeverityHandler shows all Severities an Issue can have.
+func IndexSeverityHandler(c *gin.Context) {
+	var severity models.Severity
+
+	severities, err := severity.IndexSeverities()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"severities": severities,
+	})
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"issue-tracker/auth"
+	"issue-tracker/database"
+	"issue-tracker/mailer"
+	"issue-tracker/migrations"
+	"issue-tracker/models"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm/logger"
+)
+
+// setupSQLite points database.DB at a new migrated in-memory SQLite database,
+// for the handlers which do not go through the Server's Store yet. Tokens are
+// signed with a test key.
+func setupSQLite(t *testing.T) {
+	auth.DefaultKeys = auth.NewHMACKeySet("test secret")
+
+	db, err := database.Open(database.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	database.DB = db
+	if err := migrations.MigrateTables(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createVerifiedUser saves a User with a verified e-mail and the password.
+func createVerifiedUser(t *testing.T, email string, password string, roleID int) *models.User {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Name: email, Email: email, Password: hashed, RoleID: roleID}
+	if err := user.SaveUserData(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Model(&user).Update("email_verified_at", user.CreatedAt).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+// useMemoryMailer makes the handlers send their e-mails to a new mailer.Memory,
+// with the tokens in them instead of links.
+func useMemoryMailer(t *testing.T) *mailer.Memory {
+	memory := mailer.NewMemory()
+	previous := mailer.Default
+	mailer.Default = memory
+	t.Cleanup(func() { mailer.Default = previous })
+
+	for _, env := range []string{"PASSWORD_RESET_URL", "EMAIL_VERIFICATION_URL", "INVITATION_URL"} {
+		if value, ok := os.LookupEnv(env); ok {
+			os.Unsetenv(env)
+			t.Cleanup(func() { os.Setenv(env, value) })
+		}
+	}
+	return memory
+}
+
+// mailedToken waits for the e-mail sent to the address in the background and
+// returns the token in it.
+func mailedToken(t *testing.T, memory *mailer.Memory, to string) string {
+	var msg mailer.Message
+	ok := assert.Eventually(t, func() bool {
+		var sent bool
+		msg, sent = memory.Last(to)
+		return sent
+	}, time.Second, 5*time.Millisecond, "an e-mail is sent to %s", to)
+	if !ok {
+		t.FailNow()
+	}
+
+	parts := strings.SplitN(msg.Body, "Use this code to ", 2)
+	if len(parts) != 2 {
+		t.Fatalf("no token in the e-mail to %s: %q", to, msg.Body)
+	}
+	lines := strings.Split(parts[1], "\n")
+	return strings.TrimSpace(lines[2])
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"fmt"
+	"issue-tracker/models"
+	"issue-tracker/webhooks"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IssueTransitionForm takes the Status ID an Issue is moved to.
+type IssueTransitionForm struct {
+	StatusID uint `form:"status" binding:"required"`
+}
+
+// IndexStatusHandler shows all workflow Statuses and the allowed transitions
+// between them.
+func IndexStatusHandler(c *gin.Context) {
+	var status models.Status
+	var transition models.StatusTransition
+
+	statuses, err := status.IndexStatuses()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	transitions, err := transition.IndexTransitions()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statuses":    statuses,
+		"transitions": transitions,
+	})
+}
+
+// TransitionIssueHandler moves an Issue to another Status.
+//
+// The move is rejected if the workflow does not allow it for the User's Role,
+// unless the User has issue.transition.any.
+func (s *Server) TransitionIssueHandler(c *gin.Context) {
+	source, err := s.findIssue(c)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var input IssueTransitionForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userSource, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	target, err := s.Store.Statuses().FindByID(input.StatusID)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	allowed := hasPermission(c, models.PermIssueTransitionAny)
+	if !allowed {
+		allowed, err = s.Store.Statuses().IsTransitionAllowed(source.StatusID, target.ID, userSource.RoleID)
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if !allowed {
+		returnErrorAndAbort(c, http.StatusConflict,
+			fmt.Sprintf("Moving an issue from %s to %s is not allowed for this user.", source.Status.Name, target.Name))
+		return
+	}
+
+	from := source.Status
+	if err := s.Store.Issues().Transition(source, target, userSource); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.Emit(models.EventIssueTransition, source.ProjectID, userSource, webhooks.Data{
+		"issue": webhooks.Issue(source),
+		"from":  from,
+		"to":    target,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"issueID":  source.ID,
+		"issueKey": source.Key,
+		"status":   target,
+		"msg":      "Issue status has been updated succesfully.",
+	})
+}
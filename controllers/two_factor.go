@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	"issue-tracker/auth"
+	"issue-tracker/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// challengeTokenLifetime is how long a User has to enter their 2FA code
+	// after entering their password.
+	challengeTokenLifetime = 5 * time.Minute
+	// recoveryCodeCount is how many recovery codes a User gets.
+	recoveryCodeCount = 10
+	// totpIssuer names the account in authenticator apps.
+	totpIssuer = "Purge"
+)
+
+// TOTPCodeForm takes a code from the User's authenticator app.
+type TOTPCodeForm struct {
+	Code string `form:"code" binding:"required"`
+}
+
+// SecondFactorForm takes a code from the User's authenticator app or one of
+// their recovery codes.
+type SecondFactorForm struct {
+	Code         string `form:"code"`
+	RecoveryCode string `form:"recovery_code"`
+}
+
+// DisableTOTPForm takes the User's password and second factor.
+type DisableTOTPForm struct {
+	Password string `form:"password" binding:"required"`
+	SecondFactorForm
+}
+
+// LoginTwoFactorForm takes the challenge token from Login and the User's
+// second factor.
+type LoginTwoFactorForm struct {
+	ChallengeToken string `form:"challenge_token" binding:"required"`
+	SecondFactorForm
+}
+
+// verifySecondFactor checks the code or the recovery code of a User with 2FA.
+// A code or a recovery code can only be used once.
+func verifySecondFactor(user *models.User, input SecondFactorForm) bool {
+	if input.Code != "" {
+		step, ok := auth.ValidateTOTP(user.TOTPSecret, input.Code, time.Now())
+		if !ok {
+			return false
+		}
+		used, err := user.UseTOTPStep(step)
+		return err == nil && used
+	}
+
+	if input.RecoveryCode != "" {
+		hash := auth.HashToken(auth.NormalizeRecoveryCode(input.RecoveryCode))
+		used, err := user.UseRecoveryCode(hash)
+		return err == nil && used
+	}
+	return false
+}
+
+// newRecoveryCodes generates the recovery codes for the User and their hashes
+// to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashToken(auth.NormalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// EnrollTOTPHandler generates a TOTP secret for the User. The returned
+// otpauth URI is shown as a QR code for the authenticator app. 2FA is enabled
+// once confirmed with ConfirmTOTPHandler.
+//
+// Enrolling again replaces a secret which was not confirmed.
+func EnrollTOTPHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabledAt != nil {
+		returnErrorAndAbort(c, http.StatusConflict, "2FA is already enabled.")
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error generating 2FA secret")
+		return
+	}
+
+	if err := user.StartTOTPEnrollment(secret); err != nil {
+		returnErrorAndAbort(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":     secret,
+		"otpauthURI": auth.TOTPURI(totpIssuer, user.Email, secret),
+		"msg":        "Add the account to an authenticator app and confirm it with a code.",
+	})
+}
+
+// ConfirmTOTPHandler enables 2FA for the User with a code of the enrolled
+// secret. The recovery codes are only shown in the response.
+func ConfirmTOTPHandler(c *gin.Context) {
+	var input TOTPCodeForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabledAt != nil {
+		returnErrorAndAbort(c, http.StatusConflict, "2FA is already enabled.")
+		return
+	}
+	if user.TOTPSecret == "" {
+		returnErrorAndAbort(c, http.StatusBadRequest, "2FA enrollment has not been started.")
+		return
+	}
+
+	step, valid := auth.ValidateTOTP(user.TOTPSecret, input.Code, time.Now())
+	if !valid {
+		returnErrorAndAbort(c, http.StatusBadRequest, "Invalid 2FA code.")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error generating recovery codes")
+		return
+	}
+
+	if err := user.EnableTOTP(step, hashes); err != nil {
+		returnErrorAndAbort(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recoveryCodes": codes,
+		"msg":           "2FA has been enabled. Keep the recovery codes somewhere safe, they are shown only once.",
+	})
+}
+
+// RegenerateRecoveryCodesHandler replaces the User's recovery codes. Requires
+// a code from the authenticator app.
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+	var input TOTPCodeForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabledAt == nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "2FA is not enabled.")
+		return
+	}
+	if !verifySecondFactor(user, SecondFactorForm{Code: input.Code}) {
+		returnErrorAndAbort(c, http.StatusForbidden, "Invalid 2FA code.")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusInternalServerError, "error generating recovery codes")
+		return
+	}
+
+	if err := user.ReplaceRecoveryCodes(hashes); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recoveryCodes": codes,
+		"msg":           "Recovery codes have been replaced. The old codes can no longer be used.",
+	})
+}
+
+// DisableTOTPHandler disables 2FA for the User. Requires their password and a
+// code or a recovery code.
+func DisableTOTPHandler(c *gin.Context) {
+	var input DisableTOTPForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabledAt == nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "2FA is not enabled.")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.Password, []byte(input.Password)); err != nil {
+		returnErrorAndAbort(c, http.StatusForbidden, "Password is invalid.")
+		return
+	}
+	if !verifySecondFactor(user, input.SecondFactorForm) {
+		returnErrorAndAbort(c, http.StatusForbidden, "Invalid 2FA code.")
+		return
+	}
+
+	if err := user.DisableTOTP(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg": "2FA has been disabled.",
+	})
+}
+
+// ResetTwoFactorHandler disables 2FA for a User who lost their authenticator
+// app and their recovery codes. The User's tokens are revoked.
+func (s *Server) ResetTwoFactorHandler(c *gin.Context) {
+	source, ok := s.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if source.TOTPEnabledAt == nil {
+		returnErrorAndAbort(c, http.StatusConflict, "2FA is not enabled.")
+		return
+	}
+
+	if err := s.Store.Users().ResetTwoFactor(source); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userID": source.ID,
+		"msg":    "2FA has been disabled for the user.",
+	})
+}
+
+// startChallenge signs the challenge token for a User with 2FA who logged in
+// with their password.
+func startChallenge(user *models.User, remember bool) (gin.H, error) {
+	jwtWrapper := auth.JwtWrapper{
+		Keys:       auth.DefaultKeys,
+		Issuer:     "AuthService",
+		Expiration: challengeTokenLifetime,
+	}
+
+	challenge, err := jwtWrapper.GenerateChallengeToken(user.ID, remember)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"twoFactorRequired": true,
+		"challengeToken":    challenge,
+		"expiresIn":         int(challengeTokenLifetime.Seconds()),
+	}, nil
+}
+
+// LoginTwoFactorHandler exchanges the challenge token from Login and a code or
+// a recovery code for the tokens of a new session.
+func LoginTwoFactorHandler(c *gin.Context) {
+	var input LoginTwoFactorForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	jwtWrapper := auth.JwtWrapper{
+		Keys:   auth.DefaultKeys,
+		Issuer: "AuthService",
+	}
+	claims, err := jwtWrapper.ParseChallengeToken(input.ChallengeToken)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusUnauthorized, "Invalid challenge token. Please log in again.")
+		return
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var user models.User
+	source := user.GetUserByID(userID)
+	if source == nil || source.TokenRevoked(claims.IssuedAt) || source.TOTPEnabledAt == nil {
+		returnErrorAndAbort(c, http.StatusUnauthorized, "Invalid challenge token. Please log in again.")
+		return
+	}
+	if source.DeactivatedAt != nil {
+		recordLoginAttempt(c, source.Email, source, models.LoginDeactivated)
+		returnErrorAndAbort(c, http.StatusForbidden, "Account is deactivated.")
+		return
+	}
+
+	// Guessing codes is throttled like guessing passwords.
+	defer finishLoginAttempt(c)
+	if !checkLoginThrottle(c, source.Email, source) {
+		return
+	}
+	if !verifySecondFactor(source, input.SecondFactorForm) {
+		recordLoginAttempt(c, source.Email, source, models.LoginInvalidTwoFactor)
+		returnErrorAndAbort(c, http.StatusUnauthorized, "Invalid 2FA code.")
+		return
+	}
+
+	completeLogin(c, source, claims.Remember)
+}
@@ -1,11 +1,11 @@
 package controllers
 
 import (
-	"fmt"
-	auth "issue-tracker/auth"
+	"issue-tracker/auth"
+	"issue-tracker/middlewares"
 	"issue-tracker/models"
+	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
@@ -14,11 +14,15 @@ import (
 )
 
 // RegisterForm binds the data from the Registration Form to the struct.
+//
+// With an Invitation token the Role is the invitation's, otherwise RoleID must
+// be a Role which can be chosen on Register.
 type RegisterForm struct {
-	RoleID   int    `form:"role" binding:"required"`
-	Name     string `form:"name" binding:"required"`
-	Email    string `form:"email" binding:"required"`
-	Password string `form:"password" binding:"required"`
+	RoleID     int    `form:"role"`
+	Name       string `form:"name" binding:"required"`
+	Email      string `form:"email" binding:"required"`
+	Password   string `form:"password" binding:"required"`
+	Invitation string `form:"invitation"`
 }
 
 // LoginForm binds the data from the Login form to the struct.
@@ -28,6 +32,27 @@ type LoginForm struct {
 	Remembered bool   `form:"remember"`
 }
 
+// LoginAttemptQuery takes the query parameters of the login audit.
+type LoginAttemptQuery struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
+}
+
+// UserIndexQuery takes the query parameters of the User Index.
+//
+// Active is "true" for active Users only, "false" for deactivated Users only.
+type UserIndexQuery struct {
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+	RoleID int    `form:"role"`
+	Active string `form:"active"`
+}
+
+// ChangeRoleForm takes the ID of the User's new Role.
+type ChangeRoleForm struct {
+	RoleID int `form:"role" binding:"required"`
+}
+
 // ChangePasswordForm is for binding the data from Update Password form.
 type ChangePasswordForm struct {
 	OldPassword     string `form:"old_password" binding:"required"`
@@ -40,13 +65,18 @@ type ChangePasswordForm struct {
 // 1. The functions will get the form data.
 // If there is an error, the func will send an error to the front.
 //
-// 2. Password will be encrypted.
+// 2. The invitation or the chosen Role is checked. Only invitees can register
+// when InviteOnly is set.
 //
-// 3. Send the data to the model to be saved to the database.
+// 3. Password will be encrypted.
+//
+// 4. Send the data to the model to be saved to the database.
+//
+// 5. Without an invitation, a verification e-mail is sent. The User can not use
+// the protected routes before verifying their e-mail.
 func RegisterHandler(c *gin.Context) {
 	// Check whether user is logged in.
-	token := c.Request.Header.Get("token")
-	if token != "" {
+	if middlewares.ExtractToken(c) != "" {
 		returnErrorAndAbort(c, http.StatusForbidden, "User is already logged in.")
 		return
 	}
@@ -60,6 +90,31 @@ func RegisterHandler(c *gin.Context) {
 		return
 	}
 
+	// An invitation decides the Role and proves the e-mail is the User's.
+	var invitation *models.Invitation
+	if input.Invitation != "" {
+		var pending models.Invitation
+		source, err := pending.FindPendingInvitationByHash(auth.HashToken(input.Invitation))
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusForbidden, err.Error())
+			return
+		}
+		if source.Email != strings.ToLower(input.Email) {
+			returnErrorAndAbort(c, http.StatusForbidden, "This invitation is for another e-mail.")
+			return
+		}
+		invitation = source
+	} else if InviteOnly {
+		returnErrorAndAbort(c, http.StatusForbidden, "Registration is by invitation only.")
+		return
+	} else {
+		var role models.Role
+		if !role.CanSelfRegister(input.RoleID) {
+			returnErrorAndAbort(c, http.StatusForbidden, "This role can not be chosen on registration.")
+			return
+		}
+	}
+
 	// Password encryption using bcrypt
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -78,7 +133,11 @@ func RegisterHandler(c *gin.Context) {
 		Password: hashedPassword,
 	}
 	// Saves user data.
-	err = user.SaveUserData()
+	if invitation != nil {
+		err = invitation.AcceptInvitation(&user)
+	} else {
+		err = user.SaveUserData()
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -87,8 +146,18 @@ func RegisterHandler(c *gin.Context) {
 		return
 	}
 
+	msg := "User registered successfully."
+	if user.EmailVerifiedAt == nil {
+		// The User can ask for another verification e-mail if this one fails.
+		if err := sendEmailVerification(&user); err != nil {
+			log.Printf("ERROR MAIL: e-mail verification for user %d: %s", user.ID, err)
+		}
+		msg = "User registered successfully. Please verify your e-mail."
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
-		"msg": "User registered successfully.",
+		"msg":           msg,
+		"emailVerified": user.EmailVerifiedAt != nil,
 	})
 }
 
@@ -96,17 +165,21 @@ func RegisterHandler(c *gin.Context) {
 //
 // 1. Binds the data from the login form
 //
-// 2. Check if user with inputted email exists
+// 2. Check the e-mail and the IP are not throttled after failed logins
+//
+// 3. Check if user with inputted email exists and the password, with the same
+// error for both
+//
+// 4. Send a challenge token if the User has 2FA, see LoginTwoFactorHandler
 //
-// 3. Check password
+// 5. Otherwise start a session with an access token and a refresh token
 //
-// 4. Generate JWT Token
+// 6. Send the tokens to the front.
 //
-// 5. Send the Token to the Header.
+// Every attempt is recorded as a LoginAttempt.
 func LoginHandler(c *gin.Context) {
 	// Check whether user is logged in.
-	token := c.Request.Header.Get("token")
-	if token != "" {
+	if middlewares.ExtractToken(c) != "" {
 		returnErrorAndAbort(c, http.StatusForbidden, "User is already logged in.")
 		return
 	}
@@ -119,44 +192,61 @@ func LoginHandler(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	email := strings.ToLower(strings.TrimSpace(input.Email))
 	userEmail := models.User{
-		Email: strings.ToLower(input.Email),
+		Email: email,
+	}
+	user := userEmail.GetUserByEmail()
+
+	defer finishLoginAttempt(c)
+	if !checkLoginThrottle(c, email, user) {
+		return
 	}
 
 	// Check if user with inputted email exists.
-	user := userEmail.GetUserByEmail()
 	if user == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": fmt.Sprintf("Couldn't found user with email: %s", input.Email),
-		})
-		c.Abort()
+		// Takes as long as checking a password, so the e-mail can not be told
+		// apart from an existing one.
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(input.Password))
+		recordLoginAttempt(c, email, nil, models.LoginUnknownEmail)
+		returnErrorAndAbort(c, http.StatusUnauthorized, invalidCredentials)
 		return
 	}
 
 	// Check if inputted password is the same as the User's stored password.
 	err := bcrypt.CompareHashAndPassword(user.Password, []byte(input.Password))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "invalid user credential",
-		})
-		c.Abort()
+		recordLoginAttempt(c, email, user, models.LoginInvalidPassword)
+		returnErrorAndAbort(c, http.StatusUnauthorized, invalidCredentials)
 		return
 	}
 
-	var expirationHours = 24
-	if input.Remembered {
-		// Expired in 1 year.
-		expirationHours = 8760
+	if user.DeactivatedAt != nil {
+		recordLoginAttempt(c, email, user, models.LoginDeactivated)
+		returnErrorAndAbort(c, http.StatusForbidden, "Account is deactivated.")
+		return
 	}
 
-	// JWT generation
-	jwtWrapper := auth.JwtWrapper{
-		SecretKey:       os.Getenv("JWT_SECRET"),
-		Issuer:          "AuthService",
-		ExpirationHours: int64(expirationHours),
+	// A User with 2FA gets a challenge token to exchange with their code.
+	if user.TOTPEnabledAt != nil {
+		response, err := startChallenge(user, input.Remembered)
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusInternalServerError, "error signing token")
+			return
+		}
+		recordLoginAttempt(c, email, user, models.LoginTwoFactorRequired)
+		c.JSON(http.StatusOK, response)
+		return
 	}
 
-	signedToken, err := jwtWrapper.GenerateToken(user.Email)
+	completeLogin(c, user, input.Remembered)
+}
+
+// completeLogin starts a session for the logged in User and sends the tokens
+// to the front.
+func completeLogin(c *gin.Context, user *models.User, remember bool) {
+	// Starts a session with a short-lived access token and a refresh token.
+	response, err := startSession(user, remember)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "error signing token",
@@ -165,22 +255,28 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"token":     signedToken,
-		"userID":    user.ID,
-		"userRole":  user.RoleID,
-		"userEmail": user.Email,
-		"userName":  user.Name,
-	})
+	response["userID"] = user.ID
+	response["userRole"] = user.RoleID
+	response["userEmail"] = user.Email
+	response["userName"] = user.Name
+
+	// The token only allows changing the password until it is changed.
+	response["mustResetPassword"] = user.MustResetPassword
+	// The token is refused on protected routes until the e-mail is verified.
+	response["emailVerified"] = user.EmailVerifiedAt != nil
+	response["twoFactorRequired"] = false
+
+	recordLoginAttempt(c, user.Email, user, models.LoginSucceeded)
+	c.JSON(http.StatusCreated, response)
 }
 
 // ChangePasswordHandler handles user's change password request.
-func ChangePasswordHandler(c *gin.Context) {
+func (s *Server) ChangePasswordHandler(c *gin.Context) {
 	/*
 		Flow:
 		1. Validate whether New Password and Confirm Password is the same.
-		2. Get User ID from header
-		3. Get User data by searching ID
+		2. Get the User from the token
+		3. Check the User is changing their own password
 		4. Validate Old Password with User's recorded password
 		5. Update Password
 	*/
@@ -195,9 +291,8 @@ func ChangePasswordHandler(c *gin.Context) {
 		return
 	}
 
-	userIDHeader, err := strconv.Atoi(c.GetHeader("userID"))
-	if err != nil {
-		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+	source, ok := currentUser(c)
+	if !ok {
 		return
 	}
 	userID, err := strconv.Atoi(c.Param("id"))
@@ -206,19 +301,11 @@ func ChangePasswordHandler(c *gin.Context) {
 		return
 	}
 
-	if userIDHeader != userID {
+	if int(source.ID) != userID {
 		returnErrorAndAbort(c, http.StatusForbidden, "This user is not allowed to access this request.")
 		return
 	}
 
-	// Get User from User ID
-	var user models.User
-	source := user.GetUserByID(userID)
-	if source == nil {
-		returnErrorAndAbort(c, http.StatusNotFound, "User not found.")
-		return
-	}
-
 	// Check if the Old Password is the same with the new one.
 	err = bcrypt.CompareHashAndPassword(source.Password, []byte(input.OldPassword))
 	if err != nil {
@@ -233,7 +320,7 @@ func ChangePasswordHandler(c *gin.Context) {
 		return
 	}
 
-	err = source.UpdatePassword(newPassword)
+	err = s.Store.Users().UpdatePassword(source, newPassword)
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
 		return
@@ -249,16 +336,15 @@ func ChangePasswordHandler(c *gin.Context) {
 // Requires:
 //
 // - UserID from the param (URL)
-func ShowUserHandler(c *gin.Context) {
-	userID, err := strconv.Atoi(c.Param("id"))
+func (s *Server) ShowUserHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
 		returnErrorAndAbort(c, http.StatusBadRequest, "No user ID provided.")
 		return
 	}
 
-	var user models.User
-	result := user.GetUserByID(userID)
-	if result == nil {
+	result, err := s.Store.Users().FindByID(uint(userID))
+	if err != nil {
 		returnErrorAndAbort(c, http.StatusNotFound, "No User found.")
 		return
 	}
@@ -268,3 +354,209 @@ func ShowUserHandler(c *gin.Context) {
 		"data": result,
 	})
 }
+
+// IndexUserHandler shows Users, one page at a time.
+//
+// /v1/protected/users/index?role=2&active=false
+func (s *Server) IndexUserHandler(c *gin.Context) {
+	var input UserIndexQuery
+	if err := c.ShouldBindQuery(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := models.UserFilter{
+		RoleID: input.RoleID,
+		Page:   input.Page,
+		Limit:  input.Limit,
+	}
+	if input.Active != "" {
+		active, err := strconv.ParseBool(input.Active)
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusBadRequest, "active must be true or false.")
+			return
+		}
+		filter.Active = &active
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = defaultIndexLimit
+	}
+	if filter.Limit > maxIndexLimit {
+		filter.Limit = maxIndexLimit
+	}
+
+	result, total, err := s.Store.Users().Index(filter)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":   len(*result),
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+		"links": pageLinks(c, filter.Page, filter.Limit, total),
+		"data":  result,
+	})
+}
+
+// IndexLoginAttemptHandler lists the login audit records of a User, newest
+// first.
+func IndexLoginAttemptHandler(c *gin.Context) {
+	var user models.User
+	var attempt models.LoginAttempt
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "No user ID provided.")
+		return
+	}
+
+	var input LoginAttemptQuery
+	if err := c.ShouldBindQuery(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.Page < 1 {
+		input.Page = 1
+	}
+	if input.Limit < 1 {
+		input.Limit = defaultIndexLimit
+	}
+	if input.Limit > maxIndexLimit {
+		input.Limit = maxIndexLimit
+	}
+
+	source := user.GetUserByID(userID)
+	if source == nil {
+		returnErrorAndAbort(c, http.StatusNotFound, "No User found.")
+		return
+	}
+
+	result, total, err := attempt.IndexLoginAttempts(source.ID, input.Page, input.Limit)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":   len(*result),
+		"total": total,
+		"page":  input.Page,
+		"limit": input.Limit,
+		"links": pageLinks(c, input.Page, input.Limit, total),
+		"data":  result,
+	})
+}
+
+// findManagedUser fetches the User from the :id Param for a user management
+// request. Aborts the request and returns false if the User does not exist or
+// is the User doing the request, who can not lock themselves out.
+func (s *Server) findManagedUser(c *gin.Context) (*models.User, bool) {
+	actor, ok := currentUser(c)
+	if !ok {
+		return nil, false
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "No user ID provided.")
+		return nil, false
+	}
+
+	source, err := s.Store.Users().FindByID(uint(userID))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, "No User found.")
+		return nil, false
+	}
+
+	if source.ID == actor.ID {
+		returnErrorAndAbort(c, http.StatusForbidden, "Users can not manage their own account.")
+		return nil, false
+	}
+	return source, true
+}
+
+// ChangeUserRoleHandler gives a User another Role.
+func (s *Server) ChangeUserRoleHandler(c *gin.Context) {
+	var input ChangeRoleForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, ok := s.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := s.Store.Users().ChangeRole(source, input.RoleID); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userID":   source.ID,
+		"userRole": source.RoleID,
+		"msg":      "Role has been changed succesfully.",
+	})
+}
+
+// DeactivateUserHandler blocks a User from logging in and revokes their tokens.
+func (s *Server) DeactivateUserHandler(c *gin.Context) {
+	source, ok := s.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := s.Store.Users().Deactivate(source); err != nil {
+		returnErrorAndAbort(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userID": source.ID,
+		"msg":    "User has been deactivated.",
+	})
+}
+
+// ReactivateUserHandler allows a deactivated User to log in again.
+func (s *Server) ReactivateUserHandler(c *gin.Context) {
+	source, ok := s.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := s.Store.Users().Reactivate(source); err != nil {
+		returnErrorAndAbort(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userID": source.ID,
+		"msg":    "User has been reactivated.",
+	})
+}
+
+// ForcePasswordResetHandler revokes a User's tokens and makes them change
+// their password after logging in again.
+func (s *Server) ForcePasswordResetHandler(c *gin.Context) {
+	source, ok := s.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := s.Store.Users().ForcePasswordReset(source); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userID": source.ID,
+		"msg":    "User has to reset their password on the next login.",
+	})
+}
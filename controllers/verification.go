@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"fmt"
+	"issue-tracker/auth"
+	"issue-tracker/mailer"
+	"issue-tracker/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// emailVerificationLifetime is how long an e-mail verification token can
+	// be used.
+	emailVerificationLifetime = 24 * time.Hour
+	// emailVerificationInterval is how long a User waits for another
+	// verification e-mail.
+	emailVerificationInterval = time.Minute
+)
+
+// VerifyEmailForm takes the e-mailed verification token.
+type VerifyEmailForm struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// ResendVerificationForm takes the e-mail of the User whose verification
+// e-mail is sent again.
+type ResendVerificationForm struct {
+	Email string `form:"email" binding:"required"`
+}
+
+// sendEmailVerification e-mails a new verification token to the User.
+func sendEmailVerification(user *models.User) error {
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	verification := models.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationLifetime),
+	}
+	if err := verification.SaveEmailVerificationToken(); err != nil {
+		return err
+	}
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your e-mail",
+		Body: fmt.Sprintf("Hi %s,\n\n%s\n\nIt expires in %d hours. "+
+			"If you did not register, you can ignore this e-mail.\n",
+			user.Name, tokenInstructions("EMAIL_VERIFICATION_URL", "verify your e-mail", token),
+			int(emailVerificationLifetime.Hours())),
+	}, fmt.Sprintf("e-mail verification for user %d", user.ID))
+	return nil
+}
+
+// VerifyEmailHandler verifies the User's e-mail with an e-mailed token, which
+// allows them to use the protected routes.
+func VerifyEmailHandler(c *gin.Context) {
+	var verification models.EmailVerificationToken
+
+	var input VerifyEmailForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := verification.VerifyEmail(auth.HashToken(input.Token))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userID": user.ID,
+		"msg":    "E-mail has been verified.",
+	})
+}
+
+// ResendVerificationHandler e-mails another verification token to a User
+// whose e-mail is not verified.
+//
+// The response is the same whether the User exists or not, so the endpoint can
+// not be used to find out who has an account.
+func ResendVerificationHandler(c *gin.Context) {
+	var verification models.EmailVerificationToken
+
+	var input ResendVerificationForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := gin.H{
+		"msg": "If the e-mail belongs to an unverified account, a verification link has been sent to it.",
+	}
+
+	userEmail := models.User{
+		Email: strings.ToLower(input.Email),
+	}
+	user := userEmail.GetUserByEmail()
+	if user == nil || user.DeactivatedAt != nil || user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	recent, err := verification.RecentlyIssued(user.ID, time.Now().Add(-emailVerificationInterval))
+	if err != nil || recent {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	if err := sendEmailVerification(user); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"issue-tracker/middlewares"
+	"issue-tracker/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// register posts the form to RegisterHandler.
+func register(form url.Values) *httptest.ResponseRecorder {
+	return serve(RegisterHandler, "/register", nil, nil, "POST", "/register", form)
+}
+
+// authorized calls a route behind AuthJWT with the token.
+func authorized(token string) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/protected", middlewares.AuthJWT(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestUnverifiedEmail(t *testing.T) {
+	setupSQLite(t)
+	memory := useMemoryMailer(t)
+
+	rec := register(url.Values{"name": {"qa"}, "email": {"qa@email.com"}, "password": {"secret"}, "role": {"1"}})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"emailVerified":false`)
+
+	user := (&models.User{Email: "qa@email.com"}).GetUserByEmail()
+	if !assert.NotNil(t, user) {
+		return
+	}
+	session, err := startSession(user, false)
+	assert.NoError(t, err)
+	token := session["token"].(string)
+	assert.Equal(t, http.StatusForbidden, authorized(token), "protected routes are refused until the e-mail is verified")
+
+	verify := serve(VerifyEmailHandler, "/verify", nil, nil, "POST", "/verify",
+		url.Values{"token": {mailedToken(t, memory, "qa@email.com")}})
+	assert.Equal(t, http.StatusOK, verify.Code)
+	assert.Equal(t, http.StatusOK, authorized(token))
+}
@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"issue-tracker/models"
+	"issue-tracker/webhooks"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookCreateForm takes User's input on Webhook Create form.
+//
+// Events is a comma separated list, for example "issue.created,reply.created".
+// Project is the Key of the Project whose events are sent, without it the events
+// of every Project are sent. Without a Secret one is generated.
+type WebhookCreateForm struct {
+	URL     string `form:"url" binding:"required"`
+	Events  string `form:"events" binding:"required"`
+	Project string `form:"project"`
+	Secret  string `form:"secret"`
+}
+
+// WebhookUpdateForm is for Updating. The Project and Secret can not be changed.
+type WebhookUpdateForm struct {
+	URL    string `form:"url" binding:"required"`
+	Events string `form:"events" binding:"required"`
+	Active bool   `form:"active"`
+}
+
+// WebhookDeliveryQuery takes the query parameters of the delivery log.
+type WebhookDeliveryQuery struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
+}
+
+// findOwnWebhook fetches the Webhook from the :id Param. Aborts the request
+// and returns false if it does not exist or is not owned by the User.
+func findOwnWebhook(c *gin.Context) (*models.Webhook, bool) {
+	var webhook models.Webhook
+
+	user, ok := currentUser(c)
+	if !ok {
+		return nil, false
+	}
+
+	source, err := webhook.FindWebhookByID(c.Param("id"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+
+	// Someone else's Webhook is reported as missing, its URL and events are private.
+	if source.OwnerID != int(user.ID) {
+		returnErrorAndAbort(c, http.StatusNotFound, "Webhook not found.")
+		return nil, false
+	}
+	return source, true
+}
+
+// CreateWebhookHandler registers a Webhook.
+//
+// The secret is only shown in this response.
+func CreateWebhookHandler(c *gin.Context) {
+	var input WebhookCreateForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	webhook := models.Webhook{
+		OwnerID: int(user.ID),
+		URL:     input.URL,
+		Events:  input.Events,
+		Secret:  input.Secret,
+		Active:  true,
+	}
+
+	if input.Project != "" {
+		var project models.Project
+		source, err := project.FindProjectByKey(input.Project)
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+			return
+		}
+		webhook.ProjectID = &source.ID
+	}
+
+	if webhook.Secret == "" {
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			returnErrorAndAbort(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		webhook.Secret = secret
+	}
+
+	if err := webhook.ValidateWebhook(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := webhook.SaveWebhook(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhookID": webhook.ID,
+		"secret":    webhook.Secret,
+		"msg":       "Data succesfully created.",
+	})
+}
+
+// IndexWebhookHandler shows the User's Webhooks.
+func IndexWebhookHandler(c *gin.Context) {
+	var webhook models.Webhook
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	result, err := webhook.IndexWebhooks(int(user.ID))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":    len(*result),
+		"events": models.WebhookEvents,
+		"data":   result,
+	})
+}
+
+// ShowWebhookHandler fetch ONE of the User's Webhooks by ID.
+func ShowWebhookHandler(c *gin.Context) {
+	source, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": source,
+	})
+}
+
+// UpdateWebhookHandler updates a Webhook's URL, events and whether it is active.
+func UpdateWebhookHandler(c *gin.Context) {
+	source, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input WebhookUpdateForm
+	if err := c.ShouldBind(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	webhook := models.Webhook{
+		URL:    input.URL,
+		Events: input.Events,
+		Active: input.Active,
+	}
+	if err := webhook.ValidateWebhook(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := webhook.UpdateWebhook(source); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhookID": source.ID,
+		"msg":       "Data has been updated succesfully.",
+	})
+}
+
+// DeleteWebhookHandler deletes a Webhook and its delivery log.
+func DeleteWebhookHandler(c *gin.Context) {
+	source, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := source.DeleteWebhook(); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, "Unable to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{
+		"data": "deleted",
+		"msg":  "webhook is deleted successfully",
+	})
+}
+
+// IndexWebhookDeliveryHandler shows the delivery log of a Webhook, newest first.
+//
+// /v1/protected/webhook/show/:id/deliveries?page=2&limit=50
+func IndexWebhookDeliveryHandler(c *gin.Context) {
+	var delivery models.WebhookDelivery
+
+	source, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input WebhookDeliveryQuery
+	if err := c.ShouldBindQuery(&input); err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.Page < 1 {
+		input.Page = 1
+	}
+	if input.Limit < 1 {
+		input.Limit = defaultIndexLimit
+	}
+	if input.Limit > maxIndexLimit {
+		input.Limit = maxIndexLimit
+	}
+
+	result, total, err := delivery.IndexDeliveries(source.ID, input.Page, input.Limit)
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"qty":   len(*result),
+		"total": total,
+		"page":  input.Page,
+		"limit": input.Limit,
+		"links": pageLinks(c, input.Page, input.Limit, total),
+		"data":  result,
+	})
+}
+
+// RedeliverWebhookHandler queues a delivery of the Webhook again, with the same
+// event and payload.
+func RedeliverWebhookHandler(c *gin.Context) {
+	var delivery models.WebhookDelivery
+
+	source, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliverySource, err := delivery.FindDeliveryByID(source.ID, c.Param("deliveryId"))
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	result, err := deliverySource.Redeliver()
+	if err != nil {
+		returnErrorAndAbort(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"deliveryID": result.ID,
+		"msg":        "Delivery has been queued.",
+	})
+}
@@ -1,26 +1,68 @@
 package database
 
 import (
+	"fmt"
 	"log"
 	"os"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// Drivers of the databases which can be opened.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // Initialize DB's connection and migration.
+//
+// DATABASE_DRIVER chooses the database, "postgres" by default, and
+// DATABASE_URL is its DSN. For SQLite it is the path of the database file.
 func InitializeDB() {
 	// err := godotenv.Load()
 	// if err != nil {
 	// 	log.Fatal(err)
 	// }
 
-	db, err := gorm.Open(postgres.Open(os.Getenv("DATABASE_URL")), &gorm.Config{})
+	db, err := Open(os.Getenv("DATABASE_DRIVER"), os.Getenv("DATABASE_URL"))
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	DB = db
 }
+
+// Open connects to the database of the driver at the DSN. An empty driver is
+// DriverPostgres.
+//
+// SQLite allows only one writer at a time, so its connection pool is limited
+// to one connection and foreign keys are turned on for it. This also keeps an
+// in-memory database (":memory:") on a single connection.
+func Open(driver string, dsn string) (*gorm.DB, error) {
+	switch driver {
+	case "", DriverPostgres:
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	case DriverSQLite:
+		db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+		if err != nil {
+			return nil, err
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+
+		if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+			return nil, err
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("ERROR DATABASE_DRIVER: unknown driver %q, must be %s or %s", driver, DriverPostgres, DriverSQLite)
+	}
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenSQLite(t *testing.T) {
+	db, err := Open(DriverSQLite, ":memory:")
+	assert.NoError(t, err)
+	assert.Equal(t, DriverSQLite, db.Dialector.Name())
+
+	var foreignKeys int
+	assert.NoError(t, db.Raw("PRAGMA foreign_keys").Scan(&foreignKeys).Error)
+	assert.Equal(t, 1, foreignKeys)
+
+	// The in-memory database is kept on one connection, so tables are seen by
+	// every query.
+	assert.NoError(t, db.Exec("CREATE TABLE things (id integer primary key)").Error)
+	assert.True(t, db.Migrator().HasTable("things"))
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	_, err := Open("mysql", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"issue-tracker/models"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const issueUsage = `Usage:
+  issue export [-project KEY] [-file FILE]
+  issue import -project KEY -file FILE [-reporter EMAIL]
+
+export writes the Issues, of every Project or of one, with their Replies as
+JSON to FILE or to the output. Labels, assignees and history are left out.
+
+import adds the Issues of an export to the Project, with new Keys. Reporters
+and Replies' authors are found by e-mail, and -reporter stands in for the ones
+who are not Users here. Nothing is imported if one Issue fails.`
+
+// exportedIssue is an Issue in the JSON of the issue command.
+type exportedIssue struct {
+	Key       string          `json:"key"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Severity  string          `json:"severity"`
+	Status    string          `json:"status"`
+	Reporter  string          `json:"reporter"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Replies   []exportedReply `json:"replies"`
+}
+
+// exportedReply is a Reply of an exportedIssue.
+type exportedReply struct {
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// runIssue runs the issue command.
+func runIssue(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 || isHelp(args[0]) {
+		fmt.Fprintln(out, issueUsage)
+		if len(args) > 0 {
+			return flag.ErrHelp
+		}
+		return errors.New("ERROR ISSUE: missing subcommand")
+	}
+
+	flags := newFlagSet("issue "+args[0], issueUsage, out)
+	projectKey := flags.String("project", "", "key of the Project")
+	file := flags.String("file", "", "JSON file")
+
+	switch args[0] {
+	case "export":
+		if err := parseFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return exportIssues(db, *projectKey, out)
+		}
+
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		if err := exportIssues(db, *projectKey, f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+
+	case "import":
+		reporter := flags.String("reporter", "", "e-mail of the User reporting for unknown Users")
+		if err := parseFlags(flags, args[1:], "project", "file"); err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		var issues []exportedIssue
+		if err := json.Unmarshal(data, &issues); err != nil {
+			return fmt.Errorf("ERROR ISSUE IMPORT: %s is not an export: %s", *file, err.Error())
+		}
+		return importIssues(db, *projectKey, *reporter, issues, out)
+
+	default:
+		fmt.Fprintln(out, issueUsage)
+		return fmt.Errorf("ERROR ISSUE: unknown subcommand %q", args[0])
+	}
+}
+
+// exportIssues writes the Issues of the Project, or of every Project when the
+// key is empty, as JSON.
+func exportIssues(db *gorm.DB, projectKey string, w io.Writer) error {
+	query := db.Preload("Status").Preload("Replies", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("replies.created_at, replies.id")
+	})
+	if projectKey != "" {
+		project, err := findProjectByKey(db, projectKey)
+		if err != nil {
+			return err
+		}
+		query = query.Where("project_id = ?", project.ID)
+	}
+
+	var issues []models.Issue
+	if err := query.Order("project_id, number").Find(&issues).Error; err != nil {
+		return err
+	}
+
+	var users []models.User
+	if err := db.Unscoped().Select("id", "email").Find(&users).Error; err != nil {
+		return err
+	}
+	emails := make(map[uint]string, len(users))
+	for _, user := range users {
+		emails[user.ID] = user.Email
+	}
+
+	exported := make([]exportedIssue, len(issues))
+	for i, issue := range issues {
+		exported[i] = exportedIssue{
+			Key:       issue.Key,
+			Title:     issue.Title,
+			Body:      issue.Body,
+			Severity:  issue.Severity,
+			Status:    issue.Status.Name,
+			Reporter:  emails[uint(issue.UserID)],
+			CreatedAt: issue.CreatedAt,
+			Replies:   make([]exportedReply, len(issue.Replies)),
+		}
+		for j, reply := range issue.Replies {
+			exported[i].Replies[j] = exportedReply{
+				Author:    emails[reply.UserID],
+				Body:      reply.Body,
+				CreatedAt: reply.CreatedAt,
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(exported)
+}
+
+// importIssues adds the exported Issues to the Project in one transaction.
+//
+// Replies are saved without notifying anyone, and no Webhook events are sent.
+func importIssues(db *gorm.DB, projectKey string, reporterEmail string, issues []exportedIssue, out io.Writer) error {
+	project, err := findProjectByKey(db, projectKey)
+	if err != nil {
+		return err
+	}
+
+	var statuses []models.Status
+	if err := db.Find(&statuses).Error; err != nil {
+		return err
+	}
+
+	var fallback *models.User
+	if reporterEmail != "" {
+		fallback, err = findUserByEmail(db, reporterEmail)
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(issues))
+	err = db.Transaction(func(tx *gorm.DB) error {
+		store := models.NewGormStore(tx)
+		findUser := func(email string) (*models.User, error) {
+			user, err := store.Users().FindByEmail(email)
+			if err == nil {
+				return user, nil
+			}
+			if fallback == nil {
+				return nil, fmt.Errorf("ERROR ISSUE IMPORT: %q is not a User here, use -reporter", email)
+			}
+			return fallback, nil
+		}
+
+		for _, exported := range issues {
+			reporter, err := findUser(exported.Reporter)
+			if err != nil {
+				return err
+			}
+			statusID, err := findStatusID(statuses, exported.Status)
+			if err != nil {
+				return err
+			}
+
+			issue := models.Issue{
+				UserID:   int(reporter.ID),
+				Title:    exported.Title,
+				Body:     exported.Body,
+				Severity: exported.Severity,
+				StatusID: statusID,
+			}
+			issue.CreatedAt = exported.CreatedAt
+			if err := issue.ValidateIssue(); err != nil {
+				return fmt.Errorf("ERROR ISSUE IMPORT %s: %s", exported.Key, err.Error())
+			}
+			if err := store.Issues().Create(&issue, project); err != nil {
+				return err
+			}
+
+			for _, exportedReply := range exported.Replies {
+				author, err := findUser(exportedReply.Author)
+				if err != nil {
+					return err
+				}
+				reply := models.Reply{UserID: author.ID, IssueID: issue.ID, Body: exportedReply.Body}
+				reply.CreatedAt = exportedReply.CreatedAt
+				if err := tx.Create(&reply).Error; err != nil {
+					return err
+				}
+			}
+
+			keys = append(keys, issue.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keys {
+		fmt.Fprintf(out, "Imported %s as %s.\n", issues[i].Key, key)
+	}
+	fmt.Fprintf(out, "Imported %d issue(s) into %s.\n", len(issues), project.Key)
+	return nil
+}
+
+func findProjectByKey(db *gorm.DB, key string) (*models.Project, error) {
+	var project models.Project
+	if err := db.Where("projects.key = ?", strings.ToUpper(key)).First(&project).Error; err != nil {
+		return nil, fmt.Errorf("ERROR: could not find project with key: %s", key)
+	}
+	return &project, nil
+}
+
+// findStatusID finds a Status by its name, ignoring case. An empty name is the
+// Open Status.
+func findStatusID(statuses []models.Status, name string) (uint, error) {
+	if name == "" {
+		return models.StatusOpen, nil
+	}
+	for _, status := range statuses {
+		if strings.EqualFold(status.Name, name) {
+			return status.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("ERROR ISSUE IMPORT: could not find status %q", name)
+}
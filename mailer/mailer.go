@@ -0,0 +1,37 @@
+// Package mailer sends the e-mails of the tracker, like password reset links.
+package mailer
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidHeader is returned when a Message's recipient or subject contains
+// a line break, which would inject headers into the e-mail.
+var ErrInvalidHeader = errors.New("ERROR MAIL: header must not contain line breaks")
+
+// Message is a plain text e-mail.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends Messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the Mailer the tracker sends e-mails with.
+var Default Mailer
+
+// validate checks the headers of the Message.
+func (m Message) validate() error {
+	if m.To == "" {
+		return errors.New("ERROR MAIL: recipient must not be empty")
+	}
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return ErrInvalidHeader
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	assert.NoError(t, m.Send(Message{To: "a@a", Subject: "first", Body: "1"}))
+	assert.NoError(t, m.Send(Message{To: "b@b", Subject: "second", Body: "2"}))
+	assert.NoError(t, m.Send(Message{To: "a@a", Subject: "third", Body: "3"}))
+
+	assert.Len(t, m.Messages(), 3)
+
+	last, ok := m.Last("a@a")
+	assert.True(t, ok)
+	assert.Equal(t, "third", last.Subject)
+
+	_, ok = m.Last("c@c")
+	assert.False(t, ok)
+}
+
+func TestSendRejectsHeaderInjection(t *testing.T) {
+	m := NewMemory()
+	assert.Equal(t, ErrInvalidHeader, m.Send(Message{To: "a@a\r\nBcc: b@b", Subject: "s"}))
+	assert.Equal(t, ErrInvalidHeader, m.Send(Message{To: "a@a", Subject: "s\nBcc: b@b"}))
+	assert.Error(t, m.Send(Message{Subject: "s"}))
+	assert.Empty(t, m.Messages())
+}
+
+func TestSMTPCompose(t *testing.T) {
+	s := &SMTP{From: "tracker@example.com"}
+	date := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	composed := string(s.compose(Message{To: "a@a", Subject: "Réinitialiser", Body: "Hello"}, date))
+
+	headers, body := composed[:strings.Index(composed, "\r\n\r\n")], composed[strings.Index(composed, "\r\n\r\n")+4:]
+	assert.Contains(t, headers, "From: tracker@example.com\r\n")
+	assert.Contains(t, headers, "To: a@a\r\n")
+	assert.Contains(t, headers, "Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n")
+	assert.Contains(t, headers, "Date: Wed, 03 Feb 2021 04:05:06 +0000")
+	assert.Equal(t, "Hello", body)
+}
@@ -1,17 +1,29 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"issue-tracker/auth"
 	"issue-tracker/controllers"
 	"issue-tracker/database"
+	"issue-tracker/mailer"
 	"issue-tracker/middlewares"
-	migrations "issue-tracker/migrations"
+	"issue-tracker/models"
+	"issue-tracker/seeds"
+	"issue-tracker/storage"
+	"issue-tracker/webhooks"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func main() {
@@ -26,17 +38,141 @@ func main() {
 		}
 	}
 
+	// The first argument is the command, serve by default. See commands.
+	name, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		name, args = os.Args[1], os.Args[2:]
+	}
+	command, ok := commands[name]
+	if !ok {
+		if isHelp(name) {
+			printUsage(os.Stdout)
+			return
+		}
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	// Initialize Database.
+	database.InitializeDB()
+
+	// The other commands report their errors themselves.
+	if name != "serve" {
+		database.DB.Logger = logger.Default.LogMode(logger.Silent)
+	}
+
+	if err := command.Run(database.DB, args, os.Stdout); err != nil && err != flag.ErrHelp {
+		log.Fatal(err)
+	}
+}
+
+// serve runs the HTTP server.
+func serve(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return fmt.Errorf("ERROR SERVE: unknown arguments %q", args)
+	}
+
 	// Gets PORT on environment.
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	// Initialize Database.
-	database.InitializeDB()
+	// Table Migration, applied on start only with MIGRATE_ON_START=true.
+	migrateOnStart := false
+	if value := os.Getenv("MIGRATE_ON_START"); value != "" {
+		var err error
+		migrateOnStart, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("MIGRATE_ON_START must be true or false, got %q", value)
+		}
+	}
+	if err := checkMigrations(db, migrateOnStart); err != nil {
+		log.Fatal(err)
+	}
+
+	// Built-in Roles, workflow and Severities which are missing.
+	if err := seeds.Builtins(db); err != nil {
+		log.Fatal(err)
+	}
+
+	// The first admin, created on start while there is no admin.
+	if email := os.Getenv("BOOTSTRAP_ADMIN_EMAIL"); email != "" {
+		admin, err := seeds.BootstrapAdmin(db, seeds.Admin{
+			Email:    email,
+			Name:     os.Getenv("BOOTSTRAP_ADMIN_NAME"),
+			Password: os.Getenv("BOOTSTRAP_ADMIN_PASSWORD"),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if admin != nil {
+			log.Printf("Created the admin %s, who has to change the password after logging in.", admin.Email)
+		}
+	}
+
+	// Keys the tokens are signed with, see auth.LoadKeySetFromEnv.
+	keys, err := auth.LoadKeySetFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	auth.DefaultKeys = keys
+
+	// Attachment storage, "uploads" by default.
+	attachmentDir := os.Getenv("ATTACHMENT_DIR")
+	if attachmentDir == "" {
+		attachmentDir = "uploads"
+	}
+	local, err := storage.NewLocal(attachmentDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	storage.Default = local
+
+	// Maximum size of one attachment in bytes, 10 MiB by default.
+	if maxSize := os.Getenv("ATTACHMENT_MAX_SIZE"); maxSize != "" {
+		size, err := strconv.ParseInt(maxSize, 10, 64)
+		if err != nil || size < 1 {
+			log.Fatalf("ATTACHMENT_MAX_SIZE must be a positive number of bytes, got %q", maxSize)
+		}
+		models.MaxAttachmentSize = size
+	}
+
+	// E-mails are sent through SMTP_HOST. Without it they are only kept in memory.
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpPort := 587
+		if p := os.Getenv("SMTP_PORT"); p != "" {
+			smtpPort, err = strconv.Atoi(p)
+			if err != nil {
+				log.Fatalf("SMTP_PORT must be a port number, got %q", p)
+			}
+		}
+		mailer.Default = &mailer.SMTP{
+			Host:     smtpHost,
+			Port:     smtpPort,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	} else {
+		log.Printf("SMTP_HOST is not set, e-mails will not be sent.")
+		mailer.Default = mailer.NewMemory()
+	}
 
-	// Table Migration
-	migrations.MigrateTables(database.DB)
+	// Only invited people can register with INVITE_ONLY=true.
+	if inviteOnly := os.Getenv("INVITE_ONLY"); inviteOnly != "" {
+		controllers.InviteOnly, err = strconv.ParseBool(inviteOnly)
+		if err != nil {
+			log.Fatalf("INVITE_ONLY must be true or false, got %q", inviteOnly)
+		}
+	}
+
+	// Sends the queued Webhook deliveries in the background.
+	go webhooks.NewDispatcher().Run(context.Background())
+
+	// The handlers which are methods of the Server use the repositories of the
+	// database.
+	server := controllers.NewServer(models.NewGormStore(db))
 
 	// Initiate Gin's default engine
 	r := gin.Default()
@@ -62,6 +198,9 @@ func main() {
 		return
 	})
 
+	// Public keys of the tokens as a JSON Web Key Set.
+	r.GET("/.well-known/jwks.json", controllers.JWKSHandler)
+
 	r.GET("/robots", func(c *gin.Context) {
 		c.JSON(200, gin.H{"msg": "Please don't do anything bad to this service :)."})
 	})
@@ -71,80 +210,421 @@ func main() {
 		// All requests on "/public" does not require any Header.
 		public := v1.Group("/public")
 		{
+			// Requires:
+			// - Form with input name as follows:
+			//     - name
+			//     - email
+			//     - password
+			//     - role (the Role ID, unless invited)
+			//     - invitation (the token from the invitation e-mail, optional
+			//       unless INVITE_ONLY is set)
 			public.POST("/register", controllers.RegisterHandler)
+			// Requires:
+			// - Form with input name as follows:
+			//     - email
+			//     - password
+			//     - remember (optional)
 			public.POST("/login", controllers.LoginHandler)
+			// Requires:
+			// - Form with input name as follows:
+			//     - challenge_token (from /login, for Users with 2FA)
+			//     - code (from the authenticator app) or recovery_code
+			public.POST("/login/2fa", controllers.LoginTwoFactorHandler)
+			// Requires:
+			// - Form with input name as follows:
+			//     - refresh_token
+			public.POST("/refresh", controllers.RefreshHandler)
+			// Requires:
+			// - Form with input name as follows:
+			//     - email
+			public.POST("/forgot-password", controllers.ForgotPasswordHandler)
+			// Requires:
+			// - Form with input name as follows:
+			//     - token (from the password reset e-mail)
+			//     - new_password
+			//     - confirm_password
+			public.POST("/reset-password", controllers.ResetPasswordHandler)
+			// Requires:
+			// - Form with input name as follows:
+			//     - token (from the verification e-mail)
+			public.POST("/verify-email", controllers.VerifyEmailHandler)
+			// Requires:
+			// - Form with input name as follows:
+			//     - email
+			public.POST("/resend-verification", controllers.ResendVerificationHandler)
 		}
 
 		// All requests in protected requires at least:
-		// - token in Header
-		// - userID in Header
+		// - token in the Header as "Authorization: Bearer <token>"
+		// - one of the permissions given to RequirePermission on the User's Role
 		protected := v1.Group("/protected")
 		protected.Use(middlewares.AuthJWT())
 		{
 			// NOTE:
-			// The :id Param from here and beyond are the ID of each Router group's (user or issue).
+			// The :id Param from here and beyond are the ID of each Router group's (user or issue),
+			// except on the project-scoped issue routes.
+
+			protected.POST("/logout", middlewares.RequirePermission(models.PermProfileEdit), controllers.LogoutHandler)
+			protected.POST("/logout-all", middlewares.RequirePermission(models.PermProfileEdit), controllers.LogoutAllHandler)
+
+			twoFactor := protected.Group("/2fa")
+			{
+				twoFactor.POST("/enroll", middlewares.RequirePermission(models.PermProfileEdit), controllers.EnrollTOTPHandler)
+				// Requires:
+				// - Form with input name as follows:
+				//     - code (from the authenticator app)
+				twoFactor.POST("/confirm", middlewares.RequirePermission(models.PermProfileEdit), controllers.ConfirmTOTPHandler)
+				// Requires:
+				// - Form with input name as follows:
+				//     - code (from the authenticator app)
+				twoFactor.POST("/recovery-codes", middlewares.RequirePermission(models.PermProfileEdit), controllers.RegenerateRecoveryCodesHandler)
+				// Requires:
+				// - Form with input name as follows:
+				//     - password
+				//     - code (from the authenticator app) or recovery_code
+				twoFactor.POST("/disable", middlewares.RequirePermission(models.PermProfileEdit), controllers.DisableTOTPHandler)
+			}
 
 			user := protected.Group("/user")
 			{
 				// Only requires the Param :id from URL.
-				user.PATCH("/:id/change-password", controllers.ChangePasswordHandler)
+				user.PATCH("/:id/change-password", middlewares.RequirePermission(models.PermProfileEdit), server.ChangePasswordHandler)
 				// Only requires the Param :id from URL.
-				user.GET("/:id", controllers.ShowUserHandler)
+				user.GET("/:id", middlewares.RequirePermission(models.PermUserRead), server.ShowUserHandler)
+				// Requires the Param :id from URL.
+				// Takes the same query parameters as /issue/index.
+				user.GET("/:id/assigned", middlewares.RequirePermission(models.PermIssueRead), server.IndexAssignedIssueHandler)
+
+				// Requires:
+				// - Param :id from URL
+				// - Form with input name as follows:
+				//     - role (the Role ID)
+				user.PATCH("/:id/role", middlewares.RequirePermission(models.PermUserManage), server.ChangeUserRoleHandler)
+				// Requires:
+				// - Param :id from URL
+				user.PATCH("/:id/deactivate", middlewares.RequirePermission(models.PermUserManage), server.DeactivateUserHandler)
+				// Requires:
+				// - Param :id from URL
+				user.PATCH("/:id/reactivate", middlewares.RequirePermission(models.PermUserManage), server.ReactivateUserHandler)
+				// Requires:
+				// - Param :id from URL
+				user.PATCH("/:id/force-password-reset", middlewares.RequirePermission(models.PermUserManage), server.ForcePasswordResetHandler)
+				// Requires:
+				// - Param :id from URL
+				user.PATCH("/:id/reset-2fa", middlewares.RequirePermission(models.PermUserManage), server.ResetTwoFactorHandler)
+				// Requires the Param :id from URL.
+				// Optional query parameters:
+				// - page (default 1)
+				// - limit (default 20, max 100)
+				user.GET("/:id/logins", middlewares.RequirePermission(models.PermUserManage), controllers.IndexLoginAttemptHandler)
 			}
 
-			issue := protected.Group("/issue")
+			// Personal access tokens can not manage tokens, profile.edit is never in
+			// their scopes.
+			token := protected.Group("/token")
 			{
-				// Require form data with input name as:
-				// - tite
-				// - description
-				// - severity
-				issue.POST("/create", middlewares.RoleAuth("1"), controllers.CreateIssueHandler)
+				// Requires:
+				// - Form with input name as follows:
+				//     - name
+				//     - scopes (comma separated, for example "issues:read,issues:write")
+				//     - expires_in_days (optional, at most 365)
+				token.POST("/create", middlewares.RequirePermission(models.PermProfileEdit), controllers.CreatePersonalTokenHandler)
+				token.GET("/index", middlewares.RequirePermission(models.PermProfileEdit), controllers.IndexPersonalTokenHandler)
+				// Requires:
+				// - Param :id from URL
+				token.DELETE("/delete/:id", middlewares.RequirePermission(models.PermProfileEdit), controllers.DeletePersonalTokenHandler)
+			}
+
+			users := protected.Group("/users")
+			{
+				// Optional query parameters:
+				// - page (default 1)
+				// - limit (default 20, max 100)
+				// - role (Role ID)
+				// - active (true or false)
+				users.GET("/index", middlewares.RequirePermission(models.PermUserManage), server.IndexUserHandler)
+			}
+
+			invitation := protected.Group("/invitation")
+			{
+				// Requires:
+				// - Form with input name as follows:
+				//     - email
+				//     - role (the Role ID)
+				invitation.POST("/create", middlewares.RequirePermission(models.PermUserInvite), controllers.CreateInvitationHandler)
+				invitation.GET("/index", middlewares.RequirePermission(models.PermUserInvite), controllers.IndexInvitationHandler)
+				// Requires:
+				// - Param :id from URL
+				invitation.DELETE("/delete/:id", middlewares.RequirePermission(models.PermUserInvite), controllers.DeleteInvitationHandler)
+			}
+
+			notification := protected.Group("/notification")
+			{
+				// Optional query parameters:
+				// - page (default 1)
+				// - limit (default 20, max 100)
+				notification.GET("/index", middlewares.RequirePermission(models.PermNotificationRead), controllers.IndexNotificationHandler)
+				notification.GET("/unread-count", middlewares.RequirePermission(models.PermNotificationRead), controllers.UnreadCountNotificationHandler)
+				// Requires:
+				// - Param :id from URL
+				notification.PATCH("/read/:id", middlewares.RequirePermission(models.PermNotificationRead), controllers.ReadNotificationHandler)
+				notification.PATCH("/read-all", middlewares.RequirePermission(models.PermNotificationRead), controllers.ReadAllNotificationHandler)
+			}
+
+			status := protected.Group("/status")
+			{
+				// No other requirement needed.
+				status.GET("/index", middlewares.RequirePermission(models.PermIssueRead), controllers.IndexStatusHandler)
+			}
 
+			severity := protected.Group("/severity")
+			{
 				// No other requirement needed.
-				issue.GET("/index", controllers.IndexIssueHandler)
+				severity.GET("/index", middlewares.RequirePermission(models.PermIssueRead), controllers.IndexSeverityHandler)
+			}
+
+			label := protected.Group("/label")
+			{
+				// Require form data with input name as:
+				// - name
+				// - color (hex color like #D73A4A)
+				// - description (optional)
+				// - project (the Project's Key, optional. Without it the Label is global)
+				label.POST("/create", middlewares.RequirePermission(models.PermLabelManage, models.PermProjectEditOwn, models.PermProjectEditAny), controllers.CreateLabelHandler)
+
+				// Optional query parameters:
+				// - project (the Project's Key)
+				label.GET("/index", middlewares.RequirePermission(models.PermIssueRead), controllers.IndexLabelHandler)
 
 				// Only requires the Param :id from URL.
-				issue.GET("/show/:id", controllers.ShowIssueHandler)
+				label.GET("/show/:id", middlewares.RequirePermission(models.PermIssueRead), controllers.ShowLabelHandler)
 
 				// Requires:
 				// - Param :id from URL
 				// - Form with input name as follows:
-				//     - title
-				//     - description
-				//     - status
-				//     - severity
-				// - userID from Header
-				issue.PATCH("/update/:id", controllers.UpdateIssueHandler)
+				//     - name
+				//     - color
+				//     - description (optional)
+				label.PATCH("/update/:id", middlewares.RequirePermission(models.PermLabelManage, models.PermProjectEditOwn, models.PermProjectEditAny), controllers.UpdateLabelHandler)
 
 				// Requires:
 				// - Param :id from URL
-				// - userID from Header
-				issue.DELETE("/delete/:id", middlewares.RoleAuth("1"), controllers.DeleteIssueHandler)
+				label.DELETE("/delete/:id", middlewares.RequirePermission(models.PermLabelManage, models.PermProjectEditOwn, models.PermProjectEditAny), controllers.DeleteLabelHandler)
+			}
+
+			projects := protected.Group("/projects")
+			{
+				// Require form data with input name as:
+				// - name
+				// - key
+				// - description (optional)
+				projects.POST("/create", middlewares.RequirePermission(models.PermProjectCreate), controllers.CreateProjectHandler)
+
+				// No other requirement needed.
+				projects.GET("/index", middlewares.RequirePermission(models.PermIssueRead), controllers.IndexProjectHandler)
+			}
+
+			// NOTE:
+			// The :key Param is the Project's Key, for example WEB.
+			project := protected.Group("/project/:key")
+			{
+				// Only requires the Param :key from URL.
+				project.GET("/show", middlewares.RequirePermission(models.PermIssueRead), controllers.ShowProjectHandler)
 
 				// Requires:
-				// - Param :id from URL
-				// - userID from Header
+				// - Param :key from URL
 				// - Form with input name as follows:
-				// 	   - description
-				issue.POST("/show/:id/reply", controllers.CreateReplyHandler)
+				//     - name
+				//     - description (optional)
+				project.PATCH("/update", middlewares.RequirePermission(models.PermProjectEditOwn, models.PermProjectEditAny), controllers.UpdateProjectHandler)
+
+				// Requires:
+				// - Param :key from URL
+				project.DELETE("/delete", middlewares.RequirePermission(models.PermProjectDeleteOwn, models.PermProjectDeleteAny), controllers.DeleteProjectHandler)
+
+				// Same routes as /issue, scoped to the Project.
+				// Here the :id Param is the Issue's Number inside the Project,
+				// for example /project/WEB/issue/show/42 is the Issue WEB-42.
+				registerIssueRoutes(project.Group("/issue"), server)
+			}
+
+			role := protected.Group("/role")
+			{
+				// No other requirement needed.
+				role.GET("/index", middlewares.RequirePermission(models.PermRoleManage), controllers.IndexRoleHandler)
+
+				// No other requirement needed.
+				role.GET("/permissions", middlewares.RequirePermission(models.PermRoleManage), controllers.IndexPermissionHandler)
+
+				// Require form data with input name as:
+				// - name
+				// - self_register (true or false, whether it can be chosen on Register)
+				// - permissions (comma separated Permission names)
+				role.POST("/create", middlewares.RequirePermission(models.PermRoleManage), controllers.CreateRoleHandler)
+
+				// Requires:
+				// - Param :id from URL
+				// - Form with the same input names as /role/create
+				role.PATCH("/update/:id", middlewares.RequirePermission(models.PermRoleManage), controllers.UpdateRoleHandler)
+
+				// Only requires the Param :id from URL.
+				role.DELETE("/delete/:id", middlewares.RequirePermission(models.PermRoleManage), controllers.DeleteRoleHandler)
+			}
+
+			webhook := protected.Group("/webhook")
+			{
+				// Require form data with input name as:
+				// - url
+				// - events (comma separated, for example issue.created,reply.created)
+				// - project (the Project's Key, optional. Without it every Project's events are sent)
+				// - secret (optional, generated if empty and only shown in the response)
+				webhook.POST("/create", middlewares.RequirePermission(models.PermWebhookManage), controllers.CreateWebhookHandler)
+
+				webhook.GET("/index", middlewares.RequirePermission(models.PermWebhookManage), controllers.IndexWebhookHandler)
+
+				// Requires:
+				// - Param :id from URL
+				webhook.GET("/show/:id", middlewares.RequirePermission(models.PermWebhookManage), controllers.ShowWebhookHandler)
 
 				// Requires:
 				// - Param :id from URL
-				// - Param :replyId from URL
-				// - userID from Header
 				// - Form with input name as follows:
-				// 	   - description
-				issue.PATCH("/show/:id/update-reply/:replyId", controllers.UpdateReplyHandler)
+				//     - url
+				//     - events
+				//     - active (true or false)
+				webhook.PATCH("/update/:id", middlewares.RequirePermission(models.PermWebhookManage), controllers.UpdateWebhookHandler)
+
+				// Requires:
+				// - Param :id from URL
+				webhook.DELETE("/delete/:id", middlewares.RequirePermission(models.PermWebhookManage), controllers.DeleteWebhookHandler)
 
 				// Requires:
 				// - Param :id from URL
-				// - Param :replyId from URL
-				// - userID from Header
-				issue.DELETE("/show/:id/delete-reply/:replyId", controllers.DeleteReplyHandler)
+				// Optional query parameters:
+				// - page (default 1)
+				// - limit (default 20, max 100)
+				webhook.GET("/show/:id/deliveries", middlewares.RequirePermission(models.PermWebhookManage), controllers.IndexWebhookDeliveryHandler)
+
+				// Requires:
+				// - Param :id and :deliveryId from URL
+				webhook.POST("/show/:id/deliveries/:deliveryId/redeliver", middlewares.RequirePermission(models.PermWebhookManage), controllers.RedeliverWebhookHandler)
 			}
+
+			registerIssueRoutes(protected.Group("/issue"), server)
 		}
 
 	}
 
-	r.Run(":" + port)
+	return r.Run(":" + port)
+}
+
+// registerIssueRoutes registers the Issue and Reply routes on the given group.
+//
+// Used for both the global /issue group and the project-scoped
+// /project/:key/issue group.
+func registerIssueRoutes(issue *gin.RouterGroup, server *controllers.Server) {
+	// Require form data with input name as:
+	// - tite
+	// - description
+	// - severity
+	// - project (the Project's Key, only on the global route)
+	issue.POST("/create", middlewares.RequirePermission(models.PermIssueCreate), server.CreateIssueHandler)
+
+	// Optional query parameters:
+	// - page, limit
+	// - status, severity (comma separated)
+	// - author, updater, assignee (User ID)
+	// - label_any, label_all, label_none (comma separated Label IDs)
+	// - created_from, created_to, updated_from, updated_to
+	// - sort (any IssueIndex column), order (asc or desc)
+	issue.GET("/index", middlewares.RequirePermission(models.PermIssueRead), server.IndexIssueHandler)
+
+	// Requires the query parameter q.
+	// Optional query parameters:
+	// - limit
+	issue.GET("/search", middlewares.RequirePermission(models.PermIssueRead), server.SearchIssueHandler)
+
+	// Only requires the Param :id from URL.
+	issue.GET("/show/:id", middlewares.RequirePermission(models.PermIssueRead), server.ShowIssueHandler)
+
+	// Only requires the Param :id from URL.
+	issue.GET("/show/:id/history", middlewares.RequirePermission(models.PermIssueRead), server.IssueHistoryHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Form with input name as follows:
+	//     - title
+	//     - description
+	//     - severity
+	issue.PATCH("/update/:id", middlewares.RequirePermission(models.PermIssueEditOwn, models.PermIssueEditAny), server.UpdateIssueHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Form with input name as follows:
+	//     - status (the Status ID)
+	issue.POST("/show/:id/transition", middlewares.RequirePermission(models.PermIssueTransition, models.PermIssueTransitionAny), server.TransitionIssueHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Form with input name as follows:
+	//     - user_id (the assigned Developer)
+	issue.POST("/show/:id/assign", middlewares.RequirePermission(models.PermIssueAssignSelf, models.PermIssueAssignAny), server.AssignIssueHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Param :userId from URL
+	issue.DELETE("/show/:id/assign/:userId", middlewares.RequirePermission(models.PermIssueAssignSelf, models.PermIssueAssignAny), server.UnassignIssueHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Form with input name as follows:
+	//     - label_id
+	issue.POST("/show/:id/label", middlewares.RequirePermission(models.PermIssueLabel), controllers.AddIssueLabelHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Param :labelId from URL
+	issue.DELETE("/show/:id/label/:labelId", middlewares.RequirePermission(models.PermIssueLabel), controllers.RemoveIssueLabelHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Multipart form with input name as follows:
+	//     - file (screenshot, log or crash dump, at most ATTACHMENT_MAX_SIZE bytes)
+	//     - reply_id (optional, attaches the file to that Reply)
+	issue.POST("/show/:id/attachment", middlewares.RequirePermission(models.PermAttachmentCreate), controllers.UploadAttachmentHandler)
+
+	// Only requires the Param :id from URL.
+	issue.GET("/show/:id/attachment", middlewares.RequirePermission(models.PermIssueRead), controllers.IndexAttachmentHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Param :attachmentId from URL
+	issue.GET("/show/:id/attachment/:attachmentId", middlewares.RequirePermission(models.PermIssueRead), controllers.DownloadAttachmentHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Param :attachmentId from URL
+	issue.DELETE("/show/:id/attachment/:attachmentId", middlewares.RequirePermission(models.PermAttachmentDelOwn, models.PermAttachmentDelAny), controllers.DeleteAttachmentHandler)
+
+	// Requires:
+	// - Param :id from URL
+	issue.DELETE("/delete/:id", middlewares.RequirePermission(models.PermIssueDeleteOwn, models.PermIssueDeleteAny), server.DeleteIssueHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Form with input name as follows:
+	// 	   - description
+	issue.POST("/show/:id/reply", middlewares.RequirePermission(models.PermReplyCreate), server.CreateReplyHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Param :replyId from URL
+	// - Form with input name as follows:
+	// 	   - description
+	issue.PATCH("/show/:id/update-reply/:replyId", middlewares.RequirePermission(models.PermReplyEditOwn, models.PermReplyEditAny), server.UpdateReplyHandler)
+
+	// Requires:
+	// - Param :id from URL
+	// - Param :replyId from URL
+	issue.DELETE("/show/:id/delete-reply/:replyId", middlewares.RequirePermission(models.PermReplyDeleteOwn, models.PermReplyDeleteAny), server.DeleteReplyHandler)
 }
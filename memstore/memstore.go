@@ -0,0 +1,917 @@
+// Package memstore keeps Issues, Users, Replies, Projects and assignments in
+// memory, for tests and for running the handlers without a database.
+//
+// Only the records themselves are kept. Unlike the GORM store, changes do not
+// record the Issue's history or notify its watchers, and the Issue's Status,
+// the User's Role and other associations are kept as they were given. The
+// workflow, the Roles and their Permissions are the built-in defaults of
+// package models. Issues have no Labels or Attachments, and Search matches
+// substrings instead of words.
+package memstore
+
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var _ models.Store = (*Store)(nil)
+
+// Store is a models.Store in memory. The zero value is not usable, see New.
+type Store struct {
+	mu sync.Mutex
+	// txMu serializes transactions, which restore the data on an error.
+	txMu sync.Mutex
+	data *data
+}
+
+// data is everything the Store keeps, copied to roll back a transaction.
+type data struct {
+	lastID    uint
+	counters  map[uint]int
+	issues    map[uint]models.Issue
+	users     map[uint]models.User
+	replies   map[uint]models.Reply
+	projects  map[uint]models.Project
+	assignees map[assignment]bool
+}
+
+// assignment is a User assigned to an Issue.
+type assignment struct {
+	issueID uint
+	userID  uint
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{
+		data: &data{
+			counters:  map[uint]int{},
+			issues:    map[uint]models.Issue{},
+			users:     map[uint]models.User{},
+			replies:   map[uint]models.Reply{},
+			projects:  map[uint]models.Project{},
+			assignees: map[assignment]bool{},
+		},
+	}
+}
+
+func (d *data) copy() *data {
+	c := &data{
+		lastID:    d.lastID,
+		counters:  make(map[uint]int, len(d.counters)),
+		issues:    make(map[uint]models.Issue, len(d.issues)),
+		users:     make(map[uint]models.User, len(d.users)),
+		replies:   make(map[uint]models.Reply, len(d.replies)),
+		projects:  make(map[uint]models.Project, len(d.projects)),
+		assignees: make(map[assignment]bool, len(d.assignees)),
+	}
+	for k, v := range d.counters {
+		c.counters[k] = v
+	}
+	for k, v := range d.issues {
+		c.issues[k] = v
+	}
+	for k, v := range d.users {
+		c.users[k] = v
+	}
+	for k, v := range d.replies {
+		c.replies[k] = v
+	}
+	for k, v := range d.projects {
+		c.projects[k] = v
+	}
+	for k, v := range d.assignees {
+		c.assignees[k] = v
+	}
+	return c
+}
+
+// newModel returns the gorm.Model of a new record. IDs are unique across
+// every kind of record.
+func (s *Store) newModel() gorm.Model {
+	s.data.lastID++
+	now := time.Now()
+	return gorm.Model{ID: s.data.lastID, CreatedAt: now, UpdatedAt: now}
+}
+
+func (s *Store) Issues() models.IssueRepository {
+	return issueRepository{s}
+}
+
+func (s *Store) Users() models.UserRepository {
+	return userRepository{s}
+}
+
+func (s *Store) Replies() models.ReplyRepository {
+	return replyRepository{s}
+}
+
+func (s *Store) Projects() models.ProjectRepository {
+	return projectRepository{s}
+}
+
+func (s *Store) Statuses() models.StatusRepository {
+	return statusRepository{}
+}
+
+// Transaction runs fn and restores the data as it was before when fn returns
+// an error. Transactions run one at a time, but changes made outside of them
+// meanwhile are rolled back too.
+func (s *Store) Transaction(fn func(models.Store) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	snapshot := s.data.copy()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+type issueRepository struct {
+	s *Store
+}
+
+func (r issueRepository) Create(issue *models.Issue, project *models.Project) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.data.counters[project.ID]++
+	number := r.s.data.counters[project.ID]
+
+	issue.Model = r.s.newModel()
+	issue.ProjectID = project.ID
+	issue.Number = number
+	issue.Key = fmt.Sprintf("%s-%d", project.Key, number)
+	r.s.data.issues[issue.ID] = *issue
+	return nil
+}
+
+func (r issueRepository) FindByID(id uint) (*models.Issue, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	issue, ok := r.s.data.issues[id]
+	if !ok {
+		return nil, fmt.Errorf("ERROR: Could not find issue with ID: %d", id)
+	}
+	return &issue, nil
+}
+
+func (r issueRepository) FindByProjectNumber(projectID uint, number int) (*models.Issue, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, issue := range r.s.data.issues {
+		if issue.ProjectID == projectID && issue.Number == number {
+			return &issue, nil
+		}
+	}
+	return nil, fmt.Errorf("ERROR: Could not find issue with number: %d", number)
+}
+
+// Index applies every filter of models.IssueFilter. Since Issues have no
+// Labels here, LabelAny and LabelAll match nothing and LabelNone matches
+// everything.
+func (r issueRepository) Index(filter models.IssueFilter) (*[]models.IssueIndex, int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var matched []models.IssueIndex
+	for _, issue := range r.s.data.issues {
+		if !r.matches(&issue, &filter) {
+			continue
+		}
+		matched = append(matched, models.IssueIndex{
+			ID:                int(issue.ID),
+			ProjectID:         int(issue.ProjectID),
+			Key:               issue.Key,
+			Title:             issue.Title,
+			StatusID:          int(issue.StatusID),
+			StatusName:        statusName(&issue),
+			Severity:          issue.Severity,
+			CreatedAt:         issue.CreatedAt,
+			UpdatedAt:         issue.UpdatedAt,
+			UserID:            issue.UserID,
+			UserName:          r.s.data.users[uint(issue.UserID)].Name,
+			UpdatedByUserID:   issue.UpdatedByUserID,
+			UpdatedByUserName: issue.UpdatedByUserName,
+			Labels:            []models.IssueLabel{},
+		})
+	}
+	sortIssueIndex(matched, filter.Sort, filter.Desc)
+
+	total := int64(len(matched))
+	page := []models.IssueIndex{}
+	if start := (filter.Page - 1) * filter.Limit; start < len(matched) {
+		end := start + filter.Limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		page = matched[start:end]
+	}
+	return &page, total, nil
+}
+
+// matches checks the Issue against the filter. Must be called with the lock
+// held.
+func (r issueRepository) matches(issue *models.Issue, f *models.IssueFilter) bool {
+	switch {
+	case f.ProjectID != 0 && issue.ProjectID != f.ProjectID,
+		len(f.Status) > 0 && !containsInt(f.Status, int(issue.StatusID)),
+		len(f.Severity) > 0 && !containsString(f.Severity, issue.Severity),
+		f.AuthorID != 0 && issue.UserID != f.AuthorID,
+		f.UpdaterID != 0 && issue.UpdatedByUserID != f.UpdaterID,
+		f.AssigneeID != 0 && !r.s.data.assignees[assignment{issue.ID, uint(f.AssigneeID)}],
+		len(f.LabelAny) > 0 || len(f.LabelAll) > 0,
+		f.CreatedFrom != nil && issue.CreatedAt.Before(*f.CreatedFrom),
+		f.CreatedTo != nil && !issue.CreatedAt.Before(*f.CreatedTo),
+		f.UpdatedFrom != nil && issue.UpdatedAt.Before(*f.UpdatedFrom),
+		f.UpdatedTo != nil && !issue.UpdatedAt.Before(*f.UpdatedTo):
+		return false
+	}
+	return true
+}
+
+// sortIssueIndex sorts like IssueFilter's ORDER BY, by one of the
+// models.IssueSortColumns and then by ID.
+func sortIssueIndex(issues []models.IssueIndex, column string, desc bool) {
+	if _, ok := models.IssueSortColumns[column]; !ok {
+		column = "id"
+	}
+	compare := func(a, b *models.IssueIndex) int {
+		switch column {
+		case "project_id":
+			return compareInts(a.ProjectID, b.ProjectID)
+		case "key":
+			// The Numbers of a Project's Issues follow their IDs.
+			if result := compareInts(a.ProjectID, b.ProjectID); result != 0 {
+				return result
+			}
+			return compareInts(a.ID, b.ID)
+		case "title":
+			return strings.Compare(a.Title, b.Title)
+		case "status_id":
+			return compareInts(a.StatusID, b.StatusID)
+		case "status_name":
+			return strings.Compare(a.StatusName, b.StatusName)
+		case "severity":
+			return strings.Compare(a.Severity, b.Severity)
+		case "created_at":
+			return compareTimes(a.CreatedAt, b.CreatedAt)
+		case "updated_at":
+			return compareTimes(a.UpdatedAt, b.UpdatedAt)
+		case "user_id":
+			return compareInts(a.UserID, b.UserID)
+		case "user_name":
+			return strings.Compare(a.UserName, b.UserName)
+		case "updated_by_user_id":
+			return compareInts(a.UpdatedByUserID, b.UpdatedByUserID)
+		case "updated_by_user_name":
+			return strings.Compare(a.UpdatedByUserName, b.UpdatedByUserName)
+		}
+		return 0
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := &issues[i], &issues[j]
+		result := compare(a, b)
+		if result == 0 {
+			if column != "id" {
+				// Ties are ordered by ascending ID whatever the direction.
+				return a.ID < b.ID
+			}
+			result = compareInts(a.ID, b.ID)
+		}
+		if desc {
+			return result > 0
+		}
+		return result < 0
+	})
+}
+
+// Search matches the query as a case-insensitive substring of the title and
+// body of the Issues and the body of their Replies. Every match has the same
+// rank, so the Issues are returned by ID.
+func (r issueRepository) Search(q string, projectID uint, limit int) (*[]models.IssueSearchResult, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	q = strings.ToLower(strings.TrimSpace(q))
+	results := []models.IssueSearchResult{}
+	if q == "" {
+		return &results, nil
+	}
+	contains := func(text string) bool {
+		return strings.Contains(strings.ToLower(text), q)
+	}
+
+	for _, issue := range r.s.data.issues {
+		if projectID != 0 && issue.ProjectID != projectID {
+			continue
+		}
+		result := models.IssueSearchResult{
+			IssueID:   int(issue.ID),
+			ProjectID: int(issue.ProjectID),
+			Key:       issue.Key,
+			Title:     issue.Title,
+			Rank:      1,
+		}
+		if contains(issue.Title) || contains(issue.Body) {
+			result.MatchedIn = "issue"
+			result.Snippet = issue.Body
+		} else {
+			for _, reply := range r.replies(issue.ID) {
+				if contains(reply.Body) {
+					result.MatchedIn = "reply"
+					result.ReplyID = int(reply.ID)
+					result.Snippet = reply.Body
+					break
+				}
+			}
+		}
+		if result.MatchedIn != "" {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(a, b int) bool { return results[a].IssueID < results[b].IssueID })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return &results, nil
+}
+
+func (r issueRepository) Show(issue *models.Issue) (*models.IssueShow, *[]models.RepliesInIssue, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	source, ok := r.s.data.issues[issue.ID]
+	if !ok {
+		return nil, nil, fmt.Errorf("ERROR: could not find issue with ID: %d", issue.ID)
+	}
+
+	show := models.IssueShow{
+		ID:         int(source.ID),
+		ProjectID:  int(source.ProjectID),
+		Key:        source.Key,
+		Title:      source.Title,
+		Body:       source.Body,
+		StatusID:   int(source.StatusID),
+		StatusName: statusName(&source),
+		Severity:   source.Severity,
+		CreatedAt:  source.CreatedAt,
+		UpdatedAt:  source.UpdatedAt,
+		UserID:     source.UserID,
+		UserName:   r.s.data.users[uint(source.UserID)].Name,
+	}
+
+	replies := []models.RepliesInIssue{}
+	for _, reply := range r.replies(source.ID) {
+		replies = append(replies, models.RepliesInIssue{
+			ID:        int(reply.ID),
+			UserID:    int(reply.UserID),
+			IssueID:   int(reply.IssueID),
+			Body:      reply.Body,
+			Replier:   r.s.data.users[reply.UserID].Name,
+			CreatedAt: reply.CreatedAt,
+			UpdatedAt: reply.UpdatedAt,
+		})
+	}
+	return &show, &replies, nil
+}
+
+// replies returns the Replies of the Issue, oldest first. Must be called with
+// the lock held.
+func (r issueRepository) replies(issueID uint) []models.Reply {
+	var replies []models.Reply
+	for _, reply := range r.s.data.replies {
+		if reply.IssueID == issueID {
+			replies = append(replies, reply)
+		}
+	}
+	sort.Slice(replies, func(a, b int) bool { return replies[a].ID < replies[b].ID })
+	return replies
+}
+
+// History is always empty, since changes are not recorded.
+func (r issueRepository) History(issue *models.Issue) (*[]models.IssueEvent, error) {
+	return &[]models.IssueEvent{}, nil
+}
+
+func (r issueRepository) Update(origin *models.Issue, changes *models.Issue) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	issue, ok := r.s.data.issues[origin.ID]
+	if !ok {
+		return fmt.Errorf("ERROR: Could not find issue with ID: %d", origin.ID)
+	}
+
+	if changes.Title != "" {
+		issue.Title = changes.Title
+	}
+	if changes.Body != "" {
+		issue.Body = changes.Body
+	}
+	if changes.Severity != "" {
+		issue.Severity = changes.Severity
+	}
+	if changes.StatusID != 0 {
+		issue.StatusID = changes.StatusID
+	}
+	if changes.UpdatedByUserID != 0 {
+		issue.UpdatedByUserID = changes.UpdatedByUserID
+		issue.UpdatedByUserName = changes.UpdatedByUserName
+	}
+	issue.UpdatedAt = time.Now()
+
+	r.s.data.issues[issue.ID] = issue
+	*origin = issue
+	return nil
+}
+
+func (r issueRepository) Transition(issue *models.Issue, status *models.Status, by *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	source, ok := r.s.data.issues[issue.ID]
+	if !ok {
+		return fmt.Errorf("ERROR: Could not find issue with ID: %d", issue.ID)
+	}
+	source.StatusID = status.ID
+	source.Status = *status
+	source.UpdatedByUserID = int(by.ID)
+	source.UpdatedByUserName = by.Name
+	source.UpdatedAt = time.Now()
+
+	r.s.data.issues[source.ID] = source
+	*issue = source
+	return nil
+}
+
+func (r issueRepository) Assignees(issue *models.Issue) (*[]models.IssueAssignee, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	assignees := []models.IssueAssignee{}
+	for a := range r.s.data.assignees {
+		if a.issueID != issue.ID {
+			continue
+		}
+		user := r.s.data.users[a.userID]
+		assignees = append(assignees, models.IssueAssignee{
+			ID:     int(user.ID),
+			Name:   user.Name,
+			Email:  user.Email,
+			RoleID: user.RoleID,
+		})
+	}
+	sort.Slice(assignees, func(a, b int) bool { return assignees[a].Name < assignees[b].Name })
+	return &assignees, nil
+}
+
+func (r issueRepository) IsAssigned(issue *models.Issue, userID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.data.assignees[assignment{issue.ID, userID}], nil
+}
+
+func (r issueRepository) Assign(issue *models.Issue, user *models.User, by *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.data.issues[issue.ID]; !ok {
+		return fmt.Errorf("ERROR: Could not find issue with ID: %d", issue.ID)
+	}
+	r.s.data.assignees[assignment{issue.ID, user.ID}] = true
+	return nil
+}
+
+func (r issueRepository) Unassign(issue *models.Issue, user *models.User, by *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.data.assignees, assignment{issue.ID, user.ID})
+	return nil
+}
+
+// Labels is always empty, see the package doc.
+func (r issueRepository) Labels(issue *models.Issue) ([]models.IssueLabel, error) {
+	return []models.IssueLabel{}, nil
+}
+
+// Attachments is always empty, see the package doc.
+func (r issueRepository) Attachments(issue *models.Issue) (*[]models.Attachment, error) {
+	return &[]models.Attachment{}, nil
+}
+
+func (r issueRepository) Delete(issue *models.Issue) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.data.issues, issue.ID)
+	for a := range r.s.data.assignees {
+		if a.issueID == issue.ID {
+			delete(r.s.data.assignees, a)
+		}
+	}
+	return nil
+}
+
+type userRepository struct {
+	s *Store
+}
+
+func (r userRepository) Create(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.data.users {
+		if existing.Email == user.Email {
+			return fmt.Errorf("ERROR EMAIL: %s is already registered", user.Email)
+		}
+	}
+
+	user.Model = r.s.newModel()
+	r.s.data.users[user.ID] = *user
+	return nil
+}
+
+func (r userRepository) FindByID(id uint) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.data.users[id]
+	if !ok {
+		return nil, fmt.Errorf("ERROR: could not find user with ID: %d", id)
+	}
+	return &user, nil
+}
+
+func (r userRepository) FindByEmail(email string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, user := range r.s.data.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("ERROR: could not find user with email: %s", email)
+}
+
+func (r userRepository) UpdatePassword(user *models.User, password []byte) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	source, ok := r.s.data.users[user.ID]
+	if !ok {
+		return fmt.Errorf("ERROR: could not find user with ID: %d", user.ID)
+	}
+	source.Password = password
+	source.MustResetPassword = false
+	source.UpdatedAt = time.Now()
+	r.s.data.users[user.ID] = source
+
+	user.Password = password
+	user.MustResetPassword = false
+	return nil
+}
+
+func (r userRepository) Index(filter models.UserFilter) (*[]models.UserIndex, int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var matched []models.UserIndex
+	for _, user := range r.s.data.users {
+		if filter.RoleID != 0 && user.RoleID != filter.RoleID {
+			continue
+		}
+		if filter.Active != nil && *filter.Active != (user.DeactivatedAt == nil) {
+			continue
+		}
+		role, _ := findRole(user.RoleID)
+		matched = append(matched, models.UserIndex{
+			ID:                user.ID,
+			Name:              user.Name,
+			Email:             user.Email,
+			RoleID:            user.RoleID,
+			RoleName:          role.Name,
+			CreatedAt:         user.CreatedAt,
+			DeactivatedAt:     user.DeactivatedAt,
+			MustResetPassword: user.MustResetPassword,
+			EmailVerifiedAt:   user.EmailVerifiedAt,
+			TOTPEnabledAt:     user.TOTPEnabledAt,
+		})
+	}
+	sort.Slice(matched, func(a, b int) bool { return matched[a].ID < matched[b].ID })
+
+	total := int64(len(matched))
+	page := []models.UserIndex{}
+	if start := (filter.Page - 1) * filter.Limit; start < len(matched) {
+		end := start + filter.Limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		page = matched[start:end]
+	}
+	return &page, total, nil
+}
+
+// Permissions are the models.DefaultRolePermissions of the User's Role, with
+// the same restrictions as Permission.FindUserPermissions.
+func (r userRepository) Permissions(user *models.User) (models.PermissionSet, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	permissions := models.PermissionSet{}
+	source, ok := r.s.data.users[user.ID]
+	if !ok || source.DeactivatedAt != nil {
+		return permissions, nil
+	}
+	for _, name := range models.DefaultRolePermissions[uint(source.RoleID)] {
+		if !source.MustResetPassword || name == models.PermProfileEdit {
+			permissions[name] = true
+		}
+	}
+	return permissions, nil
+}
+
+// ChangeRole only takes the models.DefaultRoles.
+func (r userRepository) ChangeRole(user *models.User, roleID int) error {
+	role, ok := findRole(roleID)
+	if !ok {
+		return fmt.Errorf("ERROR ROLE: could not find role with ID: %d", roleID)
+	}
+
+	err := r.update(user, func(source *models.User) {
+		source.RoleID = roleID
+		source.Role = role
+	})
+	return err
+}
+
+func (r userRepository) Deactivate(user *models.User) error {
+	if user.DeactivatedAt != nil {
+		return errors.New("ERROR: user is already deactivated")
+	}
+
+	now := time.Now()
+	err := r.update(user, func(source *models.User) {
+		source.DeactivatedAt = &now
+		source.TokensRevokedAt = &now
+	})
+	return err
+}
+
+func (r userRepository) Reactivate(user *models.User) error {
+	if user.DeactivatedAt == nil {
+		return errors.New("ERROR: user is not deactivated")
+	}
+
+	err := r.update(user, func(source *models.User) {
+		source.DeactivatedAt = nil
+	})
+	return err
+}
+
+func (r userRepository) SetPassword(user *models.User, password []byte, temporary bool) error {
+	now := time.Now()
+	err := r.update(user, func(source *models.User) {
+		source.Password = password
+		source.MustResetPassword = temporary
+		source.TokensRevokedAt = &now
+	})
+	return err
+}
+
+func (r userRepository) LogoutEverywhere(user *models.User) error {
+	now := time.Now()
+	err := r.update(user, func(source *models.User) {
+		source.TokensRevokedAt = &now
+	})
+	return err
+}
+
+func (r userRepository) ForcePasswordReset(user *models.User) error {
+	now := time.Now()
+	err := r.update(user, func(source *models.User) {
+		source.MustResetPassword = true
+		source.TokensRevokedAt = &now
+	})
+	return err
+}
+
+func (r userRepository) ResetTwoFactor(user *models.User) error {
+	now := time.Now()
+	err := r.update(user, func(source *models.User) {
+		source.TOTPSecret = ""
+		source.TOTPEnabledAt = nil
+		source.TOTPLastStep = 0
+		source.TokensRevokedAt = &now
+	})
+	return err
+}
+
+// update applies change to the stored User and copies the result to user.
+func (r userRepository) update(user *models.User, change func(source *models.User)) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	source, ok := r.s.data.users[user.ID]
+	if !ok {
+		return fmt.Errorf("ERROR: could not find user with ID: %d", user.ID)
+	}
+	change(&source)
+	source.UpdatedAt = time.Now()
+
+	r.s.data.users[source.ID] = source
+	*user = source
+	return nil
+}
+
+type replyRepository struct {
+	s *Store
+}
+
+func (r replyRepository) Create(reply *models.Reply, issue *models.Issue, by *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.data.issues[issue.ID]; !ok {
+		return fmt.Errorf("ERROR: Could not find issue with ID: %d", issue.ID)
+	}
+
+	reply.Model = r.s.newModel()
+	r.s.data.replies[reply.ID] = *reply
+	return nil
+}
+
+func (r replyRepository) FindByID(id uint) (*models.Reply, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	reply, ok := r.s.data.replies[id]
+	if !ok {
+		return nil, fmt.Errorf("ERROR: could not find reply with ID: %d", id)
+	}
+	return &reply, nil
+}
+
+func (r replyRepository) Update(source *models.Reply, changes *models.Reply) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	reply, ok := r.s.data.replies[source.ID]
+	if !ok {
+		return fmt.Errorf("ERROR: could not find reply with ID: %d", source.ID)
+	}
+	if changes.Body != "" {
+		reply.Body = changes.Body
+	}
+	reply.UpdatedAt = time.Now()
+
+	r.s.data.replies[reply.ID] = reply
+	*source = reply
+	return nil
+}
+
+func (r replyRepository) Delete(reply *models.Reply) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.data.replies, reply.ID)
+	return nil
+}
+
+type projectRepository struct {
+	s *Store
+}
+
+// Create keeps the Project's Key as it was given. Projects are never deleted
+// here.
+func (r projectRepository) Create(project *models.Project) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.data.projects {
+		if existing.Key == project.Key {
+			return fmt.Errorf("ERROR KEY: key %s is already used by another project", project.Key)
+		}
+	}
+
+	project.Model = r.s.newModel()
+	r.s.data.projects[project.ID] = *project
+	return nil
+}
+
+func (r projectRepository) FindByKey(key string) (*models.Project, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, project := range r.s.data.projects {
+		if strings.EqualFold(project.Key, key) {
+			return &project, nil
+		}
+	}
+	return nil, fmt.Errorf("ERROR: could not find project with key: %s", key)
+}
+
+// statusRepository reads the models.DefaultStatuses and
+// models.DefaultTransitions.
+type statusRepository struct{}
+
+func (statusRepository) FindByID(id uint) (*models.Status, error) {
+	for _, status := range models.DefaultStatuses {
+		if status.ID == id {
+			return &status, nil
+		}
+	}
+	return nil, fmt.Errorf("ERROR: could not find status with ID: %d", id)
+}
+
+func (statusRepository) IsTransitionAllowed(from uint, to uint, roleID int) (bool, error) {
+	for _, transition := range models.DefaultTransitions {
+		if transition.FromStatusID == from && transition.ToStatusID == to && transition.RoleID == roleID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// statusName is the name of the Issue's Status, which is looked up in the
+// models.DefaultStatuses if it was not given.
+func statusName(issue *models.Issue) string {
+	if issue.Status.Name != "" {
+		return issue.Status.Name
+	}
+	for _, status := range models.DefaultStatuses {
+		if status.ID == issue.StatusID {
+			return status.Name
+		}
+	}
+	return ""
+}
+
+// findRole looks up one of the models.DefaultRoles.
+func findRole(id int) (models.Role, bool) {
+	for _, role := range models.DefaultRoles {
+		if int(role.ID) == id {
+			return role, true
+		}
+	}
+	return models.Role{}, false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTimes(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
@@ -2,54 +2,63 @@ package middlewares
 
 import (
 	"issue-tracker/auth"
+	"issue-tracker/models"
 	"net/http"
-	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// userKey is the gin context key of the authenticated *models.User.
+const userKey = "user"
+
+// scopesKey is the gin context key of the scopes of the personal access token
+// the request was authenticated with.
+const scopesKey = "tokenScopes"
+
+// ExtractToken gets the token from the "Authorization: Bearer <token>" Header.
+// The "token" Header older clients send is still accepted.
+func ExtractToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(parts[1])
+	}
+	return c.GetHeader("token")
+}
+
 // AuthJWT is a middleware for protected APIs. Checks whether the User who's
 // trying to use an API is authenticated or not.
+//
+// Personal access tokens are accepted as well as the access tokens of a login
+// session. The User the token was issued to is kept in the context, see
+// CurrentUser.
 func AuthJWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get the 'authorization' from the Header.
-		clientToken := c.Request.Header.Get("token")
+		clientToken := ExtractToken(c)
 		if clientToken == "" {
-			c.JSON(http.StatusForbidden, gin.H{
+			c.Header("WWW-Authenticate", "Bearer")
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "No token in header",
 			})
 			c.Abort()
 			return
 		}
 
-		userID := c.GetHeader("userID")
-		if userID == "" {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "No userID in header.",
-			})
-			c.Abort()
+		if auth.IsPersonalToken(clientToken) {
+			authPersonalToken(c, clientToken)
 			return
 		}
-		// // Splits the Bearer and the token. (Used if the token has "Bearer " in front)
-		// extractedToken := strings.Split(clientToken, "Bearer ")
-		// log.Println(extractedToken)
-		// if len(extractedToken) == 2 {
-		// 	clientToken = strings.TrimSpace(extractedToken[1])
-		// } else {
-		// 	c.JSON(http.StatusBadRequest, gin.H{
-		// 		"error": "Incorrect format of Authorization token",
-		// 	})
-		// 	c.Abort()
-		// 	return
-		// }
 
 		// Check whether the Token is valid.
 		jwtWrapper := auth.JwtWrapper{
-			SecretKey: os.Getenv("JWT_SECRET"),
-			Issuer:    "AuthService",
+			Keys:   auth.DefaultKeys,
+			Issuer: "AuthService",
 		}
 
-		claims, err := jwtWrapper.ValidateToken(clientToken, userID)
+		claims, user, err := jwtWrapper.ValidateToken(clientToken)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": err.Error(),
@@ -58,7 +67,73 @@ func AuthJWT() gin.HandlerFunc {
 			return
 		}
 
+		if user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "E-mail is not verified. Please verify your e-mail.",
+			})
+			c.Abort()
+			return
+		}
+
+		// The access token is only accepted while its session is not logged out.
+		var refreshToken models.RefreshToken
+		active, err := refreshToken.SessionActive(claims.SessionID)
+		if err != nil || !active {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Session has ended. Please log in again.",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(userKey, user)
 		c.Set("email", claims.Email)
+		c.Set("sessionID", claims.SessionID)
 		c.Next()
 	}
 }
+
+// authPersonalToken authenticates the request with a personal access token.
+// The token's scopes are kept in the context, and RequirePermission only
+// grants the Permissions they allow.
+func authPersonalToken(c *gin.Context, clientToken string) {
+	token, user, err := auth.ValidatePersonalToken(clientToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+		})
+		c.Abort()
+		return
+	}
+
+	if user.EmailVerifiedAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "E-mail is not verified. Please verify your e-mail.",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Set(userKey, user)
+	c.Set("email", user.Email)
+	c.Set(scopesKey, token.ScopeList())
+	c.Next()
+}
+
+// TokenScopes returns the scopes of the personal access token the request was
+// authenticated with, or nil for a login session.
+func TokenScopes(c *gin.Context) []string {
+	if scopes, ok := c.Get(scopesKey); ok {
+		return scopes.([]string)
+	}
+	return nil
+}
+
+// CurrentUser returns the User authenticated by AuthJWT, or nil on a route
+// without AuthJWT.
+func CurrentUser(c *gin.Context) *models.User {
+	if user, ok := c.Get(userKey); ok {
+		return user.(*models.User)
+	}
+	return nil
+}
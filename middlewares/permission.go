@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"issue-tracker/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// permissionsKey is the gin context key of the User's models.PermissionSet.
+const permissionsKey = "permissions"
+
+// RequirePermission is the middleware to check whether the User's Role has at
+// least one of the permissions. Routes which allow an action on the User's own
+// data or on everything (like issue.delete.own and issue.delete.any) take both,
+// and the handler checks which one applies.
+//
+// A request with a personal access token only has the permissions its scopes
+// allow. The User's permissions are kept in the context, see Permissions.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var permission models.Permission
+		user := CurrentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not authenticated."})
+			c.Abort()
+			return
+		}
+
+		granted, err := permission.FindUserPermissions(int(user.ID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		if scopes := TokenScopes(c); scopes != nil {
+			granted = granted.Restrict(models.ScopePermissions(scopes))
+		}
+
+		if !granted.Has(permissions...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User is unauthorized to use this request."})
+			c.Abort()
+			return
+		}
+
+		c.Set(permissionsKey, granted)
+		c.Next()
+	}
+}
+
+// Permissions returns the User's permissions loaded by RequirePermission.
+// Without RequirePermission on the route the set is empty.
+func Permissions(c *gin.Context) models.PermissionSet {
+	if granted, ok := c.Get(permissionsKey); ok {
+		return granted.(models.PermissionSet)
+	}
+	return models.PermissionSet{}
+}
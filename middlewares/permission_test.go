@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"issue-tracker/auth"
+	"issue-tracker/database"
+	"issue-tracker/migrations"
+	"issue-tracker/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/logger"
+)
+
+// setupSQLite points database.DB at a new migrated in-memory SQLite database.
+func setupSQLite(t *testing.T) {
+	db, err := database.Open(database.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	database.DB = db
+	if err := migrations.MigrateTables(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createToken saves a verified User with the Role and a personal access token
+// of theirs with the scopes.
+func createToken(t *testing.T, roleID int, scopes string) (string, *models.User, *models.PersonalAccessToken) {
+	now := time.Now()
+	user := models.User{Name: "user", Email: "user@email.com", RoleID: roleID, EmailVerifiedAt: &now}
+	if err := user.SaveUserData(); err != nil {
+		t.Fatal(err)
+	}
+
+	token, prefix, err := auth.NewPersonalToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	personalToken := models.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      "ci",
+		Scopes:    scopes,
+		Prefix:    prefix,
+		TokenHash: auth.HashToken(token),
+	}
+	if err := personalToken.SavePersonalAccessToken(); err != nil {
+		t.Fatal(err)
+	}
+	return token, &user, &personalToken
+}
+
+// requestWithToken calls a route behind AuthJWT and RequirePermission with the
+// token.
+func requestWithToken(token string, permission string) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/protected", AuthJWT(), RequirePermission(permission), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestPersonalTokenScopes(t *testing.T) {
+	setupSQLite(t)
+	token, _, _ := createToken(t, models.RoleQA, "issues:read")
+
+	assert.Equal(t, http.StatusOK, requestWithToken(token, models.PermIssueRead))
+	assert.Equal(t, http.StatusForbidden, requestWithToken(token, models.PermIssueCreate),
+		"the Role allows it but the scope does not")
+}
+
+func TestPersonalTokenNeverManagesAccounts(t *testing.T) {
+	setupSQLite(t)
+	token, _, _ := createToken(t, models.RoleAdmin, strings.Join(models.TokenScopeNames(), ","))
+
+	assert.Equal(t, http.StatusOK, requestWithToken(token, models.PermIssueDeleteAny))
+	for _, permission := range []string{models.PermProfileEdit, models.PermUserManage, models.PermRoleManage} {
+		assert.Equal(t, http.StatusForbidden, requestWithToken(token, permission), permission)
+		assert.False(t, models.ScopePermissions(models.TokenScopeNames()).Has(permission), permission)
+	}
+}
+
+func TestPersonalTokenRevoked(t *testing.T) {
+	setupSQLite(t)
+	token, user, personalToken := createToken(t, models.RoleQA, "issues:read")
+	assert.Equal(t, http.StatusOK, requestWithToken(token, models.PermIssueRead))
+
+	assert.NoError(t, personalToken.DeletePersonalAccessToken())
+	assert.Equal(t, http.StatusUnauthorized, requestWithToken(token, models.PermIssueRead), "deleted")
+
+	assert.NoError(t, database.DB.Unscoped().Model(personalToken).Update("deleted_at", nil).Error)
+	assert.Equal(t, http.StatusOK, requestWithToken(token, models.PermIssueRead))
+
+	revokedAt := personalToken.CreatedAt.Add(time.Second)
+	assert.NoError(t, database.DB.Model(user).Update("tokens_revoked_at", revokedAt).Error)
+	assert.Equal(t, http.StatusOK, requestWithToken(token, models.PermIssueRead),
+		"logging out everywhere keeps personal access tokens")
+
+	assert.NoError(t, database.DB.Model(user).Update("deactivated_at", revokedAt).Error)
+	assert.Equal(t, http.StatusUnauthorized, requestWithToken(token, models.PermIssueRead), "deactivated")
+}
+
+func TestPersonalTokenExpired(t *testing.T) {
+	setupSQLite(t)
+	token, _, personalToken := createToken(t, models.RoleQA, "issues:read")
+
+	assert.NoError(t, database.DB.Model(personalToken).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	assert.Equal(t, http.StatusUnauthorized, requestWithToken(token, models.PermIssueRead))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"issue-tracker/migrations"
+	"issue-tracker/seeds"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `Usage:
+  migrate [-dry-run] up        apply the pending migrations
+  migrate [-dry-run] down N    roll back the last N applied migrations
+  migrate status               list the migrations and when they were applied`
+
+// runMigrate runs the migrate command with its arguments on the database.
+//
+// With -dry-run, the migrations run in a transaction which is rolled back and
+// the statements they would run are written to out.
+func runMigrate(db *gorm.DB, args []string, out io.Writer) error {
+	flags := newFlagSet("migrate", migrateUsage, out)
+	dryRun := flags.Bool("dry-run", false, "print the statements without applying them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	migrator := migrations.NewMigrator(db)
+	migrator.DryRun = *dryRun
+	migrator.Out = out
+
+	verb := "Applied"
+	if *dryRun {
+		verb = "Would apply"
+	}
+
+	switch {
+	case flags.NArg() == 1 && flags.Arg(0) == "up":
+		applied, err := migrator.Up()
+		printMigrations(out, verb, applied)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "The database is up to date.")
+		}
+		if *dryRun {
+			return nil
+		}
+		return seeds.Builtins(db)
+
+	case flags.NArg() == 2 && flags.Arg(0) == "down":
+		n, err := strconv.Atoi(flags.Arg(1))
+		if err != nil {
+			return fmt.Errorf("ERROR MIGRATION: N must be a number, got %q", flags.Arg(1))
+		}
+		if *dryRun {
+			verb = "Would roll back"
+		} else {
+			verb = "Rolled back"
+		}
+		rolledBack, err := migrator.Down(n)
+		printMigrations(out, verb, rolledBack)
+		return err
+
+	case flags.NArg() == 1 && flags.Arg(0) == "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		flags.Usage()
+		return fmt.Errorf("ERROR MIGRATION: unknown arguments %q", args)
+	}
+}
+
+func printMigrations(out io.Writer, verb string, list []migrations.Migration) {
+	for _, migration := range list {
+		fmt.Fprintf(out, "%s %d %s\n", verb, migration.Version, migration.Name)
+	}
+}
+
+// checkMigrations applies the pending migrations when MIGRATE_ON_START is
+// true. Otherwise the server does not start until they are applied with the
+// migrate command.
+func checkMigrations(db *gorm.DB, migrateOnStart bool) error {
+	migrator := migrations.NewMigrator(db)
+	if migrateOnStart {
+		_, err := migrator.Up()
+		return err
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("ERROR MIGRATION: %d pending migration(s), apply them with the migrate up command", len(pending))
+	}
+	return nil
+}
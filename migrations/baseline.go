@@ -0,0 +1,261 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The tables of the baseline, as they were when versioned Migrations were
+// introduced. Like every Migration, they are never changed: a change of the
+// models takes a new Migration.
+//
+// The structs are named like the models, so the baseline names the join
+// tables, foreign keys and indexes the way AutoMigrate of the models did.
+
+type permission struct {
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"size:100;uniqueIndex"`
+	Description string `gorm:"size:300"`
+}
+
+type role struct {
+	gorm.Model
+	Name         string       `gorm:"size:50"`
+	SelfRegister bool         `gorm:"default:false"`
+	Permissions  []permission `gorm:"many2many:role_permissions;joinForeignKey:RoleID;joinReferences:PermissionID"`
+}
+
+type user struct {
+	gorm.Model
+	RoleID            int
+	Role              role
+	Issues            []issue        `gorm:"foreignKey:UserID"`
+	Replies           []reply        `gorm:"foreignKey:UserID"`
+	Notifications     []notification `gorm:"foreignKey:UserID"`
+	Name              string         `gorm:"size:100"`
+	Email             string         `gorm:"size:300;unique;"`
+	Password          []byte
+	DeactivatedAt     *time.Time
+	TokensRevokedAt   *time.Time
+	MustResetPassword bool `gorm:"default:false"`
+	EmailVerifiedAt   *time.Time
+	TOTPSecret        string `gorm:"size:64"`
+	TOTPEnabledAt     *time.Time
+	TOTPLastStep      int64
+}
+
+type project struct {
+	gorm.Model
+	Name         string `gorm:"size:100"`
+	Key          string `gorm:"size:10;unique"`
+	Description  string `gorm:"size:2000"`
+	OwnerID      int
+	Owner        user
+	IssueCounter int
+	Issues       []issue `gorm:"foreignKey:ProjectID"`
+}
+
+type status struct {
+	ID       uint   `gorm:"primarykey"`
+	Name     string `gorm:"size:50;unique"`
+	Position int
+	IsClosed bool
+}
+
+type statusTransition struct {
+	ID           uint `gorm:"primarykey"`
+	FromStatusID uint `gorm:"uniqueIndex:idx_status_transition"`
+	ToStatusID   uint `gorm:"uniqueIndex:idx_status_transition"`
+	RoleID       int  `gorm:"uniqueIndex:idx_status_transition"`
+}
+
+type label struct {
+	gorm.Model
+	ProjectID   *uint  `gorm:"index"`
+	Name        string `gorm:"size:50"`
+	Color       string `gorm:"size:7"`
+	Description string `gorm:"size:300"`
+}
+
+type issue struct {
+	gorm.Model
+	ProjectID         uint `gorm:"index"`
+	Number            int
+	Key               string `gorm:"size:20;unique"`
+	UserID            int
+	Title             string `gorm:"size:100"`
+	Body              string `gorm:"size:2000"`
+	StatusID          uint   `gorm:"index"`
+	Status            status
+	Severity          string `gorm:"size:1"`
+	UpdatedByUserID   int
+	UpdatedByUserName string  `gorm:"size:100"`
+	Replies           []reply `gorm:"foreignKey:IssueID"`
+	Assignees         []user  `gorm:"many2many:issue_assignees;joinForeignKey:IssueID;joinReferences:UserID"`
+	Labels            []label `gorm:"many2many:issue_labels;joinForeignKey:IssueID;joinReferences:LabelID"`
+}
+
+type issueEvent struct {
+	ID        uint `gorm:"primarykey"`
+	IssueID   uint `gorm:"index"`
+	ActorID   uint
+	ActorName string `gorm:"size:100"`
+	Field     string `gorm:"size:50"`
+	OldValue  string `gorm:"size:2000"`
+	NewValue  string `gorm:"size:2000"`
+	CreatedAt time.Time
+}
+
+type reply struct {
+	gorm.Model
+	UserID  uint
+	IssueID uint
+	Body    string `gorm:"size:2000"`
+}
+
+type attachment struct {
+	gorm.Model
+	IssueID     uint  `gorm:"index"`
+	ReplyID     *uint `gorm:"index"`
+	UserID      uint
+	FileName    string `gorm:"size:255"`
+	ContentType string `gorm:"size:100"`
+	Size        int64
+	Checksum    string `gorm:"size:64"`
+	StorageKey  string `gorm:"size:255"`
+}
+
+type notification struct {
+	gorm.Model
+	UserID  int
+	IssueID uint   `gorm:"index"`
+	ActorID uint   `gorm:"index"`
+	Kind    string `gorm:"size:50"`
+	Detail  string `gorm:"size:300"`
+	ReadAt  *time.Time
+}
+
+type webhook struct {
+	gorm.Model
+	OwnerID   int
+	ProjectID *uint  `gorm:"index"`
+	URL       string `gorm:"size:500"`
+	Events    string `gorm:"size:500"`
+	Secret    string `gorm:"size:100"`
+	Active    bool
+}
+
+type webhookDelivery struct {
+	gorm.Model
+	WebhookID     uint `gorm:"index"`
+	Webhook       webhook
+	Event         string `gorm:"size:50"`
+	Payload       string `gorm:"type:text"`
+	Status        string `gorm:"size:20;index"`
+	Attempts      int
+	NextAttemptAt *time.Time `gorm:"index"`
+	LastAttemptAt *time.Time
+	ResponseCode  int
+	Error         string `gorm:"size:500"`
+}
+
+type refreshToken struct {
+	ID           uint   `gorm:"primarykey"`
+	UserID       uint   `gorm:"index"`
+	SessionID    string `gorm:"size:64;index"`
+	TokenHash    string `gorm:"size:64;uniqueIndex"`
+	Remember     bool
+	ExpiresAt    time.Time
+	RevokedAt    *time.Time
+	ReplacedByID *uint
+	CreatedAt    time.Time
+}
+
+type passwordResetToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type emailVerificationToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type invitation struct {
+	gorm.Model
+	Email        string `gorm:"size:300;index"`
+	RoleID       int
+	Role         role
+	InvitedByID  uint
+	TokenHash    string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt    time.Time
+	AcceptedAt   *time.Time
+	AcceptedByID *uint
+}
+
+type recoveryCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	CodeHash  string `gorm:"size:64;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type loginAttempt struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    *uint  `gorm:"index"`
+	Email     string `gorm:"size:300;index"`
+	IP        string `gorm:"size:45;index"`
+	UserAgent string `gorm:"size:500"`
+	Success   bool
+	Result    string    `gorm:"size:30"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+type personalAccessToken struct {
+	gorm.Model
+	UserID     uint   `gorm:"index"`
+	Name       string `gorm:"size:100"`
+	Scopes     string `gorm:"size:300"`
+	Prefix     string `gorm:"size:20"`
+	TokenHash  string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// baselineModels are the structs whose tables the baseline creates.
+func baselineModels() []interface{} {
+	return []interface{}{
+		&permission{},
+		&role{},
+		&user{},
+		&project{},
+		&status{},
+		&statusTransition{},
+		&label{},
+		&issue{},
+		&issueEvent{},
+		&reply{},
+		&attachment{},
+		&notification{},
+		&webhook{},
+		&webhookDelivery{},
+		&refreshToken{},
+		&passwordResetToken{},
+		&emailVerificationToken{},
+		&invitation{},
+		&recoveryCode{},
+		&loginAttempt{},
+		&personalAccessToken{},
+		&severity{},
+	}
+}
@@ -1,17 +1,219 @@
 package migrations
 
 import (
+	"fmt"
+	"issue-tracker/database"
 	"issue-tracker/models"
+	"issue-tracker/seeds"
 
 	"gorm.io/gorm"
 )
 
-// MigrateTables migrates the Models into the Database table.
-func MigrateTables(db *gorm.DB) {
-	db.AutoMigrate(&models.Role{},
-		&models.User{},
-		&models.Issue{},
-		&models.Reply{},
-		&models.Notification{},
-	)
+// Migrations are the versioned changes of the database, see Migrator. A new
+// Migration takes the next Version and is never changed once released.
+//
+// The baseline creates the tables as they were when versioned Migrations were
+// introduced, see baseline.go, so a database created after Migrations 3 to 6
+// already has their change. Those check the schema before changing it. The
+// baseline and the Migrations which change data use their own structs or SQL
+// instead of the models, which keep changing.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline schema",
+		Up:      migrateBaseline,
+		Down:    dropBaseline,
+	},
+	{
+		Version: 2,
+		Name:    "seed built-in roles and workflow",
+		Up: func(tx *gorm.DB) error {
+			if err := seeds.Roles(tx); err != nil {
+				return err
+			}
+			return seeds.Workflow(tx)
+		},
+		// The rows are dropped with the tables of the baseline.
+		Down: func(tx *gorm.DB) error { return nil },
+	},
+	{
+		Version: 3,
+		Name:    "move issues to the status workflow",
+		Up:      backfillIssueStatuses,
+	},
+	{
+		Version: 4,
+		Name:    "move issues to projects",
+		Up:      backfillIssueProjects,
+	},
+	{
+		Version: 5,
+		Name:    "create search indexes",
+		Up:      createSearchIndexes,
+		Down:    dropSearchIndexes,
+	},
+	{
+		Version: 6,
+		Name:    "create severities",
+		Up:      createSeverities,
+		Down:    dropSeverities,
+	},
+}
+
+// MigrateTables applies the pending Migrations and inserts the built-in rows
+// which are missing, see seeds.Builtins.
+func MigrateTables(db *gorm.DB) error {
+	if _, err := NewMigrator(db).Up(); err != nil {
+		return err
+	}
+	return seeds.Builtins(db)
+}
+
+// baselineJoinTables are the many2many tables of the baseline.
+var baselineJoinTables = []string{"role_permissions", "issue_assignees", "issue_labels"}
+
+// migrateBaseline creates the tables, or brings the tables of a database which
+// was migrated before versioned Migrations up to date.
+func migrateBaseline(tx *gorm.DB) error {
+	// Users from before e-mail verification are taken as verified, once.
+	verifyExistingEmails := tx.Migrator().HasTable(&user{}) &&
+		!tx.Migrator().HasColumn(&user{}, "email_verified_at")
+
+	if err := tx.AutoMigrate(baselineModels()...); err != nil {
+		return err
+	}
+
+	if verifyExistingEmails {
+		return backfillEmailVerified(tx)
+	}
+	return nil
+}
+
+// dropBaseline drops every table of the baseline.
+func dropBaseline(tx *gorm.DB) error {
+	for _, table := range baselineJoinTables {
+		if err := tx.Migrator().DropTable(table); err != nil {
+			return err
+		}
+	}
+	return tx.Migrator().DropTable(baselineModels()...)
+}
+
+// backfillIssueStatuses moves Issues from the old one character status column
+// ("1" = Opened, "0" = Closed) to the Status workflow and drops that column.
+func backfillIssueStatuses(db *gorm.DB) error {
+	// The IDs of the Open and Closed Statuses seeded by Migration 2.
+	const statusOpen, statusClosed = 1, 6
+
+	if !db.Migrator().HasColumn(&issue{}, "status") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE issues SET status_id = ? WHERE status = '1' AND status_id IS NULL", statusOpen).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("UPDATE issues SET status_id = ? WHERE status_id IS NULL", statusClosed).Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&issue{}, "status")
+	})
+}
+
+// backfillEmailVerified marks the e-mails of the Users who registered before
+// e-mail verification as verified, so they are not locked out.
+func backfillEmailVerified(db *gorm.DB) error {
+	return db.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error
+}
+
+// createSearchIndexes creates the GIN indexes used by the PostgreSQL full-text
+// search. Other databases use the in-memory fallback and need no index.
+func createSearchIndexes(db *gorm.DB) error {
+	if db.Dialector.Name() != database.DriverPostgres {
+		return nil
+	}
+
+	err := db.Exec("CREATE INDEX IF NOT EXISTS idx_issues_search ON issues USING GIN ((" +
+		models.IssueSearchVector + "))").Error
+	if err != nil {
+		return err
+	}
+
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_replies_search ON replies USING GIN ((" +
+		models.ReplySearchVector + "))").Error
+}
+
+// dropSearchIndexes drops the indexes of createSearchIndexes.
+func dropSearchIndexes(db *gorm.DB) error {
+	if db.Dialector.Name() != database.DriverPostgres {
+		return nil
+	}
+
+	if err := db.Exec("DROP INDEX IF EXISTS idx_issues_search").Error; err != nil {
+		return err
+	}
+	return db.Exec("DROP INDEX IF EXISTS idx_replies_search").Error
+}
+
+// backfillIssueProjects moves Issues created before Projects existed into a
+// "LEGACY" Project and gives them their Number and Key.
+func backfillIssueProjects(db *gorm.DB) error {
+	var legacy []issue
+	err := db.Where("project_id IS NULL OR project_id = 0").Order("id").Find(&legacy).Error
+	if err != nil || len(legacy) == 0 {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		target := project{
+			Name:        "Legacy issues",
+			Key:         "LEGACY",
+			Description: "Issues created before Projects were introduced.",
+			OwnerID:     legacy[0].UserID,
+		}
+		if err := tx.Where(project{Key: target.Key}).FirstOrCreate(&target).Error; err != nil {
+			return err
+		}
+
+		for _, source := range legacy {
+			target.IssueCounter++
+			err := tx.Model(&source).UpdateColumns(map[string]interface{}{
+				"project_id": target.ID,
+				"number":     target.IssueCounter,
+				"key":        fmt.Sprintf("%s-%d", target.Key, target.IssueCounter),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&target).UpdateColumn("issue_counter", target.IssueCounter).Error
+	})
+}
+
+// severity is the Severity table as created by Migration 6.
+type severity struct {
+	ID   uint   `gorm:"primarykey"`
+	Name string `gorm:"size:50;unique"`
+}
+
+func (severity) TableName() string {
+	return "severities"
+}
+
+// createSeverities creates the severities table, which the baseline already
+// created on newer databases, and inserts the built-in Severities.
+func createSeverities(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable(&severity{}) {
+		if err := tx.Migrator().CreateTable(&severity{}); err != nil {
+			return err
+		}
+	}
+	return seeds.Severities(tx)
+}
+
+func dropSeverities(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&severity{})
 }
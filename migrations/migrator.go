@@ -0,0 +1,260 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Migration is one versioned change of the database schema or data.
+//
+// Up applies the change and Down reverts it. A nil Down makes the Migration
+// irreversible, so it can not be rolled back. Both run in a transaction with
+// the record of the Migration in schema_migrations.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied Migration.
+type SchemaMigration struct {
+	Version   int64  `gorm:"primarykey;autoIncrement:false"`
+	Name      string `gorm:"size:100"`
+	AppliedAt time.Time
+}
+
+// TableName is the table applied Migrations are recorded in.
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus is a Migration and when it was applied, if it was.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back Migrations, in the order of their Version.
+//
+// With DryRun, the Migrations run in a transaction which is rolled back, and
+// the statements which change the database are written to Out.
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+	DryRun     bool
+	Out        io.Writer
+}
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// NewMigrator returns a Migrator of the Migrations of this package, which
+// writes a dry run to os.Stdout.
+func NewMigrator(db *gorm.DB) *Migrator {
+	return &Migrator{DB: db, Migrations: Migrations, Out: os.Stdout}
+}
+
+// Status returns every Migration, oldest first, with when it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	migrations, err := m.sorted()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i].Migration = migration
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the Migrations which are not applied yet, oldest first.
+func (m *Migrator) Pending() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending Migrations, oldest first. Returns the applied ones.
+//
+// Each Migration is applied in its own transaction, so the ones before a
+// failing Migration stay applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.run(pending, func(tx *gorm.DB, migration Migration) error {
+		if err := migration.Up(tx); err != nil {
+			return err
+		}
+		record := SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+		return tx.Create(&record).Error
+	})
+}
+
+// Down rolls back the last n applied Migrations, newest first. Returns the
+// rolled back ones.
+//
+// Nothing is rolled back if one of them is irreversible.
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("ERROR MIGRATION: the number of migrations to roll back must be at least 1, got %d", n)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var rollback []Migration
+	for i := len(statuses) - 1; i >= 0 && len(rollback) < n; i-- {
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+		if statuses[i].Down == nil {
+			return nil, fmt.Errorf("ERROR MIGRATION: %d %s is irreversible", statuses[i].Version, statuses[i].Name)
+		}
+		rollback = append(rollback, statuses[i].Migration)
+	}
+
+	return m.run(rollback, func(tx *gorm.DB, migration Migration) error {
+		if err := migration.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{}, migration.Version).Error
+	})
+}
+
+// run runs step for every Migration in its own transaction, or in a single
+// rolled back transaction for a dry run.
+func (m *Migrator) run(migrations []Migration, step func(tx *gorm.DB, migration Migration) error) ([]Migration, error) {
+	if len(migrations) == 0 {
+		return nil, nil
+	}
+
+	if m.DryRun {
+		db := m.DB.Session(&gorm.Session{Logger: statementWriter{out: m.Out}})
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&SchemaMigration{}); err != nil {
+				return err
+			}
+			for _, migration := range migrations {
+				fmt.Fprintf(m.Out, "-- %d %s\n", migration.Version, migration.Name)
+				if err := step(tx, migration); err != nil {
+					return fmt.Errorf("ERROR MIGRATION %d %s: %s", migration.Version, migration.Name, err.Error())
+				}
+			}
+			return errDryRun
+		})
+		if err != errDryRun {
+			return nil, err
+		}
+		return migrations, nil
+	}
+
+	var done []Migration
+	for _, migration := range migrations {
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			return step(tx, migration)
+		})
+		if err != nil {
+			return done, fmt.Errorf("ERROR MIGRATION %d %s: %s", migration.Version, migration.Name, err.Error())
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// sorted returns the Migrations ordered by Version, checking that every
+// Version is unique.
+func (m *Migrator) sorted() ([]Migration, error) {
+	migrations := make([]Migration, len(m.Migrations))
+	copy(migrations, m.Migrations)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("ERROR MIGRATION: version %d is used twice", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// applied returns the records of the applied Migrations by Version. The
+// schema_migrations table is created if it does not exist yet, except on a
+// dry run.
+func (m *Migrator) applied() (map[int64]SchemaMigration, error) {
+	if m.DryRun && !m.DB.Migrator().HasTable(&SchemaMigration{}) {
+		return map[int64]SchemaMigration{}, nil
+	}
+	if err := m.DB.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var records []SchemaMigration
+	if err := m.DB.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// statementWriter is the logger of a dry run. It writes the statements which
+// change the database, and leaves out the queries a Migration only reads with.
+type statementWriter struct {
+	out io.Writer
+}
+
+func (w statementWriter) LogMode(logger.LogLevel) logger.Interface {
+	return w
+}
+
+func (w statementWriter) Info(context.Context, string, ...interface{}) {}
+
+func (w statementWriter) Warn(context.Context, string, ...interface{}) {}
+
+func (w statementWriter) Error(context.Context, string, ...interface{}) {}
+
+func (w statementWriter) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	statement := strings.ToUpper(strings.TrimSpace(sql))
+	if strings.HasPrefix(statement, "SELECT") || strings.HasPrefix(statement, "PRAGMA") {
+		return
+	}
+	fmt.Fprintf(w.out, "%s;\n", sql)
+}
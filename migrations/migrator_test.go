@@ -0,0 +1,183 @@
+package migrations
+
+import (
+	"bytes"
+	"errors"
+	"issue-tracker/database"
+	"issue-tracker/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	db, err := database.Open(database.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	return db
+}
+
+func createTable(name string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec("CREATE TABLE " + name + " (id integer primary key)").Error
+	}
+}
+
+func dropTable(name string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec("DROP TABLE " + name).Error
+	}
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	db := openSQLite(t)
+	migrator := &Migrator{DB: db, Migrations: []Migration{
+		{Version: 2, Name: "second", Up: createTable("second"), Down: dropTable("second")},
+		{Version: 1, Name: "first", Up: createTable("first"), Down: dropTable("first")},
+	}}
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	if assert.Len(t, applied, 2) {
+		assert.Equal(t, int64(1), applied[0].Version, "oldest first")
+	}
+	assert.True(t, db.Migrator().HasTable("first"))
+	assert.True(t, db.Migrator().HasTable("second"))
+
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	rolledBack, err := migrator.Down(1)
+	assert.NoError(t, err)
+	if assert.Len(t, rolledBack, 1) {
+		assert.Equal(t, int64(2), rolledBack[0].Version, "newest first")
+	}
+	assert.True(t, db.Migrator().HasTable("first"))
+	assert.False(t, db.Migrator().HasTable("second"))
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+
+	_, err = migrator.Down(0)
+	assert.Error(t, err)
+}
+
+func TestMigratorFailureKeepsEarlierMigrations(t *testing.T) {
+	db := openSQLite(t)
+	migrator := &Migrator{DB: db, Migrations: []Migration{
+		{Version: 1, Name: "first", Up: createTable("first")},
+		{Version: 2, Name: "broken", Up: func(tx *gorm.DB) error {
+			if err := createTable("broken")(tx); err != nil {
+				return err
+			}
+			return errors.New("backfill failed")
+		}},
+	}}
+
+	applied, err := migrator.Up()
+	assert.Error(t, err)
+	assert.Len(t, applied, 1)
+	assert.True(t, db.Migrator().HasTable("first"))
+	assert.False(t, db.Migrator().HasTable("broken"), "the failed migration is rolled back")
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "broken", pending[0].Name)
+	}
+
+	_, err = migrator.Down(1)
+	assert.Error(t, err, "first is irreversible")
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	db := openSQLite(t)
+	var out bytes.Buffer
+	migrator := &Migrator{DB: db, DryRun: true, Out: &out, Migrations: []Migration{
+		{Version: 1, Name: "first", Up: createTable("first"), Down: dropTable("first")},
+	}}
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+	assert.Contains(t, out.String(), "-- 1 first")
+	assert.Contains(t, out.String(), "CREATE TABLE first")
+	assert.False(t, db.Migrator().HasTable("first"))
+	assert.False(t, db.Migrator().HasTable(&SchemaMigration{}))
+}
+
+func TestMigrations(t *testing.T) {
+	db := openSQLite(t)
+	migrator := NewMigrator(db)
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(Migrations))
+	assert.NoError(t, MigrateTables(db))
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+// The Migrations are frozen, so a change of the models needs a new one.
+func TestMigrationsCoverModels(t *testing.T) {
+	db := openSQLite(t)
+	assert.NoError(t, MigrateTables(db))
+
+	current := []interface{}{
+		&models.Permission{}, &models.Role{}, &models.User{}, &models.Project{},
+		&models.Status{}, &models.StatusTransition{}, &models.Label{}, &models.Issue{},
+		&models.IssueEvent{}, &models.Reply{}, &models.Attachment{}, &models.Notification{},
+		&models.Webhook{}, &models.WebhookDelivery{}, &models.RefreshToken{},
+		&models.PasswordResetToken{}, &models.EmailVerificationToken{}, &models.Invitation{},
+		&models.RecoveryCode{}, &models.LoginAttempt{}, &models.PersonalAccessToken{},
+		&models.Severity{},
+	}
+	for _, model := range current {
+		stmt := &gorm.Statement{DB: db}
+		if !assert.NoError(t, stmt.Parse(model)) {
+			continue
+		}
+		if !assert.True(t, db.Migrator().HasTable(model), stmt.Schema.Table) {
+			continue
+		}
+		for _, column := range stmt.Schema.DBNames {
+			assert.True(t, db.Migrator().HasColumn(model, column), stmt.Schema.Table+"."+column)
+		}
+	}
+}
+
+func TestBackfillLegacyIssues(t *testing.T) {
+	db := openSQLite(t)
+	assert.NoError(t, migrateBaseline(db))
+	assert.NoError(t, Migrations[1].Up(db))
+	assert.NoError(t, db.Exec("INSERT INTO users (id, role_id, email) VALUES (7, ?, 'qa@email.com'), (8, ?, 'dev@email.com')",
+		models.RoleQA, models.RoleDeveloper).Error)
+	assert.NoError(t, db.Exec("ALTER TABLE issues ADD COLUMN status varchar(1)").Error)
+	assert.NoError(t, db.Exec("INSERT INTO issues (title, user_id, status) VALUES ('open', 7, '1'), ('closed', 8, '0')").Error)
+
+	assert.NoError(t, backfillIssueStatuses(db))
+	assert.NoError(t, backfillIssueProjects(db))
+
+	var issues []issue
+	assert.NoError(t, db.Order("id").Find(&issues).Error)
+	if assert.Len(t, issues, 2) {
+		assert.Equal(t, uint(models.StatusOpen), issues[0].StatusID)
+		assert.Equal(t, uint(models.StatusClosed), issues[1].StatusID)
+		assert.Equal(t, "LEGACY-1", issues[0].Key)
+		assert.Equal(t, "LEGACY-2", issues[1].Key)
+	}
+
+	var legacy project
+	assert.NoError(t, db.Where("key = ?", "LEGACY").First(&legacy).Error)
+	assert.Equal(t, 2, legacy.IssueCounter)
+	assert.Equal(t, 7, legacy.OwnerID)
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"issue-tracker/database"
+	"issue-tracker/storage"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Errors of SaveAttachment.
+var (
+	ErrAttachmentTooLarge = errors.New("ERROR FILE: file is too large")
+	ErrAttachmentType     = errors.New("ERROR FILE: file type is not allowed")
+)
+
+// MaxAttachmentSize is the maximum size of an Attachment in bytes.
+var MaxAttachmentSize int64 = 10 << 20
+
+// AttachmentTypes are the allowed content types of an Attachment: screenshots,
+// logs and compressed crash dumps. The content type is detected from the
+// content, not taken from the upload. Content which is not recognized, like
+// executables, is detected as application/octet-stream and refused.
+var AttachmentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"text/plain",
+	"application/json",
+	"application/pdf",
+	"application/zip",
+	"application/x-gzip",
+}
+
+// Attachment belongs to Issue and optionally to one of its Replies.
+//
+// The content is kept in storage.Default under StorageKey. Checksum is the hex
+// encoded SHA-256 of the content.
+type Attachment struct {
+	gorm.Model
+	IssueID     uint  `gorm:"index"`
+	ReplyID     *uint `gorm:"index"`
+	UserID      uint
+	FileName    string `gorm:"size:255"`
+	ContentType string `gorm:"size:100"`
+	Size        int64
+	Checksum    string `gorm:"size:64"`
+	StorageKey  string `gorm:"size:255" json:"-"`
+}
+
+// SaveAttachment stores the content and saves the Attachment to the database.
+// Sets the ContentType, Size and Checksum from the content.
+//
+// Returns ErrAttachmentTooLarge or ErrAttachmentType if the content is not
+// allowed, nothing is kept in that case.
+func (a *Attachment) SaveAttachment(content io.Reader) error {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	head = head[:n]
+
+	a.ContentType = detectAttachmentType(head)
+	if !isAttachmentType(a.ContentType) {
+		return ErrAttachmentType
+	}
+
+	key, err := newStorageKey(a.IssueID)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	counter := &countingReader{
+		r: io.LimitReader(io.MultiReader(bytes.NewReader(head), content), MaxAttachmentSize+1),
+	}
+	if err := storage.Default.Save(key, io.TeeReader(counter, hash)); err != nil {
+		return err
+	}
+
+	if counter.n > MaxAttachmentSize {
+		removeStoredFile(key)
+		return ErrAttachmentTooLarge
+	}
+
+	a.FileName = cleanFileName(a.FileName)
+	a.Size = counter.n
+	a.Checksum = hex.EncodeToString(hash.Sum(nil))
+	a.StorageKey = key
+
+	if err := database.DB.Create(&a).Error; err != nil {
+		removeStoredFile(key)
+		return err
+	}
+	return nil
+}
+
+// IndexAttachments fetches the Attachments of an Issue and of its Replies,
+// oldest first.
+func (a *Attachment) IndexAttachments(issueID uint) (*[]Attachment, error) {
+	return indexAttachments(database.DB, issueID)
+}
+
+func indexAttachments(db *gorm.DB, issueID uint) (*[]Attachment, error) {
+	var attachments []Attachment
+	err := db.Where("issue_id = ?", issueID).Order("id").Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachments, nil
+}
+
+// FindAttachmentByID fetches an Attachment of an Issue by its ID.
+func (a *Attachment) FindAttachmentByID(issueID uint, id string) (*Attachment, error) {
+	var result Attachment
+	query := database.DB.Where("issue_id = ? AND id = ?", issueID, id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find attachment with ID: %s", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// Open opens the stored content of the Attachment.
+func (a *Attachment) Open() (io.ReadCloser, error) {
+	return storage.Default.Open(a.StorageKey)
+}
+
+// DeleteAttachment deletes the Attachment and its stored content.
+func (a *Attachment) DeleteAttachment() error {
+	if err := database.DB.Unscoped().Delete(&a).Error; err != nil {
+		return err
+	}
+	return storage.Default.Delete(a.StorageKey)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// detectAttachmentType detects the content type from the first bytes of the
+// content, without parameters like the charset.
+func detectAttachmentType(head []byte) string {
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	// DetectContentType does not know JSON, it is reported as text.
+	if contentType == "text/plain" {
+		trimmed := bytes.TrimSpace(head)
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			return "application/json"
+		}
+	}
+	return contentType
+}
+
+func isAttachmentType(contentType string) bool {
+	for _, allowed := range AttachmentTypes {
+		if allowed == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFileName keeps only the base name of an uploaded file name.
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
+
+// newStorageKey returns a random storage key for an Attachment of the Issue.
+func newStorageKey(issueID uint) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("issues/%d/%s", issueID, hex.EncodeToString(b)), nil
+}
+
+// removeStoredFile removes a file whose Attachment was not saved.
+func removeStoredFile(key string) {
+	if err := storage.Default.Delete(key); err != nil {
+		log.Printf("ERROR ATTACHMENT: %s", err)
+	}
+}
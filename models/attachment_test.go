@@ -0,0 +1,16 @@
+package models_test
+
+import (
+	"bytes"
+	"issue-tracker/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAttachmentRejectsUnknownContent(t *testing.T) {
+	attachment := models.Attachment{IssueID: 1, FileName: "tool.exe"}
+	err := attachment.SaveAttachment(bytes.NewReader([]byte("\x7fELF\x02\x01\x01\x00\x00\x00")))
+	assert.Equal(t, models.ErrAttachmentType, err)
+	assert.Equal(t, "application/octet-stream", attachment.ContentType)
+}
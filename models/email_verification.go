@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"issue-tracker/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrEmailVerificationInvalid is returned for an e-mail verification token
+// which does not exist, has expired or was used already.
+var ErrEmailVerificationInvalid = errors.New("ERROR: e-mail verification token is invalid or has expired")
+
+// EmailVerificationToken belongs to User
+//
+// An EmailVerificationToken is e-mailed to a new User to prove the e-mail is
+// theirs. It can be used once, until ExpiresAt. Only the SHA-256 of the token
+// is stored.
+type EmailVerificationToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// SaveEmailVerificationToken saves the EmailVerificationToken. Tokens issued
+// to the User before can no longer be used.
+func (t *EmailVerificationToken) SaveEmailVerificationToken() error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", t.UserID).
+			Update("expires_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&t).Error
+	})
+	return err
+}
+
+// RecentlyIssued checks whether an EmailVerificationToken was issued to the
+// User after since.
+func (t *EmailVerificationToken) RecentlyIssued(userID uint, since time.Time) (bool, error) {
+	var count int64
+	err := database.DB.Model(&EmailVerificationToken{}).
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// VerifyEmail uses the EmailVerificationToken with the given hash to verify
+// the User's e-mail. The token can not be used again.
+//
+// Returns ErrEmailVerificationInvalid if the token can not be used.
+func (t *EmailVerificationToken) VerifyEmail(hash string) (*User, error) {
+	var token EmailVerificationToken
+	if err := database.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, ErrEmailVerificationInvalid
+	}
+
+	var user User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		query := tx.Model(&EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+			Update("used_at", now)
+		if query.Error != nil {
+			return query.Error
+		}
+		if query.RowsAffected != 1 {
+			return ErrEmailVerificationInvalid
+		}
+
+		if err := tx.Where("id = ?", token.UserID).First(&user).Error; err != nil {
+			return ErrEmailVerificationInvalid
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		err := tx.Model(&User{}).Where("id = ?", user.ID).Update("email_verified_at", now).Error
+		if err != nil {
+			return err
+		}
+		user.EmailVerifiedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
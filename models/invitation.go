@@ -0,0 +1,177 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/database"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvitationInvalid is returned for an invitation token which does not
+// exist, has expired, was revoked or was accepted already.
+var ErrInvitationInvalid = errors.New("ERROR: invitation is invalid or has expired")
+
+// Invitation belongs to Role
+//
+// An Invitation allows registering with its Email and gives the new User its
+// Role. The token is e-mailed to the invitee, only its SHA-256 is stored.
+// Deleting an Invitation revokes it.
+type Invitation struct {
+	gorm.Model
+	Email        string `gorm:"size:300;index"`
+	RoleID       int
+	Role         Role `json:"-"`
+	InvitedByID  uint
+	TokenHash    string `gorm:"size:64;uniqueIndex" json:"-"`
+	ExpiresAt    time.Time
+	AcceptedAt   *time.Time
+	AcceptedByID *uint
+}
+
+// InvitationIndex is used for the Invitation Index route.
+type InvitationIndex struct {
+	ID            uint
+	Email         string
+	RoleID        int
+	RoleName      string
+	InvitedByID   uint
+	InvitedByName string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	AcceptedAt    *time.Time
+}
+
+// ValidateInvitation validates the Invitation data. The Role must exist and
+// nobody may have registered with the Email yet.
+func (i *Invitation) ValidateInvitation() error {
+	i.Email = strings.ToLower(strings.TrimSpace(i.Email))
+	if !strings.Contains(i.Email, "@") || strings.ContainsAny(i.Email, " \r\n") {
+		return fmt.Errorf("ERROR EMAIL: %s is not a valid e-mail", i.Email)
+	}
+
+	var role Role
+	if err := database.DB.Where("id = ?", i.RoleID).First(&role).Error; err != nil {
+		return fmt.Errorf("ERROR ROLE: could not find role with ID: %d", i.RoleID)
+	}
+	i.Role = role
+
+	var count int64
+	if err := database.DB.Model(&User{}).Where("email = ?", i.Email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("ERROR EMAIL: %s is already registered", i.Email)
+	}
+	return nil
+}
+
+// SaveInvitation saves the Invitation. Invitations sent to the Email before
+// can no longer be accepted.
+func (i *Invitation) SaveInvitation() error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Invitation{}).
+			Where("email = ? AND accepted_at IS NULL", i.Email).
+			Update("expires_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Omit("Role").Create(&i).Error
+	})
+	return err
+}
+
+// IndexInvitations fetches every Invitation which was not revoked, newest
+// first.
+func (i *Invitation) IndexInvitations() (*[]InvitationIndex, error) {
+	var invitations []InvitationIndex
+	err := database.DB.Model(&Invitation{}).
+		Select("invitations.id, invitations.email, invitations.role_id, roles.name AS role_name, " +
+			"invitations.invited_by_id, users.name AS invited_by_name, invitations.created_at, " +
+			"invitations.expires_at, invitations.accepted_at").
+		Joins("left join roles on roles.id = invitations.role_id").
+		Joins("left join users on users.id = invitations.invited_by_id").
+		Where("invitations.deleted_at IS NULL").
+		Order("invitations.id DESC").
+		Scan(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitations, nil
+}
+
+// FindInvitationByID fetches an Invitation by its ID.
+func (i *Invitation) FindInvitationByID(id string) (*Invitation, error) {
+	var result Invitation
+	query := database.DB.Where("id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find invitation with ID: %s", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// FindPendingInvitationByHash fetches the Invitation with the given token
+// hash, if it can still be accepted.
+func (i *Invitation) FindPendingInvitationByHash(hash string) (*Invitation, error) {
+	var result Invitation
+	err := database.DB.
+		Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", hash, time.Now()).
+		First(&result).Error
+	if err != nil {
+		return nil, ErrInvitationInvalid
+	}
+	return &result, nil
+}
+
+// DeleteInvitation revokes an Invitation which was not accepted yet.
+func (i *Invitation) DeleteInvitation() error {
+	if i.AcceptedAt != nil {
+		return errors.New("ERROR: invitation has already been accepted")
+	}
+	err := database.DB.Delete(&i).Error
+	return err
+}
+
+// AcceptInvitation registers the User with the Invitation's Role. The Email
+// got the invitation, so it is verified.
+//
+// Returns ErrInvitationInvalid if the Invitation was accepted or revoked in
+// the meantime.
+func (i *Invitation) AcceptInvitation(user *User) error {
+	now := time.Now()
+	user.RoleID = i.RoleID
+	user.EmailVerifiedAt = &now
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		query := tx.Model(&Invitation{}).
+			Where("id = ? AND accepted_at IS NULL AND deleted_at IS NULL", i.ID).
+			Updates(map[string]interface{}{
+				"accepted_at":    now,
+				"accepted_by_id": user.ID,
+			})
+		if query.Error != nil {
+			return query.Error
+		}
+		if query.RowsAffected != 1 {
+			return ErrInvitationInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	i.AcceptedAt = &now
+	i.AcceptedByID = &user.ID
+	return nil
+}
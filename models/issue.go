@@ -10,41 +10,67 @@ import (
 	"gorm.io/gorm"
 )
 
-// Issue Belongs To User
+// Issue Belongs To User and Project.
+//
+// Number is the Issue's sequence number inside its Project and Key is the
+// human-readable identifier built from it, for example "WEB-42".
 type Issue struct {
 	gorm.Model
+	ProjectID         uint `gorm:"index"`
+	Number            int
+	Key               string `gorm:"size:20;unique"`
 	UserID            int
 	Title             string `gorm:"size:100"`
 	Body              string `gorm:"size:2000"`
-	Status            string `gorm:"size:1"` // 1 = Opened or 0 = Closed
+	StatusID          uint   `gorm:"index"`
+	Status            Status
 	Severity          string `gorm:"size:1"` // 1 = Low, 2 = Medium, 3 = High
 	UpdatedByUserID   int
 	UpdatedByUserName string `gorm:"size:100"`
 	Replies           []Reply
+	Assignees         []User  `gorm:"many2many:issue_assignees;" json:"-"`
+	Labels            []Label `gorm:"many2many:issue_labels;" json:"-"`
 }
 
 // IssueIndex is used for IndexIssue operation.
 type IssueIndex struct {
-	ID        int
-	Title     string
-	Status    string
-	Severity  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    int
-	UserName  string
+	ID                int
+	ProjectID         int
+	Key               string
+	Title             string
+	StatusID          int
+	StatusName        string
+	Severity          string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	UserID            int
+	UserName          string
+	UpdatedByUserID   int
+	UpdatedByUserName string
+	Labels            []IssueLabel `gorm:"-"`
 }
 
 type IssueShow struct {
-	ID        int
-	Title     string
-	Body      string
-	Status    string
-	Severity  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    int
-	UserName  string
+	ID         int
+	ProjectID  int
+	Key        string
+	Title      string
+	Body       string
+	StatusID   int
+	StatusName string
+	Severity   string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     int
+	UserName   string
+}
+
+// IssueAssignee is a User assigned to an Issue, used for Show route.
+type IssueAssignee struct {
+	ID     int
+	Name   string
+	Email  string
+	RoleID int
 }
 
 type RepliesInIssue struct {
@@ -71,104 +97,57 @@ func (i *Issue) ValidateIssue() error {
 	return nil
 }
 
-// SaveIssue saves the Issue to the database.
-func (i *Issue) SaveIssue() error {
-	err := database.DB.Create(&i).Error
-	return err
-}
-
-// IndexIssues fetches all issues from the database.
-func (i *Issue) IndexIssues() (*[]IssueIndex, error) {
-	var issues []IssueIndex
-	query := database.DB.Model(&Issue{}).
-		Select(`
-			issues.id,
-			issues.title,
-			issues.status,
-			issues.severity,
-			issues.created_at,
-			issues.updated_at,
-			issues.user_id,
-			users."name" AS "user_name"`).
-		Joins("left join users on issues.user_id = users.id").
-		Scan(&issues)
-
-	if query.Error != nil {
-		return nil, query.Error
+// FindOneIssueByID fetches an issue by its ID.
+//
+// It and FindIssueByProjectNumber are left for the Label and Attachment
+// handlers, which do not run on a Store yet. Everything else uses the
+// IssueRepository.
+func (i *Issue) FindOneIssueByID(id string) (*Issue, error) {
+	issueID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not find issue with ID: %s", id)
 	}
-
-	return &issues, nil
+	return NewGormStore(database.DB).Issues().FindByID(uint(issueID))
 }
 
-// FindIssueAndRepliesByID fetches an issue with provided ID.
-// It will return issue, replies of that issue, and user data that is needed for Show route.
-func (i *Issue) FindIssueAndRepliesByID(id string) (*IssueShow, *[]RepliesInIssue, error) {
-	var issue IssueShow
-	// query := database.DB.Preload("Replies").Where("issues.id = ?", id).First(&result)
-	query := database.DB.Model(&Issue{}).
-		Select(`
-			issues.id,
-			issues.title,
-			issues.body,
-			issues.status,
-			issues.severity,
-			issues.created_at,
-			issues.updated_at,
-			issues.user_id,
-			users."name" AS "user_name"`).
-		Joins("left join users on issues.user_id = users.id").
-		Where("issues.id = ?", id).
-		First(&issue)
-
-	var replies []RepliesInIssue
-	queryReplies := database.DB.Model(&Reply{}).
-		Select(`
-			replies.id,
-			replies.user_id,
-			replies.issue_id,
-			users."name" as "replier",
-			replies.body,
-			replies.created_at,
-			replies.updated_at`).
-		Joins("join users on replies.user_id = users.id").
-		Joins("join issues on replies.issue_id = issues.id").
-		Where("replies.issue_id = ?", id).
-		Scan(&replies)
-
-	if issue.ID == 0 {
-		return nil, nil, fmt.Errorf("ERROR: could not find issue with ID: %s", id)
-	}
-
-	if query.Error != nil || queryReplies.Error != nil {
-		return nil, nil, query.Error
+// FindIssueByProjectNumber fetches an issue by its Number inside a Project.
+// For example, Number 42 in Project "WEB" is the Issue "WEB-42".
+func (i *Issue) FindIssueByProjectNumber(projectID uint, number string) (*Issue, error) {
+	issueNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Could not find issue with number: %s", number)
 	}
-	return &issue, &replies, nil
+	return NewGormStore(database.DB).Issues().FindByProjectNumber(projectID, issueNumber)
 }
 
-func (i *Issue) FindOneIssueByID(id string) (*Issue, error) {
-	var result Issue
-	query := database.DB.Preload("Replies").Where("issues.id = ?", id).First(&result)
-
-	if result.ID == 0 {
-		return nil, fmt.Errorf("ERROR: Could not find issue with ID: %s", id)
-	}
-
-	if query.Error != nil {
-		return nil, query.Error
+// AddLabel adds a Label to the Issue. Adding a Label twice does nothing.
+// Whether the Label is usable in the Issue's Project must be checked with
+// UsableIn first.
+func (i *Issue) AddLabel(label *Label, by *User) error {
+	var count int64
+	err := database.DB.Table("issue_labels").
+		Where("issue_id = ? AND label_id = ?", i.ID, label.ID).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return err
 	}
-	return &result, nil
-}
 
-// UpdateIssue updates an Issue data.
-// Takes an origin Issue as parameter. Origin issue is
-// the Issue that user will update.
-func (i *Issue) UpdateIssue(origin *Issue) error {
-	err := database.DB.Model(&origin).Updates(i).Error
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&i).Association("Labels").Append(label); err != nil {
+			return err
+		}
+		return recordIssueEvents(tx, newIssueEvent(i, by, "label", "", label.Name))
+	})
 	return err
 }
 
-// DeleteIssue deletes an Issue data.
-func (i *Issue) DeleteIssue() error {
-	err := database.DB.Delete(&i).Error
+// RemoveLabel removes a Label from the Issue.
+func (i *Issue) RemoveLabel(label *Label, by *User) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&i).Association("Labels").Delete(label); err != nil {
+			return err
+		}
+		return recordIssueEvents(tx, newIssueEvent(i, by, "label", label.Name, ""))
+	})
 	return err
 }
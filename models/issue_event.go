@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IssueEvent records one change of an Issue: who changed which field, from
+// what, to what and when.
+//
+// Field is one of "title", "body", "severity", "status", "assignee" or
+// "label". For "assignee" and "label" an empty OldValue means the User or Label
+// was added, an empty NewValue means it was removed.
+type IssueEvent struct {
+	ID        uint `gorm:"primarykey"`
+	IssueID   uint `gorm:"index"`
+	ActorID   uint
+	ActorName string `gorm:"size:100"`
+	Field     string `gorm:"size:50"`
+	OldValue  string `gorm:"size:2000"`
+	NewValue  string `gorm:"size:2000"`
+	CreatedAt time.Time
+}
+
+// diffIssue returns an IssueEvent for every field of origin which is changed by
+// update. Empty fields of update are not updated by GORM, so they are skipped.
+func diffIssue(origin *Issue, update *Issue) []IssueEvent {
+	var events []IssueEvent
+	add := func(field string, oldValue string, newValue string) {
+		if newValue == "" || oldValue == newValue {
+			return
+		}
+		events = append(events, IssueEvent{
+			IssueID:   origin.ID,
+			ActorID:   uint(update.UpdatedByUserID),
+			ActorName: update.UpdatedByUserName,
+			Field:     field,
+			OldValue:  oldValue,
+			NewValue:  newValue,
+		})
+	}
+
+	add("title", origin.Title, update.Title)
+	add("body", origin.Body, update.Body)
+	add("severity", origin.Severity, update.Severity)
+	return events
+}
+
+// newIssueEvent returns an IssueEvent of a change made by a User.
+func newIssueEvent(issue *Issue, by *User, field string, oldValue string, newValue string) IssueEvent {
+	return IssueEvent{
+		IssueID:   issue.ID,
+		ActorID:   by.ID,
+		ActorName: by.Name,
+		Field:     field,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+	}
+}
+
+// recordIssueEvents saves the events in the given transaction.
+func recordIssueEvents(tx *gorm.DB, events ...IssueEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
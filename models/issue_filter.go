@@ -0,0 +1,125 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IssueSortColumns maps the sortable IssueIndex columns to their SQL expression.
+var IssueSortColumns = map[string]string{
+	"id":                   "issues.id",
+	"project_id":           "issues.project_id",
+	"key":                  "issues.project_id, issues.number",
+	"title":                "issues.title",
+	"status_id":            "issues.status_id",
+	"status_name":          "statuses.name",
+	"severity":             "issues.severity",
+	"created_at":           "issues.created_at",
+	"updated_at":           "issues.updated_at",
+	"user_id":              "issues.user_id",
+	"user_name":            "users.name",
+	"updated_by_user_id":   "issues.updated_by_user_id",
+	"updated_by_user_name": "issues.updated_by_user_name",
+}
+
+// IssueFilter holds the filters, sorting and pagination used by IndexIssues.
+//
+// Zero values mean "no filter". Page starts at 1.
+type IssueFilter struct {
+	ProjectID   uint
+	Status      []int
+	Severity    []string
+	AuthorID    int
+	UpdaterID   int
+	AssigneeID  int
+	LabelAny    []int // Issues with at least one of the Labels.
+	LabelAll    []int // Issues with every one of the Labels.
+	LabelNone   []int // Issues with none of the Labels.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Sort        string // One of the IssueSortColumns keys.
+	Desc        bool
+	Page        int
+	Limit       int
+}
+
+// apply adds the filter's WHERE conditions to the query.
+func (f *IssueFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.ProjectID != 0 {
+		query = query.Where("issues.project_id = ?", f.ProjectID)
+	}
+	if len(f.Status) > 0 {
+		query = query.Where("issues.status_id IN ?", f.Status)
+	}
+	if len(f.Severity) > 0 {
+		query = query.Where("issues.severity IN ?", f.Severity)
+	}
+	if f.AuthorID != 0 {
+		query = query.Where("issues.user_id = ?", f.AuthorID)
+	}
+	if f.UpdaterID != 0 {
+		query = query.Where("issues.updated_by_user_id = ?", f.UpdaterID)
+	}
+	if f.AssigneeID != 0 {
+		query = query.Where("issues.id IN (SELECT issue_id FROM issue_assignees WHERE user_id = ?)", f.AssigneeID)
+	}
+	if len(f.LabelAny) > 0 {
+		query = query.Where("issues.id IN (SELECT issue_id FROM issue_labels WHERE label_id IN ?)", f.LabelAny)
+	}
+	if len(f.LabelAll) > 0 {
+		query = query.Where(`issues.id IN (SELECT issue_id FROM issue_labels WHERE label_id IN ?
+			GROUP BY issue_id HAVING COUNT(DISTINCT label_id) = ?)`, f.LabelAll, len(uniqueInts(f.LabelAll)))
+	}
+	if len(f.LabelNone) > 0 {
+		query = query.Where("issues.id NOT IN (SELECT issue_id FROM issue_labels WHERE label_id IN ?)", f.LabelNone)
+	}
+	if f.CreatedFrom != nil {
+		query = query.Where("issues.created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		query = query.Where("issues.created_at < ?", *f.CreatedTo)
+	}
+	if f.UpdatedFrom != nil {
+		query = query.Where("issues.updated_at >= ?", *f.UpdatedFrom)
+	}
+	if f.UpdatedTo != nil {
+		query = query.Where("issues.updated_at < ?", *f.UpdatedTo)
+	}
+	return query
+}
+
+func uniqueInts(values []int) []int {
+	seen := map[int]struct{}{}
+	result := make([]int, 0, len(values))
+	for _, value := range values {
+		if _, ok := seen[value]; !ok {
+			seen[value] = struct{}{}
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// orderBy returns the ORDER BY clause. issues.id is always added last so pages
+// stay stable when the sorted column has equal values.
+func (f *IssueFilter) orderBy() string {
+	direction := " ASC"
+	if f.Desc {
+		direction = " DESC"
+	}
+
+	column, ok := IssueSortColumns[f.Sort]
+	if !ok || f.Sort == "id" {
+		return "issues.id" + direction
+	}
+
+	columns := strings.Split(column, ", ")
+	for idx := range columns {
+		columns[idx] += direction
+	}
+	return strings.Join(columns, ", ") + ", issues.id"
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/database"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Label tags Issues, for example "regression" or "ui".
+//
+// A Label with a ProjectID can only be used on the Issues of that Project,
+// a Label without one (global Label) can be used on every Issue.
+type Label struct {
+	gorm.Model
+	ProjectID   *uint  `gorm:"index"`
+	Name        string `gorm:"size:50"`
+	Color       string `gorm:"size:7"` // #RRGGBB
+	Description string `gorm:"size:300"`
+}
+
+// IssueLabel is a Label of an Issue, used for Index and Show routes.
+type IssueLabel struct {
+	IssueID int `json:"-"`
+	ID      int
+	Name    string
+	Color   string
+}
+
+var labelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ValidateLabel validates the Label data.
+// Color must be a hex color like #D73A4A, and the Name must be unique among the
+// Labels usable in the same Project. A global Label's Name must be unique among
+// all Labels.
+func (l *Label) ValidateLabel() error {
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		return errors.New("ERROR NAME: name must not be empty")
+	}
+	if !labelColorPattern.MatchString(l.Color) {
+		return errors.New("ERROR COLOR: color must be a hex color like #D73A4A")
+	}
+	l.Color = strings.ToUpper(l.Color)
+
+	var count int64
+	query := database.DB.Model(&Label{}).Where("LOWER(name) = ? AND id <> ?", strings.ToLower(l.Name), l.ID)
+	if l.ProjectID != nil {
+		query = query.Where("project_id = ? OR project_id IS NULL", *l.ProjectID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("ERROR NAME: label %s already exists", l.Name)
+	}
+	return nil
+}
+
+// SaveLabel saves the Label to the database.
+func (l *Label) SaveLabel() error {
+	err := database.DB.Create(&l).Error
+	return err
+}
+
+// IndexLabels fetches the Labels usable in a Project, which are the global
+// Labels and the Project's own. If projectID is 0 all Labels are fetched.
+func (l *Label) IndexLabels(projectID uint) (*[]Label, error) {
+	var labels []Label
+	query := database.DB.Order("name")
+	if projectID != 0 {
+		query = query.Where("project_id = ? OR project_id IS NULL", projectID)
+	}
+
+	if err := query.Find(&labels).Error; err != nil {
+		return nil, err
+	}
+	return &labels, nil
+}
+
+// FindLabelByID fetches a Label by its ID.
+func (l *Label) FindLabelByID(id string) (*Label, error) {
+	var result Label
+	query := database.DB.Where("id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find label with ID: %s", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// UpdateLabel updates a Label's name, color and description.
+// Takes an origin Label as parameter.
+func (l *Label) UpdateLabel(origin *Label) error {
+	err := database.DB.Model(&origin).Select("name", "color", "description").Updates(l).Error
+	return err
+}
+
+// DeleteLabel deletes a Label and removes it from every Issue.
+func (l *Label) DeleteLabel() error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM issue_labels WHERE label_id = ?", l.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&l).Error
+	})
+	return err
+}
+
+// UsableIn checks whether the Label can be used on the Issues of a Project.
+func (l *Label) UsableIn(projectID uint) bool {
+	return l.ProjectID == nil || *l.ProjectID == projectID
+}
+
+// findIssueLabels fetches the Labels of several Issues at once.
+func findIssueLabels(db *gorm.DB, issueIDs []int) ([]IssueLabel, error) {
+	var labels []IssueLabel
+	err := db.Model(&Label{}).
+		Select("issue_labels.issue_id, labels.id, labels.name, labels.color").
+		Joins("join issue_labels on issue_labels.label_id = labels.id").
+		Where("issue_labels.issue_id IN ?", issueIDs).
+		Order("labels.name").
+		Scan(&labels).Error
+	return labels, err
+}
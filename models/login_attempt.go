@@ -0,0 +1,152 @@
+package models
+
+import (
+	"issue-tracker/database"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Results of a LoginAttempt. Only the ones in failedLoginResults count
+// towards throttling.
+//
+// A login is saved as pending before the password is checked, and its result
+// is saved when it is known. A login which fails on the server is saved as an
+// error, which is not the User's fault and is not counted.
+const (
+	LoginPending           = "pending"
+	LoginSucceeded         = "success"
+	LoginUnknownEmail      = "unknown_email"
+	LoginInvalidPassword   = "invalid_password"
+	LoginInvalidTwoFactor  = "invalid_2fa"
+	LoginTwoFactorRequired = "2fa_required"
+	LoginDeactivated       = "deactivated"
+	LoginThrottled         = "throttled"
+	LoginError             = "error"
+)
+
+// failedLoginResults are the results of a wrong guess. Pending attempts count
+// as failed, so concurrent guesses can not all pass the throttle before any of
+// them is recorded. Throttled attempts are not counted, so hammering a locked
+// account does not keep it locked.
+var failedLoginResults = []string{LoginPending, LoginUnknownEmail, LoginInvalidPassword, LoginInvalidTwoFactor}
+
+// LoginAttempt belongs to User
+//
+// A LoginAttempt is the audit record of a login, with the password or with a
+// 2FA code. UserID is nil when nobody has the Email. Result says why a login
+// which was not a Success failed.
+type LoginAttempt struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    *uint  `gorm:"index"`
+	Email     string `gorm:"size:300;index"`
+	IP        string `gorm:"size:45;index"`
+	UserAgent string `gorm:"size:500"`
+	Success   bool
+	Result    string    `gorm:"size:30"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// LoginFailures is the number of failed LoginAttempts and the time of the
+// latest one.
+type LoginFailures struct {
+	Count int64
+	Last  time.Time
+}
+
+// SaveLoginAttempt saves the LoginAttempt.
+func (a *LoginAttempt) SaveLoginAttempt() error {
+	if len(a.UserAgent) > 500 {
+		a.UserAgent = a.UserAgent[:500]
+	}
+	err := database.DB.Create(&a).Error
+	return err
+}
+
+// SaveResult saves the result of the pending LoginAttempt.
+func (a *LoginAttempt) SaveResult(result string) error {
+	a.Result = result
+	a.Success = result == LoginSucceeded
+	err := database.DB.Model(&a).Select("user_id", "success", "result").Updates(a).Error
+	return err
+}
+
+// AccountFailures counts the failed LoginAttempts for the Email after since.
+// Logging in successfully starts the count over. When the LoginAttempt is
+// saved, only the ones saved before it are counted.
+func (a *LoginAttempt) AccountFailures(email string, since time.Time) (*LoginFailures, error) {
+	var success LoginAttempt
+	err := database.DB.Where("email = ? AND success = ? AND created_at > ?", email, true, since).
+		Where("id < ?", a.before()).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&success).Error
+	if err != nil {
+		return nil, err
+	}
+	if success.ID != 0 {
+		since = success.CreatedAt
+	}
+
+	return countLoginFailures("email = ? AND created_at > ? AND id < ?", email, since, a.before())
+}
+
+// IPFailures counts the failed LoginAttempts from the IP after since, for any
+// Email. When the LoginAttempt is saved, only the ones saved before it are
+// counted.
+func (a *LoginAttempt) IPFailures(ip string, since time.Time) (*LoginFailures, error) {
+	return countLoginFailures("ip = ? AND created_at > ? AND id < ?", ip, since, a.before())
+}
+
+// before is the ID the counted LoginAttempts are older than.
+func (a *LoginAttempt) before() uint {
+	if a.ID == 0 {
+		return math.MaxUint32
+	}
+	return a.ID
+}
+
+// IndexLoginAttempts fetches one page of the User's LoginAttempts, newest
+// first. Returns the page and the total count.
+func (a *LoginAttempt) IndexLoginAttempts(userID uint, page int, limit int) (*[]LoginAttempt, int64, error) {
+	var attempts []LoginAttempt
+	var total int64
+
+	if err := database.DB.Model(&LoginAttempt{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := database.DB.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&attempts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &attempts, total, nil
+}
+
+func countLoginFailures(where string, args ...interface{}) (*LoginFailures, error) {
+	failed := func() *gorm.DB {
+		return database.DB.Model(&LoginAttempt{}).
+			Where(where, args...).
+			Where("success = ? AND result IN ?", false, failedLoginResults)
+	}
+
+	var failures LoginFailures
+	if err := failed().Count(&failures.Count).Error; err != nil {
+		return nil, err
+	}
+	if failures.Count == 0 {
+		return &failures, nil
+	}
+
+	var last LoginAttempt
+	if err := failed().Order("created_at DESC").First(&last).Error; err != nil {
+		return nil, err
+	}
+	failures.Last = last.CreatedAt
+	return &failures, nil
+}
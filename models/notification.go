@@ -1,10 +1,146 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"issue-tracker/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification kinds.
+const (
+	NotificationReply    = "reply"
+	NotificationStatus   = "status"
+	NotificationSeverity = "severity"
+)
 
 // Notification belongs to User
+//
+// A Notification tells a User about a change on an Issue they reported, are
+// assigned to or replied on. ReadAt is nil while it is unread.
 type Notification struct {
 	gorm.Model
-	UserID int
-	Detail string `gorm:"size:300"`
+	UserID  int
+	IssueID uint   `gorm:"index"`
+	ActorID uint   `gorm:"index"`
+	Kind    string `gorm:"size:50"`
+	Detail  string `gorm:"size:300"`
+	ReadAt  *time.Time
+}
+
+// IndexNotifications fetches one page of a User's Notifications, unread first
+// and newest first. Returns the page and the total count of Notifications.
+func (n *Notification) IndexNotifications(userID int, page int, limit int) (*[]Notification, int64, error) {
+	var notifications []Notification
+	var total int64
+
+	err := database.DB.Model(&Notification{}).Where("user_id = ?", userID).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = database.DB.Where("user_id = ?", userID).
+		Order("read_at IS NOT NULL, created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &notifications, total, nil
+}
+
+// CountUnread counts a User's unread Notifications.
+func (n *Notification) CountUnread(userID int) (int64, error) {
+	var count int64
+	err := database.DB.Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// FindNotificationByID fetches a Notification by its ID.
+func (n *Notification) FindNotificationByID(id string) (*Notification, error) {
+	var result Notification
+	query := database.DB.Where("id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find notification with ID: %s", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// MarkRead marks the Notification as read. Marking it twice keeps the first
+// time it was read.
+func (n *Notification) MarkRead() error {
+	if n.ReadAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	err := database.DB.Model(&n).Update("read_at", now).Error
+	if err != nil {
+		return err
+	}
+	n.ReadAt = &now
+	return nil
+}
+
+// MarkAllRead marks every unread Notification of a User as read.
+// Returns how many Notifications were marked.
+func (n *Notification) MarkAllRead(userID int) (int64, error) {
+	query := database.DB.Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return query.RowsAffected, query.Error
+}
+
+// notifyIssueWatchers creates a Notification for every User watching the Issue,
+// except the User who made the change. Watchers are the reporter, the
+// assignees and every User who replied on the Issue.
+//
+// Must be called in the transaction which makes the change.
+func notifyIssueWatchers(tx *gorm.DB, issue *Issue, actor *User, kind string, detail string) error {
+	var watcherIDs []uint
+	err := tx.Raw(`
+		SELECT user_id FROM issue_assignees WHERE issue_id = ?
+		UNION
+		SELECT user_id FROM replies WHERE issue_id = ? AND deleted_at IS NULL`,
+		issue.ID, issue.ID).
+		Scan(&watcherIDs).Error
+	if err != nil {
+		return err
+	}
+	watcherIDs = append(watcherIDs, uint(issue.UserID))
+
+	if runes := []rune(detail); len(runes) > 300 {
+		detail = string(runes[:297]) + "..."
+	}
+
+	seen := map[uint]struct{}{actor.ID: {}}
+	var notifications []Notification
+	for _, id := range watcherIDs {
+		if _, ok := seen[id]; ok || id == 0 {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		notifications = append(notifications, Notification{
+			UserID:  int(id),
+			IssueID: issue.ID,
+			ActorID: actor.ID,
+			Kind:    kind,
+			Detail:  detail,
+		})
+	}
+
+	if len(notifications) == 0 {
+		return nil
+	}
+	return tx.Create(&notifications).Error
 }
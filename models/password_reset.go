@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"issue-tracker/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPasswordResetInvalid is returned for a password reset token which does
+// not exist, has expired or was used already.
+var ErrPasswordResetInvalid = errors.New("ERROR: password reset token is invalid or has expired")
+
+// PasswordResetToken belongs to User
+//
+// A PasswordResetToken is e-mailed to a User who forgot their password and can
+// be used once, until ExpiresAt. Only the SHA-256 of the token is stored.
+type PasswordResetToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// SavePasswordResetToken saves the PasswordResetToken. Tokens issued to the
+// User before can no longer be used.
+func (t *PasswordResetToken) SavePasswordResetToken() error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", t.UserID).
+			Update("expires_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&t).Error
+	})
+	return err
+}
+
+// RecentlyIssued checks whether a PasswordResetToken was issued to the User
+// after since.
+func (t *PasswordResetToken) RecentlyIssued(userID uint, since time.Time) (bool, error) {
+	var count int64
+	err := database.DB.Model(&PasswordResetToken{}).
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ResetPassword uses the PasswordResetToken with the given hash to set the
+// User's password. The token can not be used again, and every session of the
+// User is logged out.
+//
+// Returns ErrPasswordResetInvalid if the token can not be used.
+func (t *PasswordResetToken) ResetPassword(hash string, newPassword []byte) (*User, error) {
+	var token PasswordResetToken
+	if err := database.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, ErrPasswordResetInvalid
+	}
+
+	var user User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		query := tx.Model(&PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+			Update("used_at", now)
+		if query.Error != nil {
+			return query.Error
+		}
+		if query.RowsAffected != 1 {
+			return ErrPasswordResetInvalid
+		}
+
+		if err := tx.Where("id = ? AND deactivated_at IS NULL", token.UserID).First(&user).Error; err != nil {
+			return ErrPasswordResetInvalid
+		}
+
+		return revokeTokens(tx, &user, now, map[string]interface{}{
+			"password":            newPassword,
+			"must_reset_password": false,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.Password = newPassword
+	user.MustResetPassword = false
+	return &user, nil
+}
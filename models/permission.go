@@ -0,0 +1,215 @@
+package models
+
+import (
+	"fmt"
+	"issue-tracker/database"
+
+	"gorm.io/gorm"
+)
+
+// Permissions. An ".own" permission allows the action on what the User created
+// or owns, an ".any" permission allows it on everything.
+const (
+	PermIssueRead          = "issue.read"
+	PermIssueCreate        = "issue.create"
+	PermIssueEditOwn       = "issue.edit.own"
+	PermIssueEditAny       = "issue.edit.any"
+	PermIssueDeleteOwn     = "issue.delete.own"
+	PermIssueDeleteAny     = "issue.delete.any"
+	PermIssueTransition    = "issue.transition"
+	PermIssueTransitionAny = "issue.transition.any"
+	PermIssueAssignSelf    = "issue.assign.self"
+	PermIssueAssignAny     = "issue.assign.any"
+	PermIssueAssignable    = "issue.assignable"
+	PermIssueLabel         = "issue.label"
+	PermReplyCreate        = "reply.create"
+	PermReplyEditOwn       = "reply.edit.own"
+	PermReplyEditAny       = "reply.edit.any"
+	PermReplyDeleteOwn     = "reply.delete.own"
+	PermReplyDeleteAny     = "reply.delete.any"
+	PermAttachmentCreate   = "attachment.create"
+	PermAttachmentDelOwn   = "attachment.delete.own"
+	PermAttachmentDelAny   = "attachment.delete.any"
+	PermProjectCreate      = "project.create"
+	PermProjectEditOwn     = "project.edit.own"
+	PermProjectEditAny     = "project.edit.any"
+	PermProjectDeleteOwn   = "project.delete.own"
+	PermProjectDeleteAny   = "project.delete.any"
+	PermLabelManage        = "label.manage"
+	PermWebhookManage      = "webhook.manage"
+	PermNotificationRead   = "notification.read"
+	PermProfileEdit        = "profile.edit"
+	PermUserRead           = "user.read"
+	PermUserManage         = "user.manage"
+	PermUserInvite         = "user.invite"
+	PermRoleManage         = "role.manage"
+)
+
+// Permission allows the Users of a Role to do one kind of request.
+type Permission struct {
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"size:100;uniqueIndex"`
+	Description string `gorm:"size:300"`
+}
+
+// PermissionSet is a set of Permission names.
+type PermissionSet map[string]bool
+
+// Has checks whether the set contains at least one of the permissions.
+func (s PermissionSet) Has(permissions ...string) bool {
+	for _, permission := range permissions {
+		if s[permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// Restrict returns the permissions of the set which are also in allowed.
+func (s PermissionSet) Restrict(allowed PermissionSet) PermissionSet {
+	restricted := PermissionSet{}
+	for permission := range s {
+		if allowed[permission] {
+			restricted[permission] = true
+		}
+	}
+	return restricted
+}
+
+// DefaultPermissions are the built-in Permissions.
+var DefaultPermissions = []Permission{
+	{Name: PermIssueRead, Description: "See Issues, their history, attachments and the workflow."},
+	{Name: PermIssueCreate, Description: "Create Issues."},
+	{Name: PermIssueEditOwn, Description: "Edit Issues the User reported."},
+	{Name: PermIssueEditAny, Description: "Edit any Issue."},
+	{Name: PermIssueDeleteOwn, Description: "Delete Issues the User reported."},
+	{Name: PermIssueDeleteAny, Description: "Delete any Issue."},
+	{Name: PermIssueTransition, Description: "Move Issues along the workflow transitions of the User's Role."},
+	{Name: PermIssueTransitionAny, Description: "Move Issues to any Status, ignoring the workflow."},
+	{Name: PermIssueAssignSelf, Description: "Assign oneself to Issues."},
+	{Name: PermIssueAssignAny, Description: "Assign anyone who is assignable to Issues."},
+	{Name: PermIssueAssignable, Description: "Be assigned to Issues."},
+	{Name: PermIssueLabel, Description: "Add and remove Labels of Issues."},
+	{Name: PermReplyCreate, Description: "Reply on Issues."},
+	{Name: PermReplyEditOwn, Description: "Edit the User's own Replies."},
+	{Name: PermReplyEditAny, Description: "Edit any Reply."},
+	{Name: PermReplyDeleteOwn, Description: "Delete the User's own Replies."},
+	{Name: PermReplyDeleteAny, Description: "Delete any Reply."},
+	{Name: PermAttachmentCreate, Description: "Attach files to Issues and Replies."},
+	{Name: PermAttachmentDelOwn, Description: "Delete files the User attached."},
+	{Name: PermAttachmentDelAny, Description: "Delete any attached file."},
+	{Name: PermProjectCreate, Description: "Create Projects."},
+	{Name: PermProjectEditOwn, Description: "Edit the Projects the User owns and their Labels."},
+	{Name: PermProjectEditAny, Description: "Edit any Project and its Labels."},
+	{Name: PermProjectDeleteOwn, Description: "Delete the Projects the User owns."},
+	{Name: PermProjectDeleteAny, Description: "Delete any Project."},
+	{Name: PermLabelManage, Description: "Manage global Labels."},
+	{Name: PermWebhookManage, Description: "Register and manage the User's own Webhooks."},
+	{Name: PermNotificationRead, Description: "Read the User's own Notifications."},
+	{Name: PermProfileEdit, Description: "Change the User's own password and end their own sessions."},
+	{Name: PermUserRead, Description: "See other Users."},
+	{Name: PermUserManage, Description: "List Users, change their Role, deactivate them and force password resets."},
+	{Name: PermUserInvite, Description: "Invite people to register, with a Role chosen for them."},
+	{Name: PermRoleManage, Description: "Create Roles and change their Permissions."},
+}
+
+// allPermissionNames returns the names of every built-in Permission.
+func allPermissionNames() []string {
+	names := make([]string, len(DefaultPermissions))
+	for i, permission := range DefaultPermissions {
+		names[i] = permission.Name
+	}
+	return names
+}
+
+// DefaultRolePermissions are the Permissions the built-in Roles start with.
+var DefaultRolePermissions = map[uint][]string{
+	RoleQA: {
+		PermIssueRead, PermIssueCreate, PermIssueEditOwn, PermIssueDeleteOwn,
+		PermIssueTransition, PermIssueAssignAny, PermIssueLabel,
+		PermReplyCreate, PermReplyEditOwn, PermReplyDeleteOwn,
+		PermAttachmentCreate, PermAttachmentDelOwn,
+		PermProjectCreate, PermProjectEditOwn, PermProjectDeleteOwn,
+		PermLabelManage, PermWebhookManage, PermNotificationRead, PermProfileEdit, PermUserRead,
+	},
+	RoleDeveloper: {
+		PermIssueRead, PermIssueEditAny,
+		PermIssueTransition, PermIssueAssignSelf, PermIssueAssignable, PermIssueLabel,
+		PermReplyCreate, PermReplyEditOwn, PermReplyDeleteOwn,
+		PermAttachmentCreate, PermAttachmentDelOwn,
+		PermProjectCreate, PermProjectEditOwn, PermProjectDeleteOwn,
+		PermNotificationRead, PermProfileEdit, PermUserRead,
+	},
+	RoleAdmin: allPermissionNames(),
+	RoleProductManager: {
+		PermIssueRead, PermIssueCreate, PermIssueEditAny,
+		PermIssueTransitionAny, PermIssueAssignAny, PermIssueLabel,
+		PermReplyCreate, PermReplyEditOwn, PermReplyDeleteOwn,
+		PermAttachmentCreate, PermAttachmentDelOwn,
+		PermProjectCreate, PermProjectEditOwn, PermProjectEditAny, PermProjectDeleteOwn,
+		PermLabelManage, PermWebhookManage, PermNotificationRead, PermProfileEdit, PermUserRead,
+	},
+	RoleViewer: {
+		PermIssueRead, PermNotificationRead, PermProfileEdit, PermUserRead,
+	},
+}
+
+// IndexPermissions fetches every Permission.
+func (p *Permission) IndexPermissions() (*[]Permission, error) {
+	var permissions []Permission
+	if err := database.DB.Order("name").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return &permissions, nil
+}
+
+// FindPermissionsByName fetches the Permissions with the given names.
+// Returns an error if one of them does not exist.
+func (p *Permission) FindPermissionsByName(names []string) ([]Permission, error) {
+	var permissions []Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+
+	if err := database.DB.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	found := PermissionSet{}
+	for _, permission := range permissions {
+		found[permission.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, fmt.Errorf("ERROR PERMISSIONS: unknown permission %s", name)
+		}
+	}
+	return permissions, nil
+}
+
+// FindUserPermissions fetches the Permissions of a User's Role.
+// A User who does not exist or is deactivated has no Permissions, and a User
+// who must reset their password only has profile.edit.
+func (p *Permission) FindUserPermissions(userID int) (PermissionSet, error) {
+	return findUserPermissions(database.DB, uint(userID))
+}
+
+func findUserPermissions(db *gorm.DB, userID uint) (PermissionSet, error) {
+	var names []string
+	err := db.Model(&Permission{}).
+		Select("permissions.name").
+		Joins("join role_permissions on role_permissions.permission_id = permissions.id").
+		Joins("join users on users.role_id = role_permissions.role_id").
+		Where("users.id = ? AND users.deleted_at IS NULL AND users.deactivated_at IS NULL", userID).
+		Where("users.must_reset_password = ? OR permissions.name = ?", false, PermProfileEdit).
+		Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := PermissionSet{}
+	for _, name := range names {
+		permissions[name] = true
+	}
+	return permissions, nil
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/database"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPersonalTokenInvalid is returned for a personal access token which does
+// not exist, has expired or was revoked.
+var ErrPersonalTokenInvalid = errors.New("ERROR: personal access token is invalid or has expired")
+
+// personalTokenUseInterval is how often LastUsedAt is updated, so every
+// request does not write to the database.
+const personalTokenUseInterval = time.Minute
+
+// TokenScopes are the scopes of personal access tokens and the Permissions
+// each one allows. A token can only use the Permissions of its scopes which
+// the User's Role has. Managing the User's own profile, Users and Roles is
+// never allowed with a token.
+var TokenScopes = map[string][]string{
+	"issues:read": {PermIssueRead},
+	"issues:write": {
+		PermIssueRead, PermIssueCreate, PermIssueEditOwn, PermIssueEditAny,
+		PermIssueDeleteOwn, PermIssueDeleteAny, PermIssueTransition, PermIssueTransitionAny,
+		PermIssueAssignSelf, PermIssueAssignAny, PermIssueLabel,
+		PermReplyCreate, PermReplyEditOwn, PermReplyEditAny, PermReplyDeleteOwn, PermReplyDeleteAny,
+		PermAttachmentCreate, PermAttachmentDelOwn, PermAttachmentDelAny,
+	},
+	"projects:write": {
+		PermIssueRead, PermProjectCreate, PermProjectEditOwn, PermProjectEditAny,
+		PermProjectDeleteOwn, PermProjectDeleteAny, PermLabelManage,
+	},
+	"notifications:read": {PermNotificationRead},
+	"users:read":         {PermUserRead},
+}
+
+// TokenScopeNames returns the names of the TokenScopes, sorted.
+func TokenScopeNames() []string {
+	names := make([]string, 0, len(TokenScopes))
+	for scope := range TokenScopes {
+		names = append(names, scope)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScopePermissions returns the Permissions the scopes allow.
+func ScopePermissions(scopes []string) PermissionSet {
+	permissions := PermissionSet{}
+	for _, scope := range scopes {
+		for _, permission := range TokenScopes[scope] {
+			permissions[permission] = true
+		}
+	}
+	return permissions
+}
+
+// ParseTokenScopes parses a comma separated list of scopes, like
+// "issues:read,issues:write". Returns the scopes sorted and without duplicates.
+func ParseTokenScopes(list string) ([]string, error) {
+	found := map[string]bool{}
+	for _, scope := range strings.Split(list, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if _, ok := TokenScopes[scope]; !ok {
+			return nil, fmt.Errorf("ERROR SCOPES: unknown scope %s", scope)
+		}
+		found[scope] = true
+	}
+	if len(found) == 0 {
+		return nil, errors.New("ERROR SCOPES: at least one scope is required")
+	}
+
+	scopes := make([]string, 0, len(found))
+	for scope := range found {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes, nil
+}
+
+// PersonalAccessToken belongs to User
+//
+// A PersonalAccessToken lets scripts and CI use the API as the User, limited to
+// its Scopes (comma separated). Only the SHA-256 of the token is stored, and
+// Prefix is the start of the token to recognize it by. Without ExpiresAt the
+// token does not expire. Deleting a PersonalAccessToken revokes it.
+type PersonalAccessToken struct {
+	gorm.Model
+	UserID     uint   `gorm:"index"`
+	Name       string `gorm:"size:100"`
+	Scopes     string `gorm:"size:300"`
+	Prefix     string `gorm:"size:20"`
+	TokenHash  string `gorm:"size:64;uniqueIndex" json:"-"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// ScopeList returns the Scopes of the PersonalAccessToken.
+func (t *PersonalAccessToken) ScopeList() []string {
+	return strings.Split(t.Scopes, ",")
+}
+
+// SavePersonalAccessToken saves the PersonalAccessToken.
+func (t *PersonalAccessToken) SavePersonalAccessToken() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" || len(t.Name) > 100 {
+		return errors.New("ERROR NAME: name must be between 1 and 100 characters")
+	}
+	err := database.DB.Create(&t).Error
+	return err
+}
+
+// IndexPersonalAccessTokens fetches the User's PersonalAccessTokens which were
+// not revoked, newest first.
+func (t *PersonalAccessToken) IndexPersonalAccessTokens(userID uint) (*[]PersonalAccessToken, error) {
+	var tokens []PersonalAccessToken
+	err := database.DB.Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tokens, nil
+}
+
+// FindPersonalAccessTokenByID fetches a PersonalAccessToken by its ID.
+func (t *PersonalAccessToken) FindPersonalAccessTokenByID(id string) (*PersonalAccessToken, error) {
+	var result PersonalAccessToken
+	query := database.DB.Where("id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find personal access token with ID: %s", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// FindActivePersonalAccessToken fetches the PersonalAccessToken with the given
+// hash, if it has not expired or been revoked.
+func (t *PersonalAccessToken) FindActivePersonalAccessToken(hash string) (*PersonalAccessToken, error) {
+	var result PersonalAccessToken
+	err := database.DB.
+		Where("token_hash = ? AND (expires_at IS NULL OR expires_at > ?)", hash, time.Now()).
+		First(&result).Error
+	if err != nil {
+		return nil, ErrPersonalTokenInvalid
+	}
+	return &result, nil
+}
+
+// Touch records that the PersonalAccessToken was used now. LastUsedAt is only
+// updated once per personalTokenUseInterval.
+func (t *PersonalAccessToken) Touch(now time.Time) error {
+	if t.LastUsedAt != nil && now.Sub(*t.LastUsedAt) < personalTokenUseInterval {
+		return nil
+	}
+	err := database.DB.Model(&PersonalAccessToken{}).Where("id = ?", t.ID).Update("last_used_at", now).Error
+	if err != nil {
+		return err
+	}
+	t.LastUsedAt = &now
+	return nil
+}
+
+// DeletePersonalAccessToken revokes the PersonalAccessToken.
+func (t *PersonalAccessToken) DeletePersonalAccessToken() error {
+	err := database.DB.Delete(&t).Error
+	return err
+}
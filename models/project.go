@@ -0,0 +1,182 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/database"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Project groups Issues together. Every Issue belongs to exactly one Project.
+//
+// Key is the short uppercase prefix used for the human-readable Issue keys,
+// for example "WEB" for "WEB-42". IssueCounter holds the last number that was
+// handed out inside the Project.
+type Project struct {
+	gorm.Model
+	Name         string `gorm:"size:100"`
+	Key          string `gorm:"size:10;unique"`
+	Description  string `gorm:"size:2000"`
+	OwnerID      int
+	Owner        User `json:"-"`
+	IssueCounter int
+	Issues       []Issue `json:"-"`
+}
+
+// ProjectIndex is used for IndexProjects operation.
+type ProjectIndex struct {
+	ID          int
+	Name        string
+	Key         string
+	Description string
+	OwnerID     int
+	OwnerName   string
+	IssueCount  int
+}
+
+var projectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+
+// ValidateProject validates the Project data.
+// Key must start with a letter and only contain uppercase letters and digits,
+// 2 - 10 characters long.
+func (p *Project) ValidateProject() error {
+	p.Key = strings.ToUpper(strings.TrimSpace(p.Key))
+	if !projectKeyPattern.MatchString(p.Key) {
+		return errors.New("ERROR KEY: key must be 2 - 10 uppercase letters or digits and start with a letter")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("ERROR NAME: name must not be empty")
+	}
+	return nil
+}
+
+// SaveProject saves the Project to the database.
+//
+// The Key of a deleted Project can not be used again, since the Issues of the
+// deleted Project keep their keys.
+func (p *Project) SaveProject() error {
+	return saveProject(database.DB, p)
+}
+
+func saveProject(db *gorm.DB, p *Project) error {
+	var existing Project
+	err := db.Unscoped().Where("projects.key = ?", p.Key).Limit(1).Find(&existing).Error
+	if err != nil {
+		return err
+	}
+	if existing.ID != 0 && existing.DeletedAt.Valid {
+		return fmt.Errorf("ERROR KEY: key %s was used by a deleted project", p.Key)
+	}
+	if existing.ID != 0 {
+		return fmt.Errorf("ERROR KEY: key %s is already used by another project", p.Key)
+	}
+
+	err = db.Create(p).Error
+	return err
+}
+
+// IndexProjects fetches all projects with their owner's name and issue count.
+func (p *Project) IndexProjects() (*[]ProjectIndex, error) {
+	var projects []ProjectIndex
+	query := database.DB.Model(&Project{}).
+		Select(`
+			projects.id,
+			projects.name,
+			projects.key,
+			projects.description,
+			projects.owner_id,
+			users.name AS owner_name,
+			(SELECT COUNT(*) FROM issues
+				WHERE issues.project_id = projects.id AND issues.deleted_at IS NULL) AS issue_count`).
+		Joins("left join users on projects.owner_id = users.id").
+		Order("projects.key").
+		Scan(&projects)
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+
+	return &projects, nil
+}
+
+// FindProjectByKey fetches a Project by its Key. The Key is case insensitive.
+func (p *Project) FindProjectByKey(key string) (*Project, error) {
+	return findProjectByKey(database.DB, key)
+}
+
+func findProjectByKey(db *gorm.DB, key string) (*Project, error) {
+	var result Project
+	query := db.Where("projects.key = ?", strings.ToUpper(key)).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find project with key: %s", key)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// FindProjectByID fetches a Project by its ID.
+func (p *Project) FindProjectByID(id uint) (*Project, error) {
+	var result Project
+	query := database.DB.Where("id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find project with ID: %d", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// UpdateProject updates a Project's name and description.
+// Takes an origin Project as parameter. The Key can not be changed because
+// it is part of every Issue key in the Project.
+func (p *Project) UpdateProject(origin *Project) error {
+	err := database.DB.Model(&origin).Select("name", "description").Updates(p).Error
+	return err
+}
+
+// DeleteProject deletes a Project. Projects which still have Issues can not be
+// deleted.
+func (p *Project) DeleteProject() error {
+	var count int64
+	err := database.DB.Model(&Issue{}).Where("project_id = ?", p.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("ERROR: project %s still has %d issue(s)", p.Key, count)
+	}
+
+	err = database.DB.Delete(&p).Error
+	return err
+}
+
+// nextIssueNumber increments the Project's IssueCounter and returns the new
+// value. Must be called inside a transaction: the UPDATE locks the Project's row
+// until the transaction ends, so two Issues can never receive the same number.
+func nextIssueNumber(tx *gorm.DB, projectID uint) (int, error) {
+	query := tx.Model(&Project{}).
+		Where("id = ?", projectID).
+		UpdateColumn("issue_counter", gorm.Expr("issue_counter + 1"))
+	if query.Error != nil {
+		return 0, query.Error
+	}
+	if query.RowsAffected == 0 {
+		return 0, fmt.Errorf("ERROR: could not find project with ID: %d", projectID)
+	}
+
+	var project Project
+	if err := tx.Select("issue_counter").Where("id = ?", projectID).First(&project).Error; err != nil {
+		return 0, err
+	}
+	return project.IssueCounter, nil
+}
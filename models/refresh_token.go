@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token which was already
+// exchanged is used again. The whole session is revoked when it happens, since
+// either the client or someone who stole the token is using an old copy.
+var ErrRefreshTokenReused = errors.New("ERROR: refresh token has already been used")
+
+// RefreshToken belongs to User
+//
+// A RefreshToken is exchanged for a new access token and a new RefreshToken on
+// refresh. Every RefreshToken issued from one login shares the SessionID, so a
+// session can be logged out at once. Only the SHA-256 of the token is stored.
+type RefreshToken struct {
+	ID           uint   `gorm:"primarykey"`
+	UserID       uint   `gorm:"index"`
+	SessionID    string `gorm:"size:64;index"`
+	TokenHash    string `gorm:"size:64;uniqueIndex"`
+	Remember     bool
+	ExpiresAt    time.Time
+	RevokedAt    *time.Time
+	ReplacedByID *uint
+	CreatedAt    time.Time
+}
+
+// SaveRefreshToken saves the RefreshToken to the database.
+func (t *RefreshToken) SaveRefreshToken() error {
+	err := database.DB.Create(&t).Error
+	return err
+}
+
+// FindRefreshTokenByHash fetches a RefreshToken by the hash of the token.
+func (t *RefreshToken) FindRefreshTokenByHash(hash string) (*RefreshToken, error) {
+	var result RefreshToken
+	query := database.DB.Where("token_hash = ?", hash).First(&result)
+
+	if result.ID == 0 {
+		return nil, errors.New("ERROR: invalid refresh token")
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// Rotate revokes the RefreshToken and saves next, which continues the same
+// session, in its place.
+//
+// Returns ErrRefreshTokenReused if the RefreshToken was revoked already, also
+// when a concurrent Rotate got to it first.
+func (t *RefreshToken) Rotate(next *RefreshToken) error {
+	next.UserID = t.UserID
+	next.SessionID = t.SessionID
+	next.Remember = t.Remember
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		query := tx.Model(&RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", t.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":     time.Now(),
+				"replaced_by_id": next.ID,
+			})
+		if query.Error != nil {
+			return query.Error
+		}
+		if query.RowsAffected != 1 {
+			return ErrRefreshTokenReused
+		}
+		return nil
+	})
+	return err
+}
+
+// Active checks whether the RefreshToken can still be exchanged.
+func (t *RefreshToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// SessionActive checks whether a session has a RefreshToken which is neither
+// revoked nor expired, meaning it is not logged out.
+func (t *RefreshToken) SessionActive(sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+
+	var count int64
+	err := database.DB.Model(&RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeSession revokes every RefreshToken of a User's session.
+func (t *RefreshToken) RevokeSession(userID uint, sessionID string) error {
+	err := revokeRefreshTokens(database.DB.Where("user_id = ? AND session_id = ?", userID, sessionID))
+	return err
+}
+
+// revokeRefreshTokens revokes the unrevoked RefreshTokens matched by query.
+func revokeRefreshTokens(query *gorm.DB) error {
+	err := query.Model(&RefreshToken{}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("ERROR: could not revoke refresh tokens: %s", err)
+	}
+	return nil
+}
@@ -14,32 +14,11 @@ type Reply struct {
 	Body    string `gorm:"size:2000"`
 }
 
-// SaveReply saves Reply record to database.
-func (r *Reply) SaveReply() error {
-	err := database.DB.Create(&r).Error
-	return err
-}
-
 // FindReplyByID gets a Reply by searching the ID.
 func (r *Reply) FindReplyByID(id uint) *Reply {
-	var result Reply
-
-	err := database.DB.Where("id = ?", id).First(&result).Error
+	result, err := NewGormStore(database.DB).Replies().FindByID(id)
 	if err != nil {
 		return nil
 	}
-
-	return &result
-}
-
-// UpdateReply updates a source Reply.
-func (r *Reply) UpdateReply(source *Reply) error {
-	err := database.DB.Model(&source).Updates(r).Error
-	return err
-}
-
-// DeleteReply deletes a Reply.
-func (r *Reply) DeleteReply() error {
-	err := database.DB.Delete(&r).Error
-	return err
+	return result
 }
@@ -0,0 +1,730 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IssueRepository stores Issues.
+type IssueRepository interface {
+	// Create saves the Issue in the Project, with the next Number of the
+	// Project.
+	Create(issue *Issue, project *Project) error
+	// FindByID fetches an Issue with its Status.
+	FindByID(id uint) (*Issue, error)
+	// FindByProjectNumber fetches an Issue with its Status by its Number in the
+	// Project.
+	FindByProjectNumber(projectID uint, number int) (*Issue, error)
+	// Index fetches one page of Issues matching the filter, with their Labels.
+	// Returns the page and the total count.
+	Index(filter IssueFilter) (*[]IssueIndex, int64, error)
+	// Search searches the title and body of the Issues and the body of their
+	// Replies. Every Issue is returned once, ranked by its best match. If
+	// projectID is not 0, only the Issues of that Project are searched.
+	Search(q string, projectID uint, limit int) (*[]IssueSearchResult, error)
+	// Show fetches what the Show route displays of the Issue and its Replies.
+	Show(issue *Issue) (*IssueShow, *[]RepliesInIssue, error)
+	// History fetches the IssueEvents of the Issue, oldest first.
+	History(issue *Issue) (*[]IssueEvent, error)
+	// Update saves the non-zero fields of changes to origin.
+	Update(origin *Issue, changes *Issue) error
+	// Transition moves the Issue to the Status. Whether the move is allowed
+	// must be checked with StatusRepository.IsTransitionAllowed first.
+	Transition(issue *Issue, status *Status, by *User) error
+	// Assignees fetches the Users assigned to the Issue, by name.
+	Assignees(issue *Issue) (*[]IssueAssignee, error)
+	IsAssigned(issue *Issue, userID uint) (bool, error)
+	// Assign assigns the User to the Issue. Assigning a User twice does
+	// nothing.
+	Assign(issue *Issue, user *User, by *User) error
+	Unassign(issue *Issue, user *User, by *User) error
+	// Labels fetches the Labels of the Issue, by name.
+	Labels(issue *Issue) ([]IssueLabel, error)
+	// Attachments fetches the Attachments of the Issue and of its Replies,
+	// oldest first.
+	Attachments(issue *Issue) (*[]Attachment, error)
+	Delete(issue *Issue) error
+}
+
+// UserRepository stores Users.
+type UserRepository interface {
+	Create(user *User) error
+	FindByID(id uint) (*User, error)
+	FindByEmail(email string) (*User, error)
+	// Index fetches one page of Users matching the filter, ordered by ID.
+	// Returns the page and the total count.
+	Index(filter UserFilter) (*[]UserIndex, int64, error)
+	// Permissions fetches the Permissions of the User's Role, see
+	// Permission.FindUserPermissions.
+	Permissions(user *User) (PermissionSet, error)
+	// UpdatePassword sets the User's password. A password reset forced on the
+	// User is done with it.
+	UpdatePassword(user *User, password []byte) error
+	// SetPassword replaces the User's password and revokes their tokens. With
+	// temporary, the User has to change it after logging in before doing
+	// anything else.
+	SetPassword(user *User, password []byte, temporary bool) error
+	// LogoutEverywhere revokes the User's tokens, see User.LogoutEverywhere.
+	LogoutEverywhere(user *User) error
+	// ChangeRole gives the User the Role with roleID.
+	ChangeRole(user *User, roleID int) error
+	// Deactivate blocks the User from logging in and revokes their tokens.
+	Deactivate(user *User) error
+	// Reactivate allows a deactivated User to log in again. Tokens revoked on
+	// deactivation stay revoked.
+	Reactivate(user *User) error
+	// ForcePasswordReset revokes the User's tokens. After logging in again the
+	// User has to change their password before doing anything else.
+	ForcePasswordReset(user *User) error
+	// ResetTwoFactor disables 2FA for the User, deletes their recovery codes
+	// and revokes their tokens.
+	ResetTwoFactor(user *User) error
+}
+
+// ReplyRepository stores Replies.
+type ReplyRepository interface {
+	// Create saves the Reply on the Issue, written by the User.
+	Create(reply *Reply, issue *Issue, by *User) error
+	FindByID(id uint) (*Reply, error)
+	// Update saves the non-zero fields of changes to source.
+	Update(source *Reply, changes *Reply) error
+	Delete(reply *Reply) error
+}
+
+// ProjectRepository stores Projects.
+type ProjectRepository interface {
+	// Create saves the Project. Its Key must not be used by another Project,
+	// even a deleted one.
+	Create(project *Project) error
+	// FindByKey fetches a Project by its Key. The Key is case insensitive.
+	FindByKey(key string) (*Project, error)
+}
+
+// StatusRepository reads the Issue workflow.
+type StatusRepository interface {
+	FindByID(id uint) (*Status, error)
+	// IsTransitionAllowed checks whether a User with roleID may move an Issue
+	// from one Status to another.
+	IsTransitionAllowed(from uint, to uint, roleID int) (bool, error)
+}
+
+// Store gives the repositories of one storage.
+//
+// Transaction runs fn with repositories whose changes are kept together: all
+// of them when fn returns nil, none of them when it returns an error.
+type Store interface {
+	Issues() IssueRepository
+	Users() UserRepository
+	Replies() ReplyRepository
+	Projects() ProjectRepository
+	Statuses() StatusRepository
+	Transaction(fn func(Store) error) error
+}
+
+// gormStore is the Store of a GORM database.
+//
+// Besides the records themselves, it keeps what the database records about a
+// change: the Issue's history and the notifications of its watchers.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore returns the Store of the GORM database.
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Issues() IssueRepository {
+	return &gormIssueRepository{db: s.db}
+}
+
+func (s *gormStore) Users() UserRepository {
+	return &gormUserRepository{db: s.db}
+}
+
+func (s *gormStore) Replies() ReplyRepository {
+	return &gormReplyRepository{db: s.db}
+}
+
+func (s *gormStore) Projects() ProjectRepository {
+	return &gormProjectRepository{db: s.db}
+}
+
+func (s *gormStore) Statuses() StatusRepository {
+	return &gormStatusRepository{db: s.db}
+}
+
+func (s *gormStore) Transaction(fn func(Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+type gormIssueRepository struct {
+	db *gorm.DB
+}
+
+// Create takes the Issue's Number and Key from the Project's counter in the
+// same transaction, so concurrent saves in one Project never share a Key.
+func (r *gormIssueRepository) Create(issue *Issue, project *Project) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		number, err := nextIssueNumber(tx, project.ID)
+		if err != nil {
+			return err
+		}
+
+		issue.ProjectID = project.ID
+		issue.Number = number
+		issue.Key = fmt.Sprintf("%s-%d", project.Key, number)
+		return tx.Create(issue).Error
+	})
+	return err
+}
+
+func (r *gormIssueRepository) FindByID(id uint) (*Issue, error) {
+	var result Issue
+	query := r.db.Preload("Replies").Preload("Status").Where("issues.id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: Could not find issue with ID: %d", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+func (r *gormIssueRepository) FindByProjectNumber(projectID uint, number int) (*Issue, error) {
+	var result Issue
+	query := r.db.Preload("Replies").Preload("Status").
+		Where("issues.project_id = ? AND issues.number = ?", projectID, number).
+		First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: Could not find issue with number: %d", number)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+func (r *gormIssueRepository) Index(filter IssueFilter) (*[]IssueIndex, int64, error) {
+	var issues []IssueIndex
+	var total int64
+
+	// GORM statements can't be reused after Count, so the filtered query is
+	// built twice.
+	filtered := func() *gorm.DB {
+		return filter.apply(r.db.Model(&Issue{}).
+			Joins("left join users on issues.user_id = users.id").
+			Joins("left join statuses on issues.status_id = statuses.id"))
+	}
+
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := filtered().
+		Select(`
+			issues.id,
+			issues.project_id,
+			issues.key,
+			issues.title,
+			issues.status_id,
+			statuses.name AS status_name,
+			issues.severity,
+			issues.created_at,
+			issues.updated_at,
+			issues.user_id,
+			users.name AS user_name,
+			issues.updated_by_user_id,
+			issues.updated_by_user_name`).
+		Order(filter.orderBy()).
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Scan(&issues)
+
+	if query.Error != nil {
+		return nil, 0, query.Error
+	}
+
+	if err := r.attachIndexLabels(issues); err != nil {
+		return nil, 0, err
+	}
+
+	return &issues, total, nil
+}
+
+// attachIndexLabels fetches the Labels of a page of issues.
+func (r *gormIssueRepository) attachIndexLabels(issues []IssueIndex) error {
+	if len(issues) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(issues))
+	for idx, issue := range issues {
+		ids[idx] = issue.ID
+	}
+	labels, err := findIssueLabels(r.db, ids)
+	if err != nil {
+		return err
+	}
+
+	byIssue := map[int][]IssueLabel{}
+	for _, label := range labels {
+		byIssue[label.IssueID] = append(byIssue[label.IssueID], label)
+	}
+	for idx := range issues {
+		issues[idx].Labels = byIssue[issues[idx].ID]
+		if issues[idx].Labels == nil {
+			issues[idx].Labels = []IssueLabel{}
+		}
+	}
+	return nil
+}
+
+// Search uses the full-text search of PostgreSQL, and the fallback index of
+// package search on other databases.
+func (r *gormIssueRepository) Search(q string, projectID uint, limit int) (*[]IssueSearchResult, error) {
+	return searchIssues(r.db, q, projectID, limit)
+}
+
+func (r *gormIssueRepository) Show(issue *Issue) (*IssueShow, *[]RepliesInIssue, error) {
+	var result IssueShow
+	query := r.db.Model(&Issue{}).
+		Select(`
+			issues.id,
+			issues.project_id,
+			issues.key,
+			issues.title,
+			issues.body,
+			issues.status_id,
+			statuses.name AS status_name,
+			issues.severity,
+			issues.created_at,
+			issues.updated_at,
+			issues.user_id,
+			users.name AS user_name`).
+		Joins("left join users on issues.user_id = users.id").
+		Joins("left join statuses on issues.status_id = statuses.id").
+		Where("issues.id = ?", issue.ID).
+		First(&result)
+
+	var replies []RepliesInIssue
+	queryReplies := r.db.Model(&Reply{}).
+		Select(`
+			replies.id,
+			replies.user_id,
+			replies.issue_id,
+			users.name AS replier,
+			replies.body,
+			replies.created_at,
+			replies.updated_at`).
+		Joins("join users on replies.user_id = users.id").
+		Joins("join issues on replies.issue_id = issues.id").
+		Where("replies.issue_id = ?", issue.ID).
+		Scan(&replies)
+
+	if result.ID == 0 {
+		return nil, nil, fmt.Errorf("ERROR: could not find issue with ID: %d", issue.ID)
+	}
+
+	if query.Error != nil {
+		return nil, nil, query.Error
+	}
+	if queryReplies.Error != nil {
+		return nil, nil, queryReplies.Error
+	}
+	return &result, &replies, nil
+}
+
+func (r *gormIssueRepository) History(issue *Issue) (*[]IssueEvent, error) {
+	var events []IssueEvent
+	err := r.db.Where("issue_id = ?", issue.ID).Order("created_at, id").Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return &events, nil
+}
+
+// Update records every changed field as an IssueEvent in the same
+// transaction, and notifies the Issue's watchers of a severity change.
+func (r *gormIssueRepository) Update(origin *Issue, changes *Issue) error {
+	events := diffIssue(origin, changes)
+	actor := &User{Name: changes.UpdatedByUserName}
+	actor.ID = uint(changes.UpdatedByUserID)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(origin).Updates(changes).Error; err != nil {
+			return err
+		}
+		if err := recordIssueEvents(tx, events...); err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if event.Field != "severity" {
+				continue
+			}
+			detail := fmt.Sprintf("%s changed the severity of %s from %s to %s",
+				actor.Name, origin.Key, event.OldValue, event.NewValue)
+			if err := notifyIssueWatchers(tx, origin, actor, NotificationSeverity, detail); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// Transition records the change as an IssueEvent and notifies the Issue's
+// watchers in the same transaction.
+func (r *gormIssueRepository) Transition(issue *Issue, status *Status, by *User) error {
+	event := newIssueEvent(issue, by, "status", issue.Status.Name, status.Name)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(issue).Updates(map[string]interface{}{
+			"status_id":            status.ID,
+			"updated_by_user_id":   by.ID,
+			"updated_by_user_name": by.Name,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := recordIssueEvents(tx, event); err != nil {
+			return err
+		}
+
+		detail := fmt.Sprintf("%s moved %s from %s to %s", by.Name, issue.Key, event.OldValue, event.NewValue)
+		return notifyIssueWatchers(tx, issue, by, NotificationStatus, detail)
+	})
+	if err != nil {
+		return err
+	}
+
+	issue.StatusID = status.ID
+	issue.Status = *status
+	return nil
+}
+
+func (r *gormIssueRepository) Assignees(issue *Issue) (*[]IssueAssignee, error) {
+	var assignees []IssueAssignee
+	query := r.db.Model(&User{}).
+		Select("users.id, users.name, users.email, users.role_id").
+		Joins("join issue_assignees on issue_assignees.user_id = users.id").
+		Where("issue_assignees.issue_id = ?", issue.ID).
+		Order("users.name").
+		Scan(&assignees)
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &assignees, nil
+}
+
+func (r *gormIssueRepository) IsAssigned(issue *Issue, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Table("issue_assignees").
+		Where("issue_id = ? AND user_id = ?", issue.ID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Assign records the change as an IssueEvent in the same transaction.
+func (r *gormIssueRepository) Assign(issue *Issue, user *User, by *User) error {
+	assigned, err := r.IsAssigned(issue, user.ID)
+	if err != nil || assigned {
+		return err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(issue).Association("Assignees").Append(user); err != nil {
+			return err
+		}
+		return recordIssueEvents(tx, newIssueEvent(issue, by, "assignee", "", user.Name))
+	})
+	return err
+}
+
+// Unassign records the change as an IssueEvent in the same transaction.
+func (r *gormIssueRepository) Unassign(issue *Issue, user *User, by *User) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(issue).Association("Assignees").Delete(user); err != nil {
+			return err
+		}
+		return recordIssueEvents(tx, newIssueEvent(issue, by, "assignee", user.Name, ""))
+	})
+	return err
+}
+
+func (r *gormIssueRepository) Labels(issue *Issue) ([]IssueLabel, error) {
+	return findIssueLabels(r.db, []int{int(issue.ID)})
+}
+
+func (r *gormIssueRepository) Attachments(issue *Issue) (*[]Attachment, error) {
+	return indexAttachments(r.db, issue.ID)
+}
+
+func (r *gormIssueRepository) Delete(issue *Issue) error {
+	return r.db.Delete(issue).Error
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) Create(user *User) error {
+	return r.db.Create(user).Error
+}
+
+// FindByID fetches the User with their Role, Issues and Replies.
+func (r *gormUserRepository) FindByID(id uint) (*User, error) {
+	var result User
+	err := r.db.Joins("Role").Preload("Issues").Preload("Replies").Where("users.id = ?", id).First(&result).Error
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: could not find user with ID: %d", id)
+	}
+	return &result, nil
+}
+
+func (r *gormUserRepository) FindByEmail(email string) (*User, error) {
+	var result User
+	err := r.db.Where(map[string]interface{}{
+		"email": email,
+	}).First(&result).Error
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: could not find user with email: %s", email)
+	}
+	return &result, nil
+}
+
+func (r *gormUserRepository) Index(filter UserFilter) (*[]UserIndex, int64, error) {
+	var users []UserIndex
+	var total int64
+
+	filtered := func() *gorm.DB {
+		query := r.db.Model(&User{}).Where("users.deleted_at IS NULL")
+		if filter.RoleID != 0 {
+			query = query.Where("users.role_id = ?", filter.RoleID)
+		}
+		if filter.Active != nil {
+			if *filter.Active {
+				query = query.Where("users.deactivated_at IS NULL")
+			} else {
+				query = query.Where("users.deactivated_at IS NOT NULL")
+			}
+		}
+		return query
+	}
+
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := filtered().
+		Select("users.id, users.name, users.email, users.role_id, roles.name AS role_name, " +
+			"users.created_at, users.deactivated_at, users.must_reset_password, users.email_verified_at, " +
+			"users.totp_enabled_at").
+		Joins("left join roles on roles.id = users.role_id").
+		Order("users.id").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Scan(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &users, total, nil
+}
+
+func (r *gormUserRepository) Permissions(user *User) (PermissionSet, error) {
+	return findUserPermissions(r.db, user.ID)
+}
+
+func (r *gormUserRepository) UpdatePassword(user *User, password []byte) error {
+	err := r.db.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"password":            password,
+		"must_reset_password": false,
+	}).Error
+	if err != nil {
+		return err
+	}
+	user.Password = password
+	user.MustResetPassword = false
+	return nil
+}
+
+func (r *gormUserRepository) SetPassword(user *User, password []byte, temporary bool) error {
+	now := time.Now()
+	err := revokeTokens(r.db, user, now, map[string]interface{}{
+		"password":            password,
+		"must_reset_password": temporary,
+	})
+	if err != nil {
+		return err
+	}
+	user.Password = password
+	user.MustResetPassword = temporary
+	return nil
+}
+
+func (r *gormUserRepository) LogoutEverywhere(user *User) error {
+	return revokeTokens(r.db, user, time.Now(), map[string]interface{}{})
+}
+
+func (r *gormUserRepository) ChangeRole(user *User, roleID int) error {
+	var role Role
+	if err := r.db.Where("id = ?", roleID).First(&role).Error; err != nil {
+		return fmt.Errorf("ERROR ROLE: could not find role with ID: %d", roleID)
+	}
+
+	err := r.db.Model(&User{}).Where("id = ?", user.ID).Update("role_id", roleID).Error
+	if err != nil {
+		return err
+	}
+	user.RoleID = roleID
+	user.Role = role
+	return nil
+}
+
+func (r *gormUserRepository) Deactivate(user *User) error {
+	if user.DeactivatedAt != nil {
+		return errors.New("ERROR: user is already deactivated")
+	}
+
+	now := time.Now()
+	err := revokeTokens(r.db, user, now, map[string]interface{}{
+		"deactivated_at": now,
+	})
+	if err != nil {
+		return err
+	}
+	user.DeactivatedAt = &now
+	return nil
+}
+
+func (r *gormUserRepository) Reactivate(user *User) error {
+	if user.DeactivatedAt == nil {
+		return errors.New("ERROR: user is not deactivated")
+	}
+
+	err := r.db.Model(&User{}).Where("id = ?", user.ID).Update("deactivated_at", nil).Error
+	if err != nil {
+		return err
+	}
+	user.DeactivatedAt = nil
+	return nil
+}
+
+func (r *gormUserRepository) ForcePasswordReset(user *User) error {
+	now := time.Now()
+	err := revokeTokens(r.db, user, now, map[string]interface{}{
+		"must_reset_password": true,
+	})
+	if err != nil {
+		return err
+	}
+	user.MustResetPassword = true
+	return nil
+}
+
+func (r *gormUserRepository) ResetTwoFactor(user *User) error {
+	now := time.Now()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return revokeTokens(tx, user, now, map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	return nil
+}
+
+type gormReplyRepository struct {
+	db *gorm.DB
+}
+
+// Create notifies the Issue's watchers in the same transaction.
+func (r *gormReplyRepository) Create(reply *Reply, issue *Issue, by *User) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(reply).Error; err != nil {
+			return err
+		}
+
+		detail := fmt.Sprintf("%s replied on %s: %s", by.Name, issue.Key, issue.Title)
+		return notifyIssueWatchers(tx, issue, by, NotificationReply, detail)
+	})
+	return err
+}
+
+func (r *gormReplyRepository) FindByID(id uint) (*Reply, error) {
+	var result Reply
+	err := r.db.Where("id = ?", id).First(&result).Error
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: could not find reply with ID: %d", id)
+	}
+	return &result, nil
+}
+
+func (r *gormReplyRepository) Update(source *Reply, changes *Reply) error {
+	return r.db.Model(source).Updates(changes).Error
+}
+
+func (r *gormReplyRepository) Delete(reply *Reply) error {
+	return r.db.Delete(reply).Error
+}
+
+type gormProjectRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormProjectRepository) Create(project *Project) error {
+	return saveProject(r.db, project)
+}
+
+func (r *gormProjectRepository) FindByKey(key string) (*Project, error) {
+	return findProjectByKey(r.db, key)
+}
+
+type gormStatusRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormStatusRepository) FindByID(id uint) (*Status, error) {
+	var result Status
+	query := r.db.Where("id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find status with ID: %d", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+func (r *gormStatusRepository) IsTransitionAllowed(from uint, to uint, roleID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&StatusTransition{}).
+		Where("from_status_id = ? AND to_status_id = ? AND role_id = ?", from, to, roleID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
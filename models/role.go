@@ -1,13 +1,138 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/database"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Built-in Role IDs.
+const (
+	RoleQA             = 1
+	RoleDeveloper      = 2
+	RoleAdmin          = 3
+	RoleProductManager = 4
+	RoleViewer         = 5
+)
 
 // Role consists of:
 //
 // ID 1: QA
 //
 // ID 2: Developer
+//
+// ID 3: Admin
+//
+// ID 4: Product Manager
+//
+// ID 5: Viewer
+//
+// What the Users of a Role may do is decided by its Permissions. Only Roles
+// with SelfRegister can be chosen on Register.
 type Role struct {
 	gorm.Model
-	Name string `gorm:"size:50"`
+	Name         string       `gorm:"size:50"`
+	SelfRegister bool         `gorm:"default:false"`
+	Permissions  []Permission `gorm:"many2many:role_permissions"`
+}
+
+// DefaultRoles are the built-in Roles.
+var DefaultRoles = []Role{
+	{Model: gorm.Model{ID: RoleQA}, Name: "QA", SelfRegister: true},
+	{Model: gorm.Model{ID: RoleDeveloper}, Name: "Developer", SelfRegister: true},
+	{Model: gorm.Model{ID: RoleAdmin}, Name: "Admin"},
+	{Model: gorm.Model{ID: RoleProductManager}, Name: "Product Manager"},
+	{Model: gorm.Model{ID: RoleViewer}, Name: "Viewer"},
+}
+
+// ValidateRole validates the Role data. The Name must be unique.
+func (r *Role) ValidateRole() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.New("ERROR NAME: name must not be empty")
+	}
+
+	var count int64
+	err := database.DB.Model(&Role{}).
+		Where("LOWER(name) = ? AND id <> ?", strings.ToLower(r.Name), r.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("ERROR NAME: role %s already exists", r.Name)
+	}
+	return nil
+}
+
+// SaveRole saves the Role together with its Permissions.
+func (r *Role) SaveRole() error {
+	err := database.DB.Create(&r).Error
+	return err
+}
+
+// IndexRoles fetches every Role with its Permissions.
+func (r *Role) IndexRoles() (*[]Role, error) {
+	var roles []Role
+	if err := database.DB.Preload("Permissions").Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return &roles, nil
+}
+
+// FindRoleByID fetches a Role with its Permissions by its ID.
+func (r *Role) FindRoleByID(id string) (*Role, error) {
+	var result Role
+	query := database.DB.Preload("Permissions").Where("id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find role with ID: %s", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// UpdateRole updates a Role's name and whether it can be chosen on Register,
+// and replaces its Permissions. Takes an origin Role as parameter.
+func (r *Role) UpdateRole(origin *Role) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&origin).Select("name", "self_register").Updates(r).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&origin).Association("Permissions").Replace(r.Permissions)
+	})
+	return err
+}
+
+// DeleteRole deletes a Role which no User has.
+func (r *Role) DeleteRole() error {
+	var count int64
+	if err := database.DB.Model(&User{}).Where("role_id = ?", r.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("ERROR: role %s still has %d users", r.Name, count)
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&r).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&r).Error
+	})
+	return err
+}
+
+// CanSelfRegister checks whether a new User may choose the Role on Register.
+func (r *Role) CanSelfRegister(roleID int) bool {
+	var result Role
+	err := database.DB.Where("id = ? AND self_register = ?", roleID, true).First(&result).Error
+	return err == nil
 }
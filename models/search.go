@@ -0,0 +1,275 @@
+package models
+
+import (
+	"fmt"
+	"issue-tracker/database"
+	"issue-tracker/search"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// IssueSearchVector and ReplySearchVector are the tsvector expressions used by
+// the PostgreSQL full-text search. The migrations create GIN indexes on the
+// same expressions, so they must be changed together.
+const (
+	IssueSearchVector = `setweight(to_tsvector('english', coalesce(issues.title, '')), 'A') || ` +
+		`setweight(to_tsvector('english', coalesce(issues.body, '')), 'B')`
+	ReplySearchVector = `to_tsvector('english', coalesce(replies.body, ''))`
+
+	searchHeadlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15"
+	searchSnippetWords    = 30
+)
+
+// IssueSearchResult is one Issue found by IssueRepository.Search.
+//
+// MatchedIn is "issue" if the best match is the Issue's title or body, or
+// "reply" if it is one of its Replies. In that case ReplyID is that Reply.
+type IssueSearchResult struct {
+	IssueID   int
+	ProjectID int
+	Key       string
+	Title     string
+	Rank      float64
+	Snippet   string
+	MatchedIn string
+	ReplyID   int
+}
+
+// searchHit is one matching Issue or Reply.
+type searchHit struct {
+	IssueID uint
+	ReplyID uint
+	Rank    float64
+	Snippet string
+}
+
+// searchIssues searches the title and body of the Issues and the body of
+// their Replies. Every Issue is returned once, ranked by its best match.
+// If projectID is not 0, only the Issues of that Project are searched.
+//
+// PostgreSQL uses its full-text search, other databases use the in-memory
+// fallback index of package search.
+func searchIssues(db *gorm.DB, q string, projectID uint, limit int) (*[]IssueSearchResult, error) {
+	var hits []searchHit
+	var err error
+	if db.Dialector.Name() == database.DriverPostgres {
+		hits, err = searchPostgres(db, q, projectID)
+	} else {
+		hits, err = searchFallback(db, q, projectID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	best := bestHitPerIssue(hits)
+	if limit > 0 && len(best) > limit {
+		best = best[:limit]
+	}
+
+	results := make([]IssueSearchResult, 0, len(best))
+	if len(best) == 0 {
+		return &results, nil
+	}
+
+	ids := make([]uint, len(best))
+	for idx, hit := range best {
+		ids[idx] = hit.IssueID
+	}
+	var issues []Issue
+	if err := db.Select("id", "project_id", "key", "title").Where("id IN ?", ids).Find(&issues).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]Issue, len(issues))
+	for _, issue := range issues {
+		byID[issue.ID] = issue
+	}
+
+	for _, hit := range best {
+		issue := byID[hit.IssueID]
+		result := IssueSearchResult{
+			IssueID:   int(issue.ID),
+			ProjectID: int(issue.ProjectID),
+			Key:       issue.Key,
+			Title:     issue.Title,
+			Rank:      hit.Rank,
+			Snippet:   hit.Snippet,
+			MatchedIn: "issue",
+		}
+		if hit.ReplyID != 0 {
+			result.MatchedIn = "reply"
+			result.ReplyID = int(hit.ReplyID)
+		}
+		results = append(results, result)
+	}
+	return &results, nil
+}
+
+// bestHitPerIssue keeps the highest ranked hit of every Issue, best first.
+func bestHitPerIssue(hits []searchHit) []searchHit {
+	best := map[uint]searchHit{}
+	for _, hit := range hits {
+		if current, ok := best[hit.IssueID]; !ok || hit.Rank > current.Rank {
+			best[hit.IssueID] = hit
+		}
+	}
+
+	result := make([]searchHit, 0, len(best))
+	for _, hit := range best {
+		result = append(result, hit)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].Rank != result[b].Rank {
+			return result[a].Rank > result[b].Rank
+		}
+		return result[a].IssueID < result[b].IssueID
+	})
+	return result
+}
+
+// searchPostgres searches with the tsvector expressions, which are backed by
+// GIN indexes.
+func searchPostgres(db *gorm.DB, q string, projectID uint) ([]searchHit, error) {
+	var hits []searchHit
+
+	issueQuery := db.Model(&Issue{}).
+		Select(fmt.Sprintf(`
+			issues.id AS issue_id,
+			ts_rank(%[1]s, plainto_tsquery('english', ?)) AS rank,
+			ts_headline('english', issues.body, plainto_tsquery('english', ?), '%[2]s') AS snippet`,
+			IssueSearchVector, searchHeadlineOptions), q, q).
+		Where(IssueSearchVector+" @@ plainto_tsquery('english', ?)", q)
+	if projectID != 0 {
+		issueQuery = issueQuery.Where("issues.project_id = ?", projectID)
+	}
+	if err := issueQuery.Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+
+	var replyHits []searchHit
+	replyQuery := db.Model(&Reply{}).
+		Select(fmt.Sprintf(`
+			replies.issue_id,
+			replies.id AS reply_id,
+			ts_rank(%[1]s, plainto_tsquery('english', ?)) AS rank,
+			ts_headline('english', replies.body, plainto_tsquery('english', ?), '%[2]s') AS snippet`,
+			ReplySearchVector, searchHeadlineOptions), q, q).
+		Joins("join issues on replies.issue_id = issues.id AND issues.deleted_at IS NULL").
+		Where(ReplySearchVector+" @@ plainto_tsquery('english', ?)", q)
+	if projectID != 0 {
+		replyQuery = replyQuery.Where("issues.project_id = ?", projectID)
+	}
+	if err := replyQuery.Scan(&replyHits).Error; err != nil {
+		return nil, err
+	}
+
+	return append(hits, replyHits...), nil
+}
+
+// searchFallbackCandidates is how many Issues and how many Replies the fallback
+// search ranks at most.
+const searchFallbackCandidates = 500
+
+// searchFallback selects the newest Issues and Replies containing any term of
+// the query with LIKE, and ranks them with an in-memory index of package
+// search. Only meant for small databases, such as the test setup.
+func searchFallback(db *gorm.DB, q string, projectID uint) ([]searchHit, error) {
+	patterns := likePatterns(q)
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	var issues []Issue
+	issueQuery := db.Select("id", "title", "body").
+		Where(likeAny(db, patterns, "LOWER(issues.title)", "LOWER(issues.body)")).
+		Order("id DESC").
+		Limit(searchFallbackCandidates)
+	if projectID != 0 {
+		issueQuery = issueQuery.Where("project_id = ?", projectID)
+	}
+	if err := issueQuery.Find(&issues).Error; err != nil {
+		return nil, err
+	}
+
+	var replies []Reply
+	replyQuery := db.Select("replies.id", "replies.issue_id", "replies.body").
+		Joins("join issues on replies.issue_id = issues.id AND issues.deleted_at IS NULL").
+		Where(likeAny(db, patterns, "LOWER(replies.body)")).
+		Order("replies.id DESC").
+		Limit(searchFallbackCandidates)
+	if projectID != 0 {
+		replyQuery = replyQuery.Where("issues.project_id = ?", projectID)
+	}
+	if err := replyQuery.Find(&replies).Error; err != nil {
+		return nil, err
+	}
+
+	index := search.NewIndex()
+	bodies := map[string]string{}
+	for _, issue := range issues {
+		id := "issue:" + strconv.Itoa(int(issue.ID))
+		index.Add(id, search.Field{Text: issue.Title, Weight: 2}, search.Field{Text: issue.Body, Weight: 1})
+		bodies[id] = issue.Body
+	}
+	replyIssues := map[string]uint{}
+	for _, reply := range replies {
+		id := "reply:" + strconv.Itoa(int(reply.ID))
+		index.Add(id, search.Field{Text: reply.Body, Weight: 1})
+		bodies[id] = reply.Body
+		replyIssues[id] = reply.IssueID
+	}
+
+	var hits []searchHit
+	for _, result := range index.Search(q, 0) {
+		hit := searchHit{
+			Rank:    result.Score,
+			Snippet: search.Highlight(bodies[result.ID], q, searchSnippetWords),
+		}
+
+		kind, rawID := splitDocumentID(result.ID)
+		if kind == "reply" {
+			hit.ReplyID = rawID
+			hit.IssueID = replyIssues[result.ID]
+		} else {
+			hit.IssueID = rawID
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// likePatterns returns a LIKE pattern for every term of the query. The stems of
+// package search drop a trailing "y" too, so "copy" also matches "copies".
+// The terms only have letters and digits, so nothing has to be escaped.
+func likePatterns(q string) []string {
+	terms := search.Tokenize(q)
+	patterns := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if len(term) > 3 {
+			term = strings.TrimSuffix(term, "y")
+		}
+		patterns = append(patterns, "%"+term+"%")
+	}
+	return patterns
+}
+
+// likeAny returns a condition matching a row where any of the columns is LIKE
+// any of the patterns.
+func likeAny(db *gorm.DB, patterns []string, columns ...string) *gorm.DB {
+	condition := db.Session(&gorm.Session{NewDB: true})
+	for _, column := range columns {
+		for _, pattern := range patterns {
+			condition = condition.Or(column+" LIKE ?", pattern)
+		}
+	}
+	return condition
+}
+
+// splitDocumentID splits a fallback index ID like "reply:12" into its kind and ID.
+func splitDocumentID(id string) (string, uint) {
+	parts := strings.SplitN(id, ":", 2)
+	number, _ := strconv.Atoi(parts[1])
+	return parts[0], uint(number)
+}
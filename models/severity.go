@@ -0,0 +1,33 @@
+package models
+
+import "issue-tracker/database"
+
+// Built-in Severity IDs, the values of Issue.Severity.
+const (
+	SeverityLow    = 1
+	SeverityMedium = 2
+	SeverityHigh   = 3
+)
+
+// Severity names a value of Issue.Severity.
+type Severity struct {
+	ID   uint   `gorm:"primarykey"`
+	Name string `gorm:"size:50;unique"`
+}
+
+// DefaultSeverities are the built-in Severities, from the lowest.
+var DefaultSeverities = []Severity{
+	{ID: SeverityLow, Name: "Low"},
+	{ID: SeverityMedium, Name: "Medium"},
+	{ID: SeverityHigh, Name: "High"},
+}
+
+// IndexSeverities fetches all Severities, from the lowest.
+func (s *Severity) IndexSeverities() (*[]Severity, error) {
+	var severities []Severity
+	err := database.DB.Order("id").Find(&severities).Error
+	if err != nil {
+		return nil, err
+	}
+	return &severities, nil
+}
@@ -0,0 +1,256 @@
+package models_test
+
+import (
+	"issue-tracker/database"
+	"issue-tracker/migrations"
+	"issue-tracker/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/logger"
+)
+
+// setupSQLite points database.DB at a new migrated in-memory SQLite database.
+func setupSQLite(t *testing.T) {
+	db, err := database.Open(database.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	database.DB = db
+	if err := migrations.MigrateTables(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createUser(t *testing.T, name string) *models.User {
+	user := models.User{Name: name, Email: name + "@email.com", RoleID: models.RoleQA}
+	if err := user.SaveUserData(); err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func TestIssuesOnSQLite(t *testing.T) {
+	setupSQLite(t)
+
+	zed := createUser(t, "zed")
+	amy := createUser(t, "amy")
+	project := models.Project{Name: "Web", Key: "WEB", OwnerID: int(zed.ID)}
+	assert.NoError(t, project.SaveProject())
+
+	store := models.NewGormStore(database.DB)
+	for _, author := range []*models.User{zed, amy} {
+		issue := models.Issue{UserID: int(author.ID), Title: "Crash", Body: "On start", Severity: "1", StatusID: models.StatusOpen}
+		assert.NoError(t, store.Issues().Create(&issue, &project))
+	}
+
+	issues, total, err := store.Issues().Index(models.IssueFilter{Sort: "user_name", Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, "amy", (*issues)[0].UserName)
+	assert.Equal(t, "WEB-2", (*issues)[0].Key)
+	assert.Equal(t, "zed", (*issues)[1].UserName)
+
+	source, err := store.Issues().FindByID(uint((*issues)[1].ID))
+	if !assert.NoError(t, err) {
+		return
+	}
+	reply := models.Reply{UserID: amy.ID, IssueID: source.ID, Body: "Same here."}
+	assert.NoError(t, store.Replies().Create(&reply, source, amy))
+
+	show, replies, err := store.Issues().Show(source)
+	assert.NoError(t, err)
+	assert.Equal(t, "zed", show.UserName)
+	assert.Equal(t, "Open", show.StatusName)
+	if assert.Len(t, *replies, 1) {
+		assert.Equal(t, "amy", (*replies)[0].Replier)
+	}
+
+	var notification models.Notification
+	unread, err := notification.CountUnread(int(zed.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), unread, "the reporter is notified of the Reply")
+}
+
+func TestMigrateTablesTwiceOnSQLite(t *testing.T) {
+	setupSQLite(t)
+	assert.NoError(t, migrations.MigrateTables(database.DB))
+
+	var roles, statuses int64
+	assert.NoError(t, database.DB.Model(&models.Role{}).Count(&roles).Error)
+	assert.NoError(t, database.DB.Model(&models.Status{}).Count(&statuses).Error)
+	assert.Equal(t, int64(len(models.DefaultRoles)), roles)
+	assert.Equal(t, int64(len(models.DefaultStatuses)), statuses)
+}
+
+func TestSearchIssuesOnSQLite(t *testing.T) {
+	setupSQLite(t)
+
+	zed := createUser(t, "zed")
+	web := models.Project{Name: "Web", Key: "WEB", OwnerID: int(zed.ID)}
+	assert.NoError(t, web.SaveProject())
+	api := models.Project{Name: "Api", Key: "API", OwnerID: int(zed.ID)}
+	assert.NoError(t, api.SaveProject())
+
+	store := models.NewGormStore(database.DB)
+	save := func(project *models.Project, title string, body string) *models.Issue {
+		issue := models.Issue{UserID: int(zed.ID), Title: title, Body: body, Severity: "1", StatusID: models.StatusOpen}
+		assert.NoError(t, store.Issues().Create(&issue, project))
+		return &issue
+	}
+	crash := save(&web, "Crashes on start", "The app closes.")
+	save(&web, "Typo", "In the footer.")
+	copies := save(&web, "Export", "Too many copies are made.")
+	apiCrash := save(&api, "Crash", "The server crashed.")
+	reply := models.Reply{UserID: zed.ID, IssueID: crash.ID, Body: "The footer crashed too."}
+	assert.NoError(t, store.Replies().Create(&reply, crash, zed))
+
+	results, err := store.Issues().Search("crash", 0, 10)
+	assert.NoError(t, err)
+	ids := []int{}
+	for _, result := range *results {
+		ids = append(ids, result.IssueID)
+	}
+	assert.ElementsMatch(t, []int{int(crash.ID), int(apiCrash.ID)}, ids)
+
+	results, err = store.Issues().Search("CRASH", web.ID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, *results, 1) {
+		assert.Equal(t, int(crash.ID), (*results)[0].IssueID)
+	}
+
+	results, err = store.Issues().Search("copy", 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, *results, 1) {
+		assert.Equal(t, int(copies.ID), (*results)[0].IssueID)
+	}
+
+	results, err = store.Issues().Search("crash", 0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, *results, 1)
+
+	results, err = store.Issues().Search("the", 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, *results, "stop words match nothing")
+}
+
+func TestIssueWorkflowOnSQLite(t *testing.T) {
+	setupSQLite(t)
+	store := models.NewGormStore(database.DB)
+
+	qa := createUser(t, "qa")
+	dev := createUser(t, "dev")
+	project := models.Project{Name: "Web", Key: "WEB", OwnerID: int(qa.ID)}
+	assert.NoError(t, project.SaveProject())
+	issue := models.Issue{UserID: int(qa.ID), Title: "Crash", Body: "On start", Severity: "1", StatusID: models.StatusOpen}
+	assert.NoError(t, store.Issues().Create(&issue, &project))
+
+	assert.NoError(t, store.Issues().Assign(&issue, dev, qa))
+	assert.NoError(t, store.Issues().Assign(&issue, dev, qa), "assigning twice does nothing")
+	assignees, err := store.Issues().Assignees(&issue)
+	assert.NoError(t, err)
+	assert.Len(t, *assignees, 1)
+
+	allowed, err := store.Statuses().IsTransitionAllowed(models.StatusOpen, models.StatusTriaged, models.RoleQA)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	triaged, err := store.Statuses().FindByID(models.StatusTriaged)
+	if assert.NoError(t, err) {
+		assert.NoError(t, store.Issues().Transition(&issue, triaged, qa))
+	}
+	assert.NoError(t, store.Issues().Unassign(&issue, dev, qa))
+
+	history, err := store.Issues().History(&issue)
+	assert.NoError(t, err)
+	fields := []string{}
+	for _, event := range *history {
+		fields = append(fields, event.Field)
+	}
+	assert.Equal(t, []string{"assignee", "status", "assignee"}, fields)
+
+	var notification models.Notification
+	unread, err := notification.CountUnread(int(dev.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), unread, "the assignee is notified of the transition")
+}
+
+func TestUserManagementOnSQLite(t *testing.T) {
+	setupSQLite(t)
+	store := models.NewGormStore(database.DB)
+	user := createUser(t, "qa")
+
+	permissions, err := store.Users().Permissions(user)
+	assert.NoError(t, err)
+	assert.True(t, permissions.Has(models.PermIssueCreate))
+
+	assert.NoError(t, store.Users().Deactivate(user))
+	assert.Error(t, store.Users().Deactivate(user))
+	permissions, err = store.Users().Permissions(user)
+	assert.NoError(t, err)
+	assert.Empty(t, permissions)
+
+	active := false
+	users, total, err := store.Users().Index(models.UserFilter{Active: &active, Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "QA", (*users)[0].RoleName)
+
+	assert.NoError(t, store.Users().Reactivate(user))
+	assert.Error(t, store.Users().ChangeRole(user, 99))
+	assert.NoError(t, store.Users().ChangeRole(user, models.RoleDeveloper))
+
+	now := time.Now()
+	assert.NoError(t, database.DB.Model(user).Updates(map[string]interface{}{"totp_secret": "secret", "totp_enabled_at": now}).Error)
+	assert.NoError(t, store.Users().ResetTwoFactor(user))
+
+	saved, err := store.Users().FindByID(user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, saved.DeactivatedAt)
+	assert.Nil(t, saved.TOTPEnabledAt)
+	assert.NotNil(t, saved.TokensRevokedAt)
+	assert.Equal(t, models.RoleDeveloper, saved.RoleID)
+}
+
+func TestProjectKeyOnSQLite(t *testing.T) {
+	setupSQLite(t)
+	owner := createUser(t, "zed")
+
+	project := models.Project{Name: "Web", Key: "WEB", OwnerID: int(owner.ID)}
+	assert.NoError(t, project.SaveProject())
+
+	again := models.Project{Name: "Website", Key: "WEB", OwnerID: int(owner.ID)}
+	err := again.SaveProject()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "already used by another project")
+	}
+
+	assert.NoError(t, project.DeleteProject())
+	again = models.Project{Name: "Website", Key: "WEB", OwnerID: int(owner.ID)}
+	err = again.SaveProject()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "was used by a deleted project")
+	}
+}
+
+func TestUseRecoveryCodeOnSQLite(t *testing.T) {
+	setupSQLite(t)
+	user := createUser(t, "qa")
+	// Recovery codes are random, but nothing stops two from having the same
+	// hash. Each use takes one of them.
+	assert.NoError(t, user.ReplaceRecoveryCodes([]string{"same", "same", "other"}))
+
+	for i := 0; i < 2; i++ {
+		used, err := user.UseRecoveryCode("same")
+		assert.NoError(t, err)
+		assert.True(t, used)
+	}
+	used, err := user.UseRecoveryCode("same")
+	assert.NoError(t, err)
+	assert.False(t, used, "both codes are used")
+
+	remaining, err := user.RemainingRecoveryCodes()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), remaining)
+}
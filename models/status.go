@@ -0,0 +1,87 @@
+package models
+
+import (
+	"issue-tracker/database"
+)
+
+// Built-in Status IDs.
+const (
+	StatusOpen       = 1
+	StatusTriaged    = 2
+	StatusInProgress = 3
+	StatusInReview   = 4
+	StatusResolved   = 5
+	StatusClosed     = 6
+	StatusWontFix    = 7
+)
+
+// Status is a state of the Issue workflow.
+//
+// Issues in a Status with IsClosed can not be replied to anymore.
+type Status struct {
+	ID       uint   `gorm:"primarykey"`
+	Name     string `gorm:"size:50;unique"`
+	Position int
+	IsClosed bool
+}
+
+// StatusTransition allows Users with RoleID to move an Issue from the
+// FromStatusID Status to the ToStatusID Status.
+type StatusTransition struct {
+	ID           uint `gorm:"primarykey"`
+	FromStatusID uint `gorm:"uniqueIndex:idx_status_transition"`
+	ToStatusID   uint `gorm:"uniqueIndex:idx_status_transition"`
+	RoleID       int  `gorm:"uniqueIndex:idx_status_transition"`
+}
+
+// DefaultStatuses are the built-in workflow states, in workflow order.
+var DefaultStatuses = []Status{
+	{ID: StatusOpen, Name: "Open", Position: 1},
+	{ID: StatusTriaged, Name: "Triaged", Position: 2},
+	{ID: StatusInProgress, Name: "In Progress", Position: 3},
+	{ID: StatusInReview, Name: "In Review", Position: 4},
+	{ID: StatusResolved, Name: "Resolved", Position: 5},
+	{ID: StatusClosed, Name: "Closed", Position: 6, IsClosed: true},
+	{ID: StatusWontFix, Name: "Won't Fix", Position: 7, IsClosed: true},
+}
+
+// DefaultTransitions is the built-in workflow.
+//
+// QA triages, verifies and closes Issues, Developers work on them.
+var DefaultTransitions = []StatusTransition{
+	{FromStatusID: StatusOpen, ToStatusID: StatusTriaged, RoleID: RoleQA},
+	{FromStatusID: StatusOpen, ToStatusID: StatusTriaged, RoleID: RoleDeveloper},
+	{FromStatusID: StatusOpen, ToStatusID: StatusClosed, RoleID: RoleQA},
+	{FromStatusID: StatusOpen, ToStatusID: StatusWontFix, RoleID: RoleQA},
+	{FromStatusID: StatusTriaged, ToStatusID: StatusInProgress, RoleID: RoleDeveloper},
+	{FromStatusID: StatusTriaged, ToStatusID: StatusWontFix, RoleID: RoleQA},
+	{FromStatusID: StatusInProgress, ToStatusID: StatusTriaged, RoleID: RoleDeveloper},
+	{FromStatusID: StatusInProgress, ToStatusID: StatusInReview, RoleID: RoleDeveloper},
+	{FromStatusID: StatusInReview, ToStatusID: StatusInProgress, RoleID: RoleQA},
+	{FromStatusID: StatusInReview, ToStatusID: StatusInProgress, RoleID: RoleDeveloper},
+	{FromStatusID: StatusInReview, ToStatusID: StatusResolved, RoleID: RoleQA},
+	{FromStatusID: StatusResolved, ToStatusID: StatusOpen, RoleID: RoleQA},
+	{FromStatusID: StatusResolved, ToStatusID: StatusClosed, RoleID: RoleQA},
+	{FromStatusID: StatusClosed, ToStatusID: StatusOpen, RoleID: RoleQA},
+	{FromStatusID: StatusWontFix, ToStatusID: StatusOpen, RoleID: RoleQA},
+}
+
+// IndexStatuses fetches all Statuses in workflow order.
+func (s *Status) IndexStatuses() (*[]Status, error) {
+	var statuses []Status
+	err := database.DB.Order("position").Find(&statuses).Error
+	if err != nil {
+		return nil, err
+	}
+	return &statuses, nil
+}
+
+// IndexTransitions fetches all StatusTransitions.
+func (t *StatusTransition) IndexTransitions() (*[]StatusTransition, error) {
+	var transitions []StatusTransition
+	err := database.DB.Order("from_status_id, to_status_id, role_id").Find(&transitions).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transitions, nil
+}
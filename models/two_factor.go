@@ -0,0 +1,165 @@
+package models
+
+import (
+	"errors"
+	"issue-tracker/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTOTPEnabled is returned when enrolling a User who already has 2FA.
+var ErrTOTPEnabled = errors.New("ERROR: 2FA is already enabled")
+
+// RecoveryCode belongs to User
+//
+// A RecoveryCode logs a User with 2FA in once, in place of a code from their
+// authenticator app. Only the SHA-256 of the code is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	CodeHash  string `gorm:"size:64;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// StartTOTPEnrollment saves a new TOTP secret for the User. 2FA is enabled
+// once the User confirms it with a code.
+func (u *User) StartTOTPEnrollment(secret string) error {
+	query := database.DB.Model(&User{}).
+		Where("id = ? AND totp_enabled_at IS NULL", u.ID).
+		Update("totp_secret", secret)
+	if query.Error != nil {
+		return query.Error
+	}
+	if query.RowsAffected != 1 {
+		return ErrTOTPEnabled
+	}
+	u.TOTPSecret = secret
+	return nil
+}
+
+// EnableTOTP enables 2FA for the User with the recovery codes of the given
+// hashes. step is the time step of the code which confirmed the enrollment, so
+// it can not be used to log in.
+func (u *User) EnableTOTP(step int64, codeHashes []string) error {
+	now := time.Now()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&User{}).
+			Where("id = ? AND totp_enabled_at IS NULL", u.ID).
+			Updates(map[string]interface{}{
+				"totp_enabled_at": now,
+				"totp_last_step":  step,
+			})
+		if query.Error != nil {
+			return query.Error
+		}
+		if query.RowsAffected != 1 {
+			return ErrTOTPEnabled
+		}
+		return replaceRecoveryCodes(tx, u.ID, codeHashes)
+	})
+	if err != nil {
+		return err
+	}
+	u.TOTPEnabledAt = &now
+	u.TOTPLastStep = step
+	return nil
+}
+
+// DisableTOTP disables 2FA for the User and deletes their recovery codes.
+func (u *User) DisableTOTP() error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", u.ID).Delete(&RecoveryCode{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	u.TOTPSecret = ""
+	u.TOTPEnabledAt = nil
+	u.TOTPLastStep = 0
+	return nil
+}
+
+// UseTOTPStep records that the User logged in with the code of the time step.
+// Returns false if a code of that step or a later one was used already, so a
+// code can not be replayed.
+func (u *User) UseTOTPStep(step int64) (bool, error) {
+	query := database.DB.Model(&User{}).
+		Where("id = ? AND totp_last_step < ?", u.ID, step).
+		Update("totp_last_step", step)
+	if query.Error != nil {
+		return false, query.Error
+	}
+	if query.RowsAffected != 1 {
+		return false, nil
+	}
+	u.TOTPLastStep = step
+	return true, nil
+}
+
+// UseRecoveryCode uses the User's recovery code with the given hash. Returns
+// false if the User has no such unused code.
+func (u *User) UseRecoveryCode(hash string) (bool, error) {
+	var code RecoveryCode
+	err := database.DB.Where("user_id = ? AND code_hash = ? AND used_at IS NULL", u.ID, hash).
+		Order("id").
+		Limit(1).
+		Find(&code).Error
+	if err != nil || code.ID == 0 {
+		return false, err
+	}
+
+	// Only one of concurrent logins with the code uses it.
+	query := database.DB.Model(&RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", code.ID).
+		Update("used_at", time.Now())
+	if query.Error != nil {
+		return false, query.Error
+	}
+	return query.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes replaces the User's recovery codes with the codes of
+// the given hashes.
+func (u *User) ReplaceRecoveryCodes(codeHashes []string) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, u.ID, codeHashes)
+	})
+	return err
+}
+
+// RemainingRecoveryCodes counts the User's unused recovery codes.
+func (u *User) RemainingRecoveryCodes() (int64, error) {
+	var count int64
+	err := database.DB.Model(&RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", u.ID).
+		Count(&count).Error
+	return count, err
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = RecoveryCode{
+			UserID:   userID,
+			CodeHash: hash,
+		}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
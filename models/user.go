@@ -2,71 +2,119 @@ package models
 
 import (
 	"issue-tracker/database"
-	"strconv"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 // User belongs to a Role
+//
+// A deactivated User (DeactivatedAt is set) can not log in. Tokens issued up to
+// TokensRevokedAt are rejected. A User with MustResetPassword has to change
+// their password before doing anything else. A User whose e-mail is not
+// verified (EmailVerifiedAt is nil) can log in, but not use protected routes.
+// A User with TOTPEnabledAt set logs in with a 2FA code after their password.
 type User struct {
 	gorm.Model
-	RoleID        int
-	Role          Role
-	Issues        []Issue
-	Replies       []Reply
-	Notifications []Notification
-	Name          string `gorm:"size:100"`
-	Email         string `gorm:"size:300;unique;"`
-	Password      []byte
+	RoleID            int
+	Role              Role
+	Issues            []Issue
+	Replies           []Reply
+	Notifications     []Notification
+	Name              string `gorm:"size:100"`
+	Email             string `gorm:"size:300;unique;"`
+	Password          []byte
+	DeactivatedAt     *time.Time
+	TokensRevokedAt   *time.Time
+	MustResetPassword bool `gorm:"default:false"`
+	EmailVerifiedAt   *time.Time
+	TOTPSecret        string `gorm:"size:64" json:"-"`
+	TOTPEnabledAt     *time.Time
+	TOTPLastStep      int64 `json:"-"`
+}
+
+// UserIndex is used for the User Index route.
+type UserIndex struct {
+	ID                uint
+	Name              string
+	Email             string
+	RoleID            int
+	RoleName          string
+	CreatedAt         time.Time
+	DeactivatedAt     *time.Time
+	MustResetPassword bool
+	EmailVerifiedAt   *time.Time
+	TOTPEnabledAt     *time.Time
+}
+
+// UserFilter narrows down the User Index.
+// A zero RoleID and a nil Active do not filter.
+type UserFilter struct {
+	RoleID int
+	Active *bool
+	Page   int
+	Limit  int
 }
 
 // SaveUserData saves a User's data from Register.
 // Returns error if failed.
 func (u *User) SaveUserData() error {
-	err := database.DB.Create(&u).Error
-	return err
+	return NewGormStore(database.DB).Users().Create(u)
 }
 
 // GetUserByEmail searches a User by presented Email.
 // Returns the User data.
 func (u *User) GetUserByEmail() *User {
-	var result = &User{}
-	err := database.DB.Where(map[string]interface{}{
-		"email": u.Email,
-	}).First(&result).Error
+	result, err := NewGormStore(database.DB).Users().FindByEmail(u.Email)
 	if err != nil {
 		return nil
 	}
 	return result
 }
 
-// GetUserRoleByID fetches a User's RoleID by searching its ID.
-// Returns a string and error.
-func (u *User) GetUserRoleByID(id int) (string, error) {
-	var result string
-	var user User
-
-	query := database.DB.Where("id = ?", id).First(&user)
-	if query.Error != nil {
-		return "", query.Error
-	}
-
-	result = strconv.Itoa(user.RoleID)
-	return result, nil
-}
-
 // GetUserByID gets a User data by ID.
 func (u *User) GetUserByID(id int) *User {
-	var result User
-	err := database.DB.Joins("Role").Preload("Issues").Preload("Replies").Where("users.id = ?", id).First(&result).Error
+	if id < 1 {
+		return nil
+	}
+	result, err := NewGormStore(database.DB).Users().FindByID(uint(id))
 	if err != nil {
 		return nil
 	}
-	return &result
+	return result
 }
 
-// UpdatePassword updates a User's password.
-func (u *User) UpdatePassword(newPassword []byte) error {
-	err := database.DB.Model(&u).Update("password", newPassword).Error
+// LogoutEverywhere revokes every token of the User and ends all their
+// sessions. Personal access tokens are not revoked, see
+// auth.ValidatePersonalToken.
+func (u *User) LogoutEverywhere() error {
+	err := revokeTokens(database.DB, u, time.Now(), map[string]interface{}{})
 	return err
 }
+
+// revokeTokens revokes the User's access tokens issued up to now and their
+// refresh tokens, together with the other updates.
+func revokeTokens(db *gorm.DB, u *User, now time.Time, updates map[string]interface{}) error {
+	updates["tokens_revoked_at"] = now
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", u.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return revokeRefreshTokens(tx.Where("user_id = ?", u.ID))
+	})
+	if err != nil {
+		return err
+	}
+	u.TokensRevokedAt = &now
+	return nil
+}
+
+// TokenRevoked checks whether a token issued at issuedAt (Unix seconds) has
+// been revoked.
+//
+// A token issued in the same second as the revocation is kept, so logging in
+// right after a password reset works. Revoking also ends the sessions of the
+// older tokens, which are rejected for that.
+func (u *User) TokenRevoked(issuedAt int64) bool {
+	return u.TokensRevokedAt != nil && issuedAt < u.TokensRevokedAt.Unix()
+}
@@ -0,0 +1,366 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/database"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook events.
+const (
+	EventIssueCreated    = "issue.created"
+	EventIssueUpdated    = "issue.updated"
+	EventIssueDeleted    = "issue.deleted"
+	EventIssueTransition = "issue.transitioned"
+	EventIssueAssigned   = "issue.assigned"
+	EventIssueUnassigned = "issue.unassigned"
+	EventIssueLabeled    = "issue.labeled"
+	EventIssueUnlabeled  = "issue.unlabeled"
+	EventReplyCreated    = "reply.created"
+	EventReplyUpdated    = "reply.updated"
+	EventReplyDeleted    = "reply.deleted"
+)
+
+// WebhookEvents are the events a Webhook can subscribe to.
+var WebhookEvents = []string{
+	EventIssueCreated,
+	EventIssueUpdated,
+	EventIssueDeleted,
+	EventIssueTransition,
+	EventIssueAssigned,
+	EventIssueUnassigned,
+	EventIssueLabeled,
+	EventIssueUnlabeled,
+	EventReplyCreated,
+	EventReplyUpdated,
+	EventReplyDeleted,
+}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook belongs to User
+//
+// A Webhook receives a signed POST request for every event it subscribes to.
+// Events is a comma separated list of WebhookEvents. A Webhook with a ProjectID
+// only receives the events of that Project.
+type Webhook struct {
+	gorm.Model
+	OwnerID   int
+	ProjectID *uint  `gorm:"index"`
+	URL       string `gorm:"size:500"`
+	Events    string `gorm:"size:500"`
+	Secret    string `gorm:"size:100" json:"-"`
+	Active    bool
+}
+
+// WebhookDelivery is one queued or sent request of a Webhook.
+//
+// Pending deliveries are sent when NextAttemptAt is due. After the last failed
+// attempt the Status is failed and NextAttemptAt is nil.
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID     uint    `gorm:"index"`
+	Webhook       Webhook `json:"-"`
+	Event         string  `gorm:"size:50"`
+	Payload       string  `gorm:"type:text"`
+	Status        string  `gorm:"size:20;index"`
+	Attempts      int
+	NextAttemptAt *time.Time `gorm:"index"`
+	LastAttemptAt *time.Time
+	ResponseCode  int
+	Error         string `gorm:"size:500"`
+}
+
+// internalNetworks are the loopback, private, link-local and unspecified
+// networks a Webhook may not be sent to.
+var internalNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.168.0.0/16", "::/128", "::1/128", "fc00::/7", "fe80::/10",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// IsInternalIP checks whether the IP is a loopback, private, link-local,
+// multicast or unspecified address, which Webhooks may not be sent to.
+func IsInternalIP(ip net.IP) bool {
+	if ip.IsMulticast() {
+		return true
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupIP resolves the host of a Webhook's URL.
+var lookupIP = net.LookupIP
+
+// ValidateWebhook validates the Webhook data.
+// URL must be an absolute http(s) URL whose host only resolves to public
+// addresses, and Events must only contain WebhookEvents.
+func (w *Webhook) ValidateWebhook() error {
+	target, err := url.Parse(w.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errors.New("ERROR URL: url must be an absolute http or https URL")
+	}
+	if err := validateWebhookHost(target.Hostname()); err != nil {
+		return err
+	}
+
+	events := splitEvents(w.Events)
+	if len(events) == 0 {
+		return errors.New("ERROR EVENTS: at least one event is required")
+	}
+	for _, event := range events {
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("ERROR EVENTS: unknown event %s", event)
+		}
+	}
+	w.Events = strings.Join(events, ",")
+	return nil
+}
+
+// validateWebhookHost checks that the host is not internal and that every
+// address it resolves to is public.
+func validateWebhookHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("ERROR URL: url must not point to an internal address")
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = lookupIP(host); err != nil || len(ips) == 0 {
+			return fmt.Errorf("ERROR URL: could not resolve host %s", host)
+		}
+	}
+	for _, ip := range ips {
+		if IsInternalIP(ip) {
+			return errors.New("ERROR URL: url must not point to an internal address")
+		}
+	}
+	return nil
+}
+
+// SaveWebhook saves the Webhook to the database.
+func (w *Webhook) SaveWebhook() error {
+	err := database.DB.Create(&w).Error
+	return err
+}
+
+// IndexWebhooks fetches the Webhooks of a User.
+func (w *Webhook) IndexWebhooks(ownerID int) (*[]Webhook, error) {
+	var webhooks []Webhook
+	err := database.DB.Where("owner_id = ?", ownerID).Order("id").Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webhooks, nil
+}
+
+// FindWebhookByID fetches a Webhook by its ID.
+func (w *Webhook) FindWebhookByID(id string) (*Webhook, error) {
+	var result Webhook
+	query := database.DB.Where("id = ?", id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find webhook with ID: %s", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// UpdateWebhook updates a Webhook's URL, events and whether it is active.
+// Takes an origin Webhook as parameter.
+func (w *Webhook) UpdateWebhook(origin *Webhook) error {
+	err := database.DB.Model(&origin).Select("url", "events", "active").Updates(w).Error
+	return err
+}
+
+// DeleteWebhook deletes a Webhook and its deliveries.
+func (w *Webhook) DeleteWebhook() error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", w.ID).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&w).Error
+	})
+	return err
+}
+
+// Subscribes checks whether the Webhook subscribes to the event.
+func (w *Webhook) Subscribes(event string) bool {
+	for _, subscribed := range splitEvents(w.Events) {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// QueueEvent queues a delivery of the payload for every active Webhook which
+// subscribes to the event. projectID is the Project the event happened in.
+// Returns how many deliveries were queued.
+func (w *Webhook) QueueEvent(event string, projectID uint, payload []byte) (int, error) {
+	var webhooks []Webhook
+	err := database.DB.Where("active = ?", true).
+		Where("project_id IS NULL OR project_id = ?", projectID).
+		Find(&webhooks).Error
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var deliveries []WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	if err := database.DB.Create(&deliveries).Error; err != nil {
+		return 0, err
+	}
+	return len(deliveries), nil
+}
+
+// IndexDeliveries fetches one page of a Webhook's deliveries, newest first.
+// Returns the page and the total count of deliveries.
+func (d *WebhookDelivery) IndexDeliveries(webhookID uint, page int, limit int) (*[]WebhookDelivery, int64, error) {
+	var deliveries []WebhookDelivery
+	var total int64
+
+	err := database.DB.Model(&WebhookDelivery{}).Where("webhook_id = ?", webhookID).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = database.DB.Where("webhook_id = ?", webhookID).
+		Order("id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &deliveries, total, nil
+}
+
+// FindDeliveryByID fetches a delivery of a Webhook by its ID.
+func (d *WebhookDelivery) FindDeliveryByID(webhookID uint, id string) (*WebhookDelivery, error) {
+	var result WebhookDelivery
+	query := database.DB.Where("webhook_id = ? AND id = ?", webhookID, id).First(&result)
+
+	if result.ID == 0 {
+		return nil, fmt.Errorf("ERROR: could not find delivery with ID: %s", id)
+	}
+
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	return &result, nil
+}
+
+// DueDeliveries fetches at most limit pending deliveries whose next attempt is
+// due, oldest first, together with their Webhook.
+func (d *WebhookDelivery) DueDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := database.DB.Preload("Webhook").
+		Where("status = ? AND next_attempt_at <= ?", DeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// SaveAttempt saves the outcome of the delivery's last attempt.
+func (d *WebhookDelivery) SaveAttempt() error {
+	if runes := []rune(d.Error); len(runes) > 500 {
+		d.Error = string(runes[:500])
+	}
+
+	err := database.DB.Model(&d).
+		Select("status", "attempts", "next_attempt_at", "last_attempt_at", "response_code", "error").
+		Updates(d).Error
+	return err
+}
+
+// Redeliver queues a new delivery with the same event and payload.
+// The original delivery stays in the log as it is.
+func (d *WebhookDelivery) Redeliver() (*WebhookDelivery, error) {
+	now := time.Now()
+	delivery := WebhookDelivery{
+		WebhookID:     d.WebhookID,
+		Event:         d.Event,
+		Payload:       d.Payload,
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+	}
+
+	if err := database.DB.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// splitEvents splits a comma separated list of events, dropping blanks and
+// duplicates.
+func splitEvents(events string) []string {
+	var result []string
+	seen := map[string]struct{}{}
+	for _, event := range strings.Split(events, ",") {
+		event = strings.TrimSpace(event)
+		if _, ok := seen[event]; ok || event == "" {
+			continue
+		}
+		seen[event] = struct{}{}
+		result = append(result, event)
+	}
+	return result
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if known == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWebhookRejectsInternalAddresses(t *testing.T) {
+	defer func(lookup func(string) ([]net.IP, error)) { lookupIP = lookup }(lookupIP)
+	lookupIP = func(host string) ([]net.IP, error) {
+		if host == "rebound.example.com" {
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.1.2.3")}, nil
+		}
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+
+	for _, url := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://10.0.0.5/hook",
+		"http://172.20.1.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://[fd00::1]/hook",
+		"http://rebound.example.com/hook",
+	} {
+		webhook := Webhook{URL: url, Events: EventIssueCreated}
+		assert.Error(t, webhook.ValidateWebhook(), url)
+	}
+
+	webhook := Webhook{URL: "https://hooks.example.com/tracker", Events: EventIssueCreated}
+	assert.NoError(t, webhook.ValidateWebhook())
+}
@@ -0,0 +1,250 @@
+// Package search is a small in-memory full-text index.
+//
+// It is the fallback used by the Issue search when the database is not
+// PostgreSQL, which has its own tsvector based full-text search. The behaviour
+// follows Postgres' plainto_tsquery: every term of the query must be present in
+// a document for it to match.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Field is one weighted piece of text of a document, for example an Issue's
+// title. Matches in fields with a higher Weight rank higher.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Hit is one matching document of a Search.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+type document struct {
+	// terms holds the weighted term frequency of every term in the document.
+	terms map[string]float64
+	// length is the weighted number of terms, used to normalize the score.
+	length float64
+}
+
+// Index is an inverted index of documents identified by a string ID.
+// An Index is not safe for concurrent use.
+type Index struct {
+	documents map[string]*document
+	postings  map[string]map[string]struct{}
+}
+
+// NewIndex returns an empty Index.
+func NewIndex() *Index {
+	return &Index{
+		documents: map[string]*document{},
+		postings:  map[string]map[string]struct{}{},
+	}
+}
+
+// Add indexes a document. Adding an ID which is already indexed replaces it.
+func (ix *Index) Add(id string, fields ...Field) {
+	ix.Remove(id)
+
+	doc := &document{terms: map[string]float64{}}
+	for _, field := range fields {
+		for _, term := range Tokenize(field.Text) {
+			doc.terms[term] += field.Weight
+			doc.length += field.Weight
+		}
+	}
+
+	for term := range doc.terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[string]struct{}{}
+		}
+		ix.postings[term][id] = struct{}{}
+	}
+	ix.documents[id] = doc
+}
+
+// Remove removes a document from the Index.
+func (ix *Index) Remove(id string) {
+	doc, ok := ix.documents[id]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.documents, id)
+}
+
+// Search returns the documents containing every term of the query, best match
+// first. Returns at most limit Hits, or all of them if limit is 0.
+func (ix *Index) Search(query string, limit int) []Hit {
+	terms := uniqueTerms(Tokenize(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	// Start with the rarest term, it has the fewest candidates.
+	sort.Slice(terms, func(a, b int) bool {
+		return len(ix.postings[terms[a]]) < len(ix.postings[terms[b]])
+	})
+
+	var hits []Hit
+	for id := range ix.postings[terms[0]] {
+		doc := ix.documents[id]
+
+		score := 0.0
+		for _, term := range terms {
+			frequency, ok := doc.terms[term]
+			if !ok {
+				score = -1
+				break
+			}
+			idf := math.Log(1 + float64(len(ix.documents))/float64(len(ix.postings[term])))
+			score += frequency * idf
+		}
+		if score < 0 {
+			continue
+		}
+
+		hits = append(hits, Hit{ID: id, Score: score / (1 + math.Log(1+doc.length))})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// Tokenize splits text into lowercase terms, without stop words and with a
+// light stemming of plural and past tense endings.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if _, ok := stopWords[word]; ok {
+			continue
+		}
+		terms = append(terms, stem(word))
+	}
+	return terms
+}
+
+// Highlight returns an excerpt of text of about maxWords words around the first
+// term of the query found in it, with every matching word wrapped in <b></b>
+// like Postgres' ts_headline does. Returns the start of the text if no term is
+// found.
+func Highlight(text string, query string, maxWords int) string {
+	terms := map[string]struct{}{}
+	for _, term := range Tokenize(query) {
+		terms[term] = struct{}{}
+	}
+
+	words := strings.Fields(text)
+	first := -1
+	for idx, word := range words {
+		if matchesAny(word, terms) {
+			first = idx
+			break
+		}
+	}
+
+	start := 0
+	if first > maxWords/2 {
+		start = first - maxWords/2
+	}
+	end := start + maxWords
+	if end > len(words) {
+		end = len(words)
+	}
+
+	excerpt := make([]string, 0, end-start)
+	for _, word := range words[start:end] {
+		if matchesAny(word, terms) {
+			word = "<b>" + word + "</b>"
+		}
+		excerpt = append(excerpt, word)
+	}
+	return strings.Join(excerpt, " ")
+}
+
+// matchesAny reports whether a word of the original text is one of the terms.
+func matchesAny(word string, terms map[string]struct{}) bool {
+	for _, term := range Tokenize(word) {
+		if _, ok := terms[term]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := map[string]struct{}{}
+	result := terms[:0]
+	for _, term := range terms {
+		if _, ok := seen[term]; ok {
+			continue
+		}
+		seen[term] = struct{}{}
+		result = append(result, term)
+	}
+	return result
+}
+
+// stem removes the most common English suffixes so that, for example,
+// "crashes", "crashed" and "crash" are the same term.
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		word = strings.TrimSuffix(word, "ies") + "y"
+	case hasAnySuffix(word, "sses", "shes", "ches", "xes", "zes"):
+		word = strings.TrimSuffix(word, "es")
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		word = strings.TrimSuffix(word, "s")
+	case len(word) > 5 && strings.HasSuffix(word, "ing"):
+		word = strings.TrimSuffix(word, "ing")
+	case len(word) > 4 && strings.HasSuffix(word, "ed"):
+		word = strings.TrimSuffix(word, "ed")
+	}
+
+	if len(word) > 4 && strings.HasSuffix(word, "e") {
+		word = strings.TrimSuffix(word, "e")
+	}
+	return word
+}
+
+func hasAnySuffix(word string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "but": {},
+	"by": {}, "for": {}, "if": {}, "in": {}, "into": {}, "is": {}, "it": {}, "no": {},
+	"not": {}, "of": {}, "on": {}, "or": {}, "such": {}, "that": {}, "the": {}, "their": {},
+	"then": {}, "there": {}, "these": {}, "they": {}, "this": {}, "to": {}, "was": {},
+	"will": {}, "with": {},
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"crash", "crash", "crash", "login", "page"},
+		Tokenize("Crash crashes CRASHED on the login-page"))
+}
+
+func TestSearch(t *testing.T) {
+	ix := NewIndex()
+	ix.Add("issue:1", Field{Text: "Login page crashes", Weight: 2}, Field{Text: "Clicking submit crashes the browser.", Weight: 1})
+	ix.Add("issue:2", Field{Text: "Typo on login page", Weight: 2}, Field{Text: "Welcome is misspelled.", Weight: 1})
+	ix.Add("reply:1", Field{Text: "The crash also happens on the signup page.", Weight: 1})
+
+	hits := ix.Search("page crash", 0)
+	assert.Len(t, hits, 2)
+	assert.Equal(t, "issue:1", hits[0].ID)
+	assert.Equal(t, "reply:1", hits[1].ID)
+
+	assert.Len(t, ix.Search("login", 1), 1)
+	assert.Empty(t, ix.Search("the", 0))
+
+	ix.Remove("issue:1")
+	hits = ix.Search("page crash", 0)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "reply:1", hits[0].ID)
+}
+
+func TestHighlight(t *testing.T) {
+	text := "Steps: open the app, go to settings and the app crashes when saving."
+
+	assert.Equal(t, "the app <b>crashes</b> when saving.", Highlight(text, "crash", 5))
+	assert.Equal(t, "Steps: open the", Highlight(text, "missing", 3))
+}
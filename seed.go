@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"issue-tracker/migrations"
+	"issue-tracker/seeds"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+const seedUsage = `Usage:
+  seed [-force]`
+
+// runSeed runs the seed command, which loads the demo data of seeds.Demo for
+// local development. The database has to be migrated first.
+//
+// The demo Users have a known password, so the command refuses to run with
+// GIN_MODE=release unless -force is given.
+func runSeed(db *gorm.DB, args []string, out io.Writer) error {
+	flags := newFlagSet("seed", seedUsage, out)
+	force := flags.Bool("force", false, "load the demo data with GIN_MODE=release too")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if os.Getenv("GIN_MODE") == "release" && !*force {
+		return errors.New("ERROR SEED: the demo data is for local development, use -force to load it with GIN_MODE=release")
+	}
+
+	pending, err := migrations.NewMigrator(db).Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("ERROR SEED: %d pending migration(s), apply them with the migrate up command first", len(pending))
+	}
+
+	if err := seeds.Builtins(db); err != nil {
+		return err
+	}
+	loaded, err := seeds.Demo(db)
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		fmt.Fprintf(out, "The demo project %s already exists, nothing was loaded.\n", seeds.DemoProjectKey)
+		return nil
+	}
+
+	fmt.Fprintf(out, "Loaded the demo project %s. Log in with the password %q as:\n", seeds.DemoProjectKey, seeds.DemoPassword)
+	for _, user := range seeds.DemoUsers {
+		fmt.Fprintf(out, "  %s\n", user.Email)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package seeds
+
+import (
+	"errors"
+	"fmt"
+	"issue-tracker/models"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Admin is the first admin created by BootstrapAdmin.
+type Admin struct {
+	Email    string
+	Name     string
+	Password string
+}
+
+// BootstrapAdmin creates the Admin with the Admin Role, unless an active User
+// with that Role exists. Returns the created User, or nil when there already
+// is an admin.
+//
+// The Admin's e-mail is lowercased, as on registration, and taken as verified.
+// The Admin has to change the password after logging in, since it was given in
+// the configuration.
+func BootstrapAdmin(db *gorm.DB, admin Admin) (*models.User, error) {
+	admin.Email = strings.ToLower(strings.TrimSpace(admin.Email))
+	if admin.Email == "" || admin.Password == "" {
+		return nil, errors.New("ERROR BOOTSTRAP ADMIN: email and password must not be empty")
+	}
+	if strings.TrimSpace(admin.Name) == "" {
+		admin.Name = "Admin"
+	}
+
+	var created *models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var admins int64
+		err := tx.Model(&models.User{}).
+			Where("role_id = ? AND deactivated_at IS NULL", models.RoleAdmin).
+			Count(&admins).Error
+		if err != nil || admins > 0 {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("email = ?", admin.Email).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("ERROR BOOTSTRAP ADMIN: %s is already registered without the Admin role", admin.Email)
+		}
+
+		password, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		user := models.User{
+			RoleID:            models.RoleAdmin,
+			Name:              admin.Name,
+			Email:             admin.Email,
+			Password:          password,
+			MustResetPassword: true,
+			EmailVerifiedAt:   &now,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		created = &user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
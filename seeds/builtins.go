@@ -0,0 +1,119 @@
+// Package seeds inserts the rows the tracker needs: the built-in Roles,
+// Permissions, workflow and Severities, the first admin, and demo data for
+// local development.
+//
+// Every seed is idempotent. Rows which already exist are left as they are, so
+// changes made to them in the database are kept.
+package seeds
+
+import (
+	"issue-tracker/database"
+	"issue-tracker/models"
+
+	"gorm.io/gorm"
+)
+
+// Builtins inserts the built-in Permissions, Roles, Statuses,
+// StatusTransitions and Severities which are not in the database yet.
+func Builtins(db *gorm.DB) error {
+	if err := Roles(db); err != nil {
+		return err
+	}
+	if err := Workflow(db); err != nil {
+		return err
+	}
+	return Severities(db)
+}
+
+// Roles inserts the built-in Permissions and Roles which are not in the
+// database yet. A new built-in Role gets all of its default Permissions, and a
+// new Permission is granted to the built-in Roles which have it by default.
+// Permissions taken away from a Role in the database are not granted again.
+func Roles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		permissions := map[string]models.Permission{}
+		createdPermissions := map[string]bool{}
+		for _, permission := range models.DefaultPermissions {
+			created, err := createIfMissing(tx, &permission, "name = ?", permission.Name)
+			if err != nil {
+				return err
+			}
+			permissions[permission.Name] = permission
+			createdPermissions[permission.Name] = created
+		}
+
+		for _, role := range models.DefaultRoles {
+			created, err := createIfMissing(tx, &role, "id = ?", role.ID)
+			if err != nil {
+				return err
+			}
+
+			var grant []models.Permission
+			for _, name := range models.DefaultRolePermissions[role.ID] {
+				if created || createdPermissions[name] {
+					grant = append(grant, permissions[name])
+				}
+			}
+			if len(grant) == 0 {
+				continue
+			}
+			if err := tx.Model(&role).Association("Permissions").Append(grant); err != nil {
+				return err
+			}
+		}
+
+		// The built-in Roles are inserted with their IDs, so the sequence has to
+		// be moved past them before Roles are created without one.
+		if tx.Dialector.Name() == database.DriverPostgres {
+			return tx.Exec("SELECT setval(pg_get_serial_sequence('roles', 'id'), (SELECT MAX(id) FROM roles))").Error
+		}
+		return nil
+	})
+}
+
+// createIfMissing creates value unless a row matching the query exists, in
+// which case that row is loaded into value. Soft deleted rows count as
+// existing. Returns whether value was created.
+func createIfMissing(tx *gorm.DB, value interface{}, query string, args ...interface{}) (bool, error) {
+	result := tx.Unscoped().Where(query, args...).Limit(1).Find(value)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return false, nil
+	}
+	return true, tx.Create(value).Error
+}
+
+// Workflow inserts the built-in Statuses and StatusTransitions which are
+// not in the database yet. Existing rows are left untouched, so changes made to
+// the workflow in the database are kept.
+func Workflow(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, status := range models.DefaultStatuses {
+			if err := tx.FirstOrCreate(&status, models.Status{ID: status.ID}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, transition := range models.DefaultTransitions {
+			if err := tx.FirstOrCreate(&transition, transition).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Severities inserts the built-in Severities which are not in the database
+// yet.
+func Severities(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, severity := range models.DefaultSeverities {
+			if err := tx.FirstOrCreate(&severity, models.Severity{ID: severity.ID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,140 @@
+package seeds
+
+import (
+	"issue-tracker/models"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// DemoPassword is the password of every demo User.
+const DemoPassword = "demo-password"
+
+// DemoProjectKey is the Key of the demo Project. Demo data is only loaded when
+// no Project has it.
+const DemoProjectKey = "DEMO"
+
+// DemoUsers are the demo Users, one for each built-in Role but Admin.
+var DemoUsers = []models.User{
+	{Name: "Quinn QA", Email: "qa@demo.local", RoleID: models.RoleQA},
+	{Name: "Dana Developer", Email: "dev@demo.local", RoleID: models.RoleDeveloper},
+	{Name: "Parker PM", Email: "pm@demo.local", RoleID: models.RoleProductManager},
+	{Name: "Vic Viewer", Email: "viewer@demo.local", RoleID: models.RoleViewer},
+}
+
+// demoIssue is a demo Issue, reported by the DemoUsers at Reporter and
+// replied to by the others in turn.
+type demoIssue struct {
+	Reporter int
+	Title    string
+	Body     string
+	Severity string
+	StatusID uint
+	Replies  []string
+}
+
+var demoIssues = []demoIssue{
+	{
+		Reporter: 0,
+		Title:    "Login page crashes on Safari",
+		Body:     "Opening the login page on Safari 14 shows a blank page and an error in the console.",
+		Severity: "3",
+		StatusID: models.StatusInProgress,
+		Replies:  []string{"I can reproduce it, looking into it.", "It only happens with private browsing."},
+	},
+	{
+		Reporter: 2,
+		Title:    "Export issues as CSV",
+		Body:     "Product needs to share the open issues with the support team every week.",
+		Severity: "1",
+		StatusID: models.StatusTriaged,
+	},
+	{
+		Reporter: 0,
+		Title:    "Typo on the settings page",
+		Body:     "\"Notifcations\" should be \"Notifications\".",
+		Severity: "1",
+		StatusID: models.StatusResolved,
+		Replies:  []string{"Fixed in the latest build."},
+	},
+	{
+		Reporter: 1,
+		Title:    "Search is slow on large projects",
+		Body:     "Searching a project with thousands of issues takes several seconds.",
+		Severity: "2",
+		StatusID: models.StatusOpen,
+	},
+}
+
+// Demo loads demo Users, a Project with Issues and Replies, for local
+// development. Returns false when the demo Project already exists, in which
+// case nothing is loaded.
+//
+// The demo Users log in with DemoPassword. Users with the same e-mail which
+// already exist are used as they are.
+func Demo(db *gorm.DB) (bool, error) {
+	var projects int64
+	if err := db.Unscoped().Model(&models.Project{}).Where(&models.Project{Key: DemoProjectKey}).Count(&projects).Error; err != nil {
+		return false, err
+	}
+	if projects > 0 {
+		return false, nil
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(DemoPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return false, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		users := make([]models.User, len(DemoUsers))
+		for i, user := range DemoUsers {
+			user.Password = password
+			user.EmailVerifiedAt = &now
+			if err := tx.Where(models.User{Email: user.Email}).FirstOrCreate(&user).Error; err != nil {
+				return err
+			}
+			users[i] = user
+		}
+
+		project := models.Project{
+			Name:        "Demo",
+			Key:         DemoProjectKey,
+			Description: "A project to try the tracker with.",
+			OwnerID:     int(users[2].ID),
+		}
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+
+		store := models.NewGormStore(tx)
+		for _, demo := range demoIssues {
+			reporter := users[demo.Reporter]
+			issue := models.Issue{
+				UserID:   int(reporter.ID),
+				Title:    demo.Title,
+				Body:     demo.Body,
+				Severity: demo.Severity,
+				StatusID: demo.StatusID,
+			}
+			if err := store.Issues().Create(&issue, &project); err != nil {
+				return err
+			}
+
+			for i, body := range demo.Replies {
+				replier := users[(demo.Reporter+1+i)%len(users)]
+				reply := models.Reply{UserID: replier.ID, IssueID: issue.ID, Body: body}
+				if err := store.Replies().Create(&reply, &issue, &replier); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
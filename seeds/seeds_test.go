@@ -0,0 +1,109 @@
+package seeds_test
+
+import (
+	"issue-tracker/database"
+	"issue-tracker/migrations"
+	"issue-tracker/models"
+	"issue-tracker/seeds"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func migratedSQLite(t *testing.T) *gorm.DB {
+	db, err := database.Open(database.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	if err := migrations.MigrateTables(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestBuiltins(t *testing.T) {
+	db := migratedSQLite(t)
+
+	// A renamed built-in Severity is kept.
+	assert.NoError(t, db.Model(&models.Severity{ID: models.SeverityHigh}).Update("name", "Critical").Error)
+	assert.NoError(t, seeds.Builtins(db))
+	assert.NoError(t, seeds.Builtins(db))
+
+	var severities []models.Severity
+	assert.NoError(t, db.Order("id").Find(&severities).Error)
+	if assert.Len(t, severities, len(models.DefaultSeverities)) {
+		assert.Equal(t, "Critical", severities[2].Name)
+	}
+
+	var roles int64
+	assert.NoError(t, db.Model(&models.Role{}).Count(&roles).Error)
+	assert.Equal(t, int64(len(models.DefaultRoles)), roles)
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	db := migratedSQLite(t)
+
+	_, err := seeds.BootstrapAdmin(db, seeds.Admin{Email: "admin@email.com"})
+	assert.Error(t, err, "the password is required")
+
+	admin, err := seeds.BootstrapAdmin(db, seeds.Admin{Email: "admin@email.com", Password: "secret"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, admin) {
+		assert.Equal(t, models.RoleAdmin, admin.RoleID)
+		assert.Equal(t, "Admin", admin.Name)
+		assert.True(t, admin.MustResetPassword)
+		assert.NotNil(t, admin.EmailVerifiedAt)
+	}
+
+	again, err := seeds.BootstrapAdmin(db, seeds.Admin{Email: "other@email.com", Password: "secret"})
+	assert.NoError(t, err)
+	assert.Nil(t, again, "there already is an admin")
+}
+
+func TestBootstrapAdminTakenEmail(t *testing.T) {
+	db := migratedSQLite(t)
+
+	qa := models.User{Name: "qa", Email: "qa@email.com", RoleID: models.RoleQA}
+	assert.NoError(t, db.Create(&qa).Error)
+
+	_, err := seeds.BootstrapAdmin(db, seeds.Admin{Email: "qa@email.com", Password: "secret"})
+	assert.Error(t, err, "an existing User is not made an admin")
+
+	_, err = seeds.BootstrapAdmin(db, seeds.Admin{Email: " QA@Email.com ", Password: "secret"})
+	assert.Error(t, err, "the e-mail is compared lowercased")
+}
+
+func TestBootstrapAdminLowercasesEmail(t *testing.T) {
+	db := migratedSQLite(t)
+
+	admin, err := seeds.BootstrapAdmin(db, seeds.Admin{Email: "Admin@Corp.com", Password: "secret"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, admin) {
+		assert.Equal(t, "admin@corp.com", admin.Email)
+	}
+}
+
+func TestDemo(t *testing.T) {
+	db := migratedSQLite(t)
+
+	loaded, err := seeds.Demo(db)
+	assert.NoError(t, err)
+	assert.True(t, loaded)
+
+	loaded, err = seeds.Demo(db)
+	assert.NoError(t, err)
+	assert.False(t, loaded)
+
+	var issues []models.Issue
+	assert.NoError(t, db.Order("number").Find(&issues).Error)
+	if assert.NotEmpty(t, issues) {
+		assert.Equal(t, seeds.DemoProjectKey+"-1", issues[0].Key)
+	}
+
+	var users int64
+	assert.NoError(t, db.Model(&models.User{}).Count(&users).Error)
+	assert.Equal(t, int64(len(seeds.DemoUsers)), users)
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, local.Save("issues/1/a", strings.NewReader("crash log")))
+	assert.Error(t, local.Save("issues/1/a", strings.NewReader("again")), "existing files are not overwritten")
+
+	file, err := local.Open("issues/1/a")
+	if assert.NoError(t, err) {
+		content, _ := ioutil.ReadAll(file)
+		file.Close()
+		assert.Equal(t, "crash log", string(content))
+	}
+
+	assert.NoError(t, local.Delete("issues/1/a"))
+	assert.NoError(t, local.Delete("issues/1/a"))
+
+	_, err = local.Open("issues/1/a")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	for _, key := range []string{"", "/", "../secret", "issues/../../secret", `issues\..\secret`} {
+		assert.Error(t, local.Save(key, strings.NewReader("x")), key)
+	}
+}
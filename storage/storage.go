@@ -0,0 +1,22 @@
+// Package storage stores the content of uploaded files.
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no file is stored under a key.
+var ErrNotFound = errors.New("storage: file not found")
+
+// Storage saves, opens and deletes files by key.
+//
+// Keys are slash separated paths like "issues/12/3f9a...", chosen by the caller.
+type Storage interface {
+	Save(key string, content io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Default is the Storage used by the application.
+var Default Storage
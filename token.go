@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"issue-tracker/models"
+
+	"gorm.io/gorm"
+)
+
+const tokenUsage = `Usage:
+  token revoke -id ID
+  token revoke -prefix PREFIX
+  token revoke -user EMAIL
+
+-id and -prefix revoke one personal access token, by its ID or the prefix
+shown in the token list. -user revokes every personal access token of the User
+and logs them out everywhere.`
+
+// runToken runs the token command.
+func runToken(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "revoke" {
+		fmt.Fprintln(out, tokenUsage)
+		if len(args) > 0 && isHelp(args[0]) {
+			return flag.ErrHelp
+		}
+		return errors.New("ERROR TOKEN: the only subcommand is revoke")
+	}
+
+	flags := newFlagSet("token revoke", tokenUsage, out)
+	id := flags.Uint("id", 0, "ID of the personal access token")
+	prefix := flags.String("prefix", "", "prefix of the personal access token")
+	email := flags.String("user", "", "e-mail of the User")
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+
+	given := 0
+	for _, set := range []bool{*id != 0, *prefix != "", *email != ""} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		flags.Usage()
+		return errors.New("ERROR TOKEN REVOKE: give one of -id, -prefix or -user")
+	}
+
+	if *email != "" {
+		return revokeUserTokens(db, *email, out)
+	}
+
+	var token models.PersonalAccessToken
+	query := db.Where("prefix = ?", *prefix)
+	if *id != 0 {
+		query = db.Where("id = ?", *id)
+	}
+	if err := query.First(&token).Error; err != nil {
+		return errors.New("ERROR TOKEN REVOKE: could not find the personal access token")
+	}
+
+	if err := db.Delete(&token).Error; err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Revoked the personal access token %q (%s...) of User %d.\n", token.Name, token.Prefix, token.UserID)
+	return nil
+}
+
+// revokeUserTokens deletes every personal access token of the User and
+// revokes all their other tokens.
+func revokeUserTokens(db *gorm.DB, email string, out io.Writer) error {
+	user, err := findUserByEmail(db, email)
+	if err != nil {
+		return err
+	}
+
+	deleted := db.Where("user_id = ?", user.ID).Delete(&models.PersonalAccessToken{})
+	if deleted.Error != nil {
+		return deleted.Error
+	}
+	if err := models.NewGormStore(db).Users().LogoutEverywhere(user); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Revoked %d personal access token(s) of %s, who is logged out everywhere.\n", deleted.RowsAffected, user.Email)
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"issue-tracker/auth"
+	"issue-tracker/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const userUsage = `Usage:
+  user create -email EMAIL -name NAME -role ROLE [-password PASSWORD]
+  user set-role -email EMAIL -role ROLE
+  user disable -email EMAIL
+  user reset-password -email EMAIL [-password PASSWORD]
+
+ROLE is the ID or the name of a Role. Without -password, a temporary password
+is generated and printed, which the User has to change after logging in.`
+
+// runUser runs the user command, which manages Users without the API.
+func runUser(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 || isHelp(args[0]) {
+		fmt.Fprintln(out, userUsage)
+		if len(args) > 0 {
+			return flag.ErrHelp
+		}
+		return errors.New("ERROR USER: missing subcommand")
+	}
+
+	flags := newFlagSet("user "+args[0], userUsage, out)
+	email := flags.String("email", "", "e-mail of the User")
+
+	switch args[0] {
+	case "create":
+		name := flags.String("name", "", "name of the User")
+		roleName := flags.String("role", "", "ID or name of the Role")
+		password := flags.String("password", "", "password, generated when empty")
+		if err := parseFlags(flags, args[1:], "email", "name", "role"); err != nil {
+			return err
+		}
+		return createUser(db, out, *email, *name, *roleName, *password)
+
+	case "set-role":
+		roleName := flags.String("role", "", "ID or name of the Role")
+		if err := parseFlags(flags, args[1:], "email", "role"); err != nil {
+			return err
+		}
+		user, err := findUserByEmail(db, *email)
+		if err != nil {
+			return err
+		}
+		role, err := findRole(db, *roleName)
+		if err != nil {
+			return err
+		}
+		if err := models.NewGormStore(db).Users().ChangeRole(user, int(role.ID)); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s has the role %s now.\n", user.Email, role.Name)
+		return nil
+
+	case "disable":
+		if err := parseFlags(flags, args[1:], "email"); err != nil {
+			return err
+		}
+		user, err := findUserByEmail(db, *email)
+		if err != nil {
+			return err
+		}
+		if err := models.NewGormStore(db).Users().Deactivate(user); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s is disabled and logged out everywhere.\n", user.Email)
+		return nil
+
+	case "reset-password":
+		password := flags.String("password", "", "new password, generated when empty")
+		if err := parseFlags(flags, args[1:], "email"); err != nil {
+			return err
+		}
+		user, err := findUserByEmail(db, *email)
+		if err != nil {
+			return err
+		}
+		hashed, generated, err := hashPassword(*password)
+		if err != nil {
+			return err
+		}
+		temporary := generated != ""
+		if err := models.NewGormStore(db).Users().SetPassword(user, hashed, temporary); err != nil {
+			return err
+		}
+		if temporary {
+			fmt.Fprintf(out, "Reset the password of %s, who is logged out everywhere and has to change it after logging in.\n", user.Email)
+		} else {
+			fmt.Fprintf(out, "Reset the password of %s, who is logged out everywhere.\n", user.Email)
+		}
+		printGeneratedPassword(out, generated)
+		return nil
+
+	default:
+		fmt.Fprintln(out, userUsage)
+		return fmt.Errorf("ERROR USER: unknown subcommand %q", args[0])
+	}
+}
+
+// createUser creates a User whose e-mail is taken as verified. Without a
+// password, the User has to change the generated one after logging in.
+func createUser(db *gorm.DB, out io.Writer, email string, name string, roleName string, password string) error {
+	role, err := findRole(db, roleName)
+	if err != nil {
+		return err
+	}
+	hashed, generated, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := models.User{
+		RoleID:            int(role.ID),
+		Name:              name,
+		Email:             strings.ToLower(strings.TrimSpace(email)),
+		Password:          hashed,
+		MustResetPassword: generated != "",
+		EmailVerifiedAt:   &now,
+	}
+	if err := models.NewGormStore(db).Users().Create(&user); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Created %s with the role %s, ID %d.\n", user.Email, role.Name, user.ID)
+	printGeneratedPassword(out, generated)
+	return nil
+}
+
+func findUserByEmail(db *gorm.DB, email string) (*models.User, error) {
+	return models.NewGormStore(db).Users().FindByEmail(strings.ToLower(strings.TrimSpace(email)))
+}
+
+// findRole finds a Role by its ID or, ignoring case, its name.
+func findRole(db *gorm.DB, value string) (*models.Role, error) {
+	var role models.Role
+	query := db.Where("LOWER(name) = ?", strings.ToLower(strings.TrimSpace(value)))
+	if id, err := strconv.Atoi(value); err == nil {
+		query = db.Where("id = ?", id)
+	}
+
+	if err := query.First(&role).Error; err != nil {
+		return nil, fmt.Errorf("ERROR ROLE: could not find role %q", value)
+	}
+	return &role, nil
+}
+
+// hashPassword hashes the password, or a generated one when it is empty.
+// Returns the generated password, if any.
+func hashPassword(password string) ([]byte, string, error) {
+	generated := ""
+	if password == "" {
+		token, err := auth.NewOpaqueToken()
+		if err != nil {
+			return nil, "", err
+		}
+		generated = token[:20]
+		password = generated
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", err
+	}
+	return hashed, generated, nil
+}
+
+func printGeneratedPassword(out io.Writer, generated string) {
+	if generated != "" {
+		fmt.Fprintf(out, "Temporary password: %s\n", generated)
+	}
+}
@@ -0,0 +1,198 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"issue-tracker/models"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers of every delivery.
+const (
+	EventHeader     = "X-Tracker-Event"
+	DeliveryHeader  = "X-Tracker-Delivery"
+	SignatureHeader = "X-Tracker-Signature-256"
+)
+
+// Dispatcher sends the queued deliveries.
+//
+// A delivery succeeds on a 2xx response. A failed delivery is tried again after
+// BaseDelay, then after twice as long every time, up to MaxDelay. After
+// MaxAttempts it is marked failed and can only be sent again by redelivering.
+type Dispatcher struct {
+	Client      *http.Client
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// NewDispatcher returns a Dispatcher with the default settings.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		Client:      NewClient(),
+		Interval:    5 * time.Second,
+		BatchSize:   20,
+		MaxAttempts: 8,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    6 * time.Hour,
+	}
+}
+
+// errInternalAddress is returned when a delivery would connect to an internal
+// address.
+var errInternalAddress = errors.New("webhook url resolves to an internal address")
+
+// NewClient returns the http.Client deliveries are sent with.
+//
+// The host of a Webhook is checked when it is saved, but it may resolve to
+// another address by the time a delivery is sent. So the address is checked
+// again on every connection, no proxy is used and redirects are not followed.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || models.IsInternalIP(ip) {
+				return errInternalAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run sends the due deliveries every Interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DeliverDue(time.Now()); err != nil {
+			log.Printf("ERROR WEBHOOK: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends every delivery which is due at now.
+// Returns how many deliveries were attempted.
+func (d *Dispatcher) DeliverDue(now time.Time) (int, error) {
+	var delivery models.WebhookDelivery
+
+	deliveries, err := delivery.DueDeliveries(now, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deliveries {
+		d.attempt(&deliveries[i])
+		if err := deliveries[i].SaveAttempt(); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Backoff returns how long to wait after the given number of failed attempts.
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	delay := d.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.MaxDelay {
+			return d.MaxDelay
+		}
+	}
+	return delay
+}
+
+// attempt sends the delivery once and records the outcome on it.
+func (d *Dispatcher) attempt(delivery *models.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseCode = 0
+	delivery.Error = ""
+
+	if delivery.Webhook.ID == 0 || !delivery.Webhook.Active {
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = "webhook is inactive"
+		return
+	}
+
+	code, err := d.send(delivery)
+	delivery.ResponseCode = code
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	delivery.Error = err.Error()
+	if delivery.Attempts >= d.MaxAttempts {
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+	next := now.Add(d.Backoff(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+}
+
+// send POSTs the signed payload to the Webhook's URL.
+// Returns the response's status code. The body of the response is not kept.
+func (d *Dispatcher) send(delivery *models.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, delivery.Webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "issue-tracker-webhooks")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(int(delivery.ID)))
+	req.Header.Set(SignatureHeader, Sign(delivery.Webhook.Secret, payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 2000))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
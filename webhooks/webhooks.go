@@ -0,0 +1,132 @@
+// Package webhooks emits tracker events to the registered Webhooks.
+//
+// Emit queues one WebhookDelivery per subscribed Webhook in the database, and
+// a Dispatcher sends the queued deliveries in the background, retrying failed
+// ones with exponential backoff.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"issue-tracker/models"
+	"log"
+	"time"
+)
+
+// Envelope is the JSON body of every delivery.
+type Envelope struct {
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Actor     *Actor      `json:"actor,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// Actor is the User who caused the event.
+type Actor struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// Data is the event specific part of the Envelope.
+type Data map[string]interface{}
+
+// IssueData is an Issue as sent in a payload.
+type IssueData struct {
+	ID        uint      `json:"id"`
+	ProjectID uint      `json:"projectId"`
+	Key       string    `json:"key"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	StatusID  uint      `json:"statusId"`
+	Severity  string    `json:"severity"`
+	UserID    int       `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ReplyData is a Reply as sent in a payload.
+type ReplyData struct {
+	ID        uint      `json:"id"`
+	IssueID   uint      `json:"issueId"`
+	IssueKey  string    `json:"issueKey"`
+	UserID    uint      `json:"userId"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Issue returns the payload of an Issue.
+func Issue(issue *models.Issue) IssueData {
+	return IssueData{
+		ID:        issue.ID,
+		ProjectID: issue.ProjectID,
+		Key:       issue.Key,
+		Title:     issue.Title,
+		Body:      issue.Body,
+		StatusID:  issue.StatusID,
+		Severity:  issue.Severity,
+		UserID:    issue.UserID,
+		CreatedAt: issue.CreatedAt,
+		UpdatedAt: issue.UpdatedAt,
+	}
+}
+
+// Reply returns the payload of a Reply on an Issue.
+func Reply(reply *models.Reply, issue *models.Issue) ReplyData {
+	return ReplyData{
+		ID:        reply.ID,
+		IssueID:   issue.ID,
+		IssueKey:  issue.Key,
+		UserID:    reply.UserID,
+		Body:      reply.Body,
+		CreatedAt: reply.CreatedAt,
+		UpdatedAt: reply.UpdatedAt,
+	}
+}
+
+// Emit queues the event for every active Webhook subscribed to it.
+// projectID is the Project the event happened in, actor may be nil.
+//
+// A failure is only logged, the request which caused the event still succeeds.
+func Emit(event string, projectID uint, actor *models.User, data interface{}) {
+	var webhook models.Webhook
+
+	envelope := Envelope{
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+	if actor != nil {
+		envelope.Actor = &Actor{ID: actor.ID, Name: actor.Name}
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("ERROR WEBHOOK: %s", err)
+		return
+	}
+
+	if _, err := webhook.QueueEvent(event, projectID, payload); err != nil {
+		log.Printf("ERROR WEBHOOK: %s", err)
+	}
+}
+
+// Sign returns the signature of a payload, sent in the SignatureHeader.
+// It is the hex encoded HMAC-SHA256 of the payload, prefixed with "sha256=".
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a random Webhook secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,117 @@
+package webhooks
+
+import (
+	"io/ioutil"
+	"issue-tracker/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 test case 2 of RFC 4231.
+	assert.Equal(t,
+		"sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		Sign("Jefe", []byte("what do ya want for nothing?")))
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	assert.Equal(t, time.Second, d.Backoff(1))
+	assert.Equal(t, 2*time.Second, d.Backoff(2))
+	assert.Equal(t, 8*time.Second, d.Backoff(4))
+	assert.Equal(t, 10*time.Second, d.Backoff(5))
+	assert.Equal(t, 10*time.Second, d.Backoff(50))
+}
+
+func newDelivery(url string) *models.WebhookDelivery {
+	delivery := &models.WebhookDelivery{
+		Webhook: models.Webhook{URL: url, Secret: "s3cret", Active: true},
+		Event:   models.EventIssueCreated,
+		Payload: `{"event":"issue.created"}`,
+		Status:  models.DeliveryPending,
+	}
+	delivery.ID = 7
+	delivery.Webhook.ID = 1
+	return delivery
+}
+
+func TestAttemptDeliversSignedPayload(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher()
+	d.Client = receiver.Client()
+	delivery := newDelivery(receiver.URL)
+	d.attempt(delivery)
+
+	assert.Equal(t, models.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, 200, delivery.ResponseCode)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	assert.Equal(t, `{"event":"issue.created"}`, string(body))
+	assert.Equal(t, "issue.created", got.Header.Get(EventHeader))
+	assert.Equal(t, "7", got.Header.Get(DeliveryHeader))
+	assert.Equal(t, Sign("s3cret", body), got.Header.Get(SignatureHeader))
+}
+
+func TestAttemptRetriesThenFails(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher()
+	d.Client = receiver.Client()
+	d.MaxAttempts = 2
+	delivery := newDelivery(receiver.URL)
+
+	before := time.Now()
+	d.attempt(delivery)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.ResponseCode)
+	assert.NotEmpty(t, delivery.Error)
+	if assert.NotNil(t, delivery.NextAttemptAt) {
+		assert.True(t, delivery.NextAttemptAt.After(before.Add(d.BaseDelay-time.Second)))
+	}
+
+	d.attempt(delivery)
+	assert.Equal(t, models.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+}
+
+func TestAttemptSkipsInactiveWebhook(t *testing.T) {
+	delivery := newDelivery("http://127.0.0.1:1")
+	delivery.Webhook.Active = false
+
+	NewDispatcher().attempt(delivery)
+	assert.Equal(t, models.DeliveryFailed, delivery.Status)
+	assert.Equal(t, "webhook is inactive", delivery.Error)
+}
+
+func TestAttemptRefusesInternalAddress(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	delivery := newDelivery(receiver.URL)
+	NewDispatcher().attempt(delivery)
+
+	assert.False(t, called, "the default client does not connect to loopback addresses")
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Contains(t, delivery.Error, errInternalAddress.Error())
+}